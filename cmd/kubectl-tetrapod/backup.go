@@ -0,0 +1,433 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+const (
+	backupKind = "IPAMBackup"
+
+	// backupVersion is bumped on incompatible changes of the file format
+	backupVersion = "v1"
+)
+
+// ipamBackup is the file written by export and read by import
+type ipamBackup struct {
+	Kind         string              `json:"kind"`
+	Version      string              `json:"version"`
+	ExportedAt   metav1.Time         `json:"exportedAt"`
+	Reservations []backupReservation `json:"reservations,omitempty"`
+	Quotas       []backupQuota       `json:"quotas,omitempty"`
+	Blocks       []backupBlock       `json:"blocks"`
+	BlockClaims  []backupBlockClaim  `json:"blockClaims,omitempty"`
+	Claims       []backupClaim       `json:"claims"`
+}
+
+// backupObjects are the objects listed by export
+type backupObjects struct {
+	reservations []controlplanev1alpha1.CIDRReservation
+	quotas       []controlplanev1alpha1.CIDRQuota
+	blocks       []controlplanev1alpha1.CIDRBlock
+	blockClaims  []controlplanev1alpha1.CIDRBlockClaim
+	claims       []controlplanev1alpha1.CIDRClaim
+}
+
+// backupReservation is a CIDRReservation
+type backupReservation struct {
+	Namespace string                                   `json:"namespace"`
+	Name      string                                   `json:"name"`
+	Labels    map[string]string                        `json:"labels,omitempty"`
+	Spec      controlplanev1alpha1.CIDRReservationSpec `json:"spec"`
+}
+
+// backupQuota is a CIDRQuota without the status rebuilt by the controller
+type backupQuota struct {
+	Namespace string                             `json:"namespace"`
+	Name      string                             `json:"name"`
+	Labels    map[string]string                  `json:"labels,omitempty"`
+	Spec      controlplanev1alpha1.CIDRQuotaSpec `json:"spec"`
+}
+
+// backupBlock is a CIDRBlock without the status rebuilt by the controller
+type backupBlock struct {
+	Namespace string                             `json:"namespace"`
+	Name      string                             `json:"name"`
+	Labels    map[string]string                  `json:"labels,omitempty"`
+	Spec      controlplanev1alpha1.CIDRBlockSpec `json:"spec"`
+
+	// Owners are the owners of the block like the CIDRBlockClaim of a child block
+	Owners []backupOwner `json:"owners,omitempty"`
+}
+
+// backupBlockClaim is a CIDRBlockClaim without the status rebuilt by the controller
+type backupBlockClaim struct {
+	Namespace string                                  `json:"namespace"`
+	Name      string                                  `json:"name"`
+	Labels    map[string]string                       `json:"labels,omitempty"`
+	Spec      controlplanev1alpha1.CIDRBlockClaimSpec `json:"spec"`
+	Owners    []backupOwner                           `json:"owners,omitempty"`
+}
+
+// backupClaim is a CIDRClaim with the allocations bound to it
+type backupClaim struct {
+	Namespace   string                             `json:"namespace"`
+	Name        string                             `json:"name"`
+	Labels      map[string]string                  `json:"labels,omitempty"`
+	Annotations map[string]string                  `json:"annotations,omitempty"`
+	Spec        controlplanev1alpha1.CIDRClaimSpec `json:"spec"`
+
+	// Owners are the owners of the claim like PeerNodes. Their UIDs are looked up on import.
+	Owners []backupOwner `json:"owners,omitempty"`
+
+	// CIDRs are the allocations bound to the claim when it was exported
+	CIDRs []controlplanev1alpha1.CIDRClaimFamilyStatus `json:"cidrs,omitempty"`
+}
+
+// backupOwner is an owner reference without the UID which changes when the owner is recreated
+type backupOwner struct {
+	APIVersion         string `json:"apiVersion"`
+	Kind               string `json:"kind"`
+	Name               string `json:"name"`
+	Controller         bool   `json:"controller,omitempty"`
+	BlockOwnerDeletion bool   `json:"blockOwnerDeletion,omitempty"`
+}
+
+// newBackup returns the backup of the objects sorted by their namespaces and names.
+// Claims being deleted are left out.
+func newBackup(objects *backupObjects, now metav1.Time) *ipamBackup {
+	backup := &ipamBackup{
+		Kind:       backupKind,
+		Version:    backupVersion,
+		ExportedAt: now,
+		Blocks:     []backupBlock{},
+		Claims:     []backupClaim{},
+	}
+
+	for _, reservation := range objects.reservations {
+		backup.Reservations = append(backup.Reservations, backupReservation{
+			Namespace: reservation.Namespace,
+			Name:      reservation.Name,
+			Labels:    reservation.Labels,
+			Spec:      reservation.Spec,
+		})
+	}
+
+	for _, quota := range objects.quotas {
+		backup.Quotas = append(backup.Quotas, backupQuota{
+			Namespace: quota.Namespace,
+			Name:      quota.Name,
+			Labels:    quota.Labels,
+			Spec:      quota.Spec,
+		})
+	}
+
+	for _, block := range objects.blocks {
+		backup.Blocks = append(backup.Blocks, backupBlock{
+			Namespace: block.Namespace,
+			Name:      block.Name,
+			Labels:    block.Labels,
+			Spec:      block.Spec,
+			Owners:    backupOwners(block.OwnerReferences),
+		})
+	}
+
+	for _, blockClaim := range objects.blockClaims {
+		if blockClaim.DeletionTimestamp != nil {
+			continue
+		}
+
+		backup.BlockClaims = append(backup.BlockClaims, backupBlockClaim{
+			Namespace: blockClaim.Namespace,
+			Name:      blockClaim.Name,
+			Labels:    blockClaim.Labels,
+			Spec:      blockClaim.Spec,
+			Owners:    backupOwners(blockClaim.OwnerReferences),
+		})
+	}
+
+	for _, claim := range objects.claims {
+		if claim.DeletionTimestamp != nil {
+			continue
+		}
+
+		backup.Claims = append(backup.Claims, backupClaim{
+			Namespace:   claim.Namespace,
+			Name:        claim.Name,
+			Labels:      claim.Labels,
+			Annotations: claim.Annotations,
+			Spec:        claim.Spec,
+			Owners:      backupOwners(claim.OwnerReferences),
+			CIDRs:       claim.Status.FamilyStatuses(),
+		})
+	}
+
+	sort.Slice(backup.Reservations, func(i, j int) bool {
+		return backup.Reservations[i].key().String() < backup.Reservations[j].key().String()
+	})
+	sort.Slice(backup.Quotas, func(i, j int) bool {
+		return backup.Quotas[i].key().String() < backup.Quotas[j].key().String()
+	})
+	sort.Slice(backup.Blocks, func(i, j int) bool {
+		return backup.Blocks[i].key().String() < backup.Blocks[j].key().String()
+	})
+	sort.Slice(backup.BlockClaims, func(i, j int) bool {
+		return backup.BlockClaims[i].key().String() < backup.BlockClaims[j].key().String()
+	})
+	sort.Slice(backup.Claims, func(i, j int) bool {
+		return backup.Claims[i].key().String() < backup.Claims[j].key().String()
+	})
+
+	return backup
+}
+
+// backupOwners returns the owner references without the UIDs
+func backupOwners(refs []metav1.OwnerReference) []backupOwner {
+	var owners []backupOwner
+	for _, ref := range refs {
+		owners = append(owners, backupOwner{
+			APIVersion:         ref.APIVersion,
+			Kind:               ref.Kind,
+			Name:               ref.Name,
+			Controller:         ref.Controller != nil && *ref.Controller,
+			BlockOwnerDeletion: ref.BlockOwnerDeletion != nil && *ref.BlockOwnerDeletion,
+		})
+	}
+
+	return owners
+}
+
+// object returns the CIDRBlock without the owner references
+func (b *backupBlock) object() *controlplanev1alpha1.CIDRBlock {
+	return &controlplanev1alpha1.CIDRBlock{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: b.Namespace,
+			Name:      b.Name,
+			Labels:    b.Labels,
+		},
+		Spec: b.Spec,
+	}
+}
+
+// blockClaim returns the name of the CIDRBlockClaim the block is the child of, or an empty string
+func (b *backupBlock) blockClaim() string {
+	for _, o := range b.Owners {
+		if o.Controller && o.Kind == "CIDRBlockClaim" && o.APIVersion == controlplanev1alpha1.GroupVersion.String() {
+			return o.Name
+		}
+	}
+
+	return ""
+}
+
+func (r *backupReservation) key() types.NamespacedName {
+	return types.NamespacedName{Namespace: r.Namespace, Name: r.Name}
+}
+
+func (q *backupQuota) key() types.NamespacedName {
+	return types.NamespacedName{Namespace: q.Namespace, Name: q.Name}
+}
+
+func (b *backupBlock) key() types.NamespacedName {
+	return types.NamespacedName{Namespace: b.Namespace, Name: b.Name}
+}
+
+func (c *backupBlockClaim) key() types.NamespacedName {
+	return types.NamespacedName{Namespace: c.Namespace, Name: c.Name}
+}
+
+func (c *backupClaim) key() types.NamespacedName {
+	return types.NamespacedName{Namespace: c.Namespace, Name: c.Name}
+}
+
+// blockKey returns the CIDRBlock the allocation is bound to
+func (c *backupClaim) blockKey(s *controlplanev1alpha1.CIDRClaimFamilyStatus) types.NamespacedName {
+	namespace := s.CIDRBlockNamespace
+	if namespace == "" {
+		namespace = c.Namespace
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: s.CIDRBlockName}
+}
+
+// boundStatus returns the status of the claim bound to the block in the family of the CIDR in the backup
+func (c *backupClaim) boundStatus(claim *controlplanev1alpha1.CIDRClaim, want *controlplanev1alpha1.CIDRClaimFamilyStatus) *controlplanev1alpha1.CIDRClaimFamilyStatus {
+	for _, s := range claim.Status.FamilyStatuses() {
+		if s.CIDRBlockName != "" && s.Family == want.Family && c.blockKey(&s) == c.blockKey(want) {
+			return &s
+		}
+	}
+
+	return nil
+}
+
+// findInLedger returns true if the CIDR is allocated to or retained for the claim in the ledger of the block.
+// Otherwise it returns the name of the claim the prefix overlapping the CIDR is allocated to or released by, if any.
+func findInLedger(block *controlplanev1alpha1.CIDRBlock, claim *backupClaim, cidr string) (bool, string) {
+	addr := ipaddr.NewIPAddressString(cidr).GetAddress()
+
+	owns := func(name, namespace string) bool {
+		if namespace == "" {
+			namespace = block.Namespace
+		}
+
+		return name == claim.Name && namespace == claim.Namespace
+	}
+
+	recorded := false
+	for _, a := range block.Status.Allocations {
+		if used := ipaddr.NewIPAddressString(a.CIDR).GetAddress(); used == nil || !used.Overlaps(addr) {
+			continue
+		}
+
+		if a.CIDR != cidr || !owns(a.ClaimName, a.ClaimNamespace) {
+			return false, a.ClaimName
+		}
+		recorded = true
+	}
+
+	for _, p := range block.Status.ReleasedPrefixes {
+		if released := ipaddr.NewIPAddressString(p.CIDR).GetAddress(); released == nil || !released.Overlaps(addr) {
+			continue
+		}
+
+		if p.CIDR != cidr || !p.Retained || !owns(p.ClaimName, p.ClaimNamespace) {
+			return false, p.ClaimName
+		}
+		recorded = true
+	}
+
+	return recorded, ""
+}
+
+// validate checks the format of the backup and that no two claims overlap in the same address space.
+// CIDRBlocks in a namespace share an address space with each other and with the shared CIDRBlocks
+// of any namespace, while CIDRBlocks in different namespaces may overlap unless they are shared.
+// The claims in a child CIDRBlock overlap the claim of the CIDRBlockClaim the block is carved by.
+func (b *ipamBackup) validate() error {
+	if b.Kind != backupKind {
+		return fmt.Errorf("unknown kind %q", b.Kind)
+	}
+	if b.Version != backupVersion {
+		return fmt.Errorf("unsupported version %q", b.Version)
+	}
+
+	type allocation struct {
+		claim types.NamespacedName
+		block types.NamespacedName
+		addr  *ipaddr.IPAddress
+
+		// carves is the child block whose prefix is the allocation
+		carves types.NamespacedName
+	}
+
+	blocks := map[types.NamespacedName]*backupBlock{}
+	for i := range b.Blocks {
+		blocks[b.Blocks[i].key()] = &b.Blocks[i]
+	}
+
+	// parents are the blocks the child blocks are carved from
+	parents := map[types.NamespacedName]types.NamespacedName{}
+
+	var allocations []allocation
+	for i := range b.Claims {
+		claim := &b.Claims[i]
+
+		for j := range claim.CIDRs {
+			s := &claim.CIDRs[j]
+
+			addr := ipaddr.NewIPAddressString(s.CIDR).GetAddress()
+			if addr == nil || addr.GetPrefixLen() == nil {
+				return fmt.Errorf("invalid CIDR %q of CIDRClaim %s", s.CIDR, claim.key())
+			}
+			addr = addr.ToPrefixBlock()
+
+			a := allocation{claim: claim.key(), block: claim.blockKey(s), addr: addr}
+			for _, child := range b.Blocks {
+				if child.Namespace != claim.Namespace || child.blockClaim() == "" {
+					continue
+				}
+
+				if childSubnet := ipaddr.NewIPAddressString(child.Spec.CIDR).GetAddress(); childSubnet != nil && childSubnet.ToPrefixBlock().Equal(addr) {
+					a.carves = child.key()
+					parents[child.key()] = a.block
+				}
+			}
+
+			allocations = append(allocations, a)
+		}
+	}
+
+	// shared returns true if the block of the allocation shares the address space with all namespaces.
+	// The blocks not in the backup are shared if they are in the other namespaces than the claims.
+	shared := func(a *allocation) bool {
+		if block, ok := blocks[a.block]; ok {
+			return block.Spec.NamespaceSelector != nil
+		}
+
+		return a.block.Namespace != a.claim.Namespace
+	}
+
+	// carves returns true if the block is carved from the allocation directly or indirectly
+	carves := func(a *allocation, block types.NamespacedName) bool {
+		for range len(parents) + 1 {
+			if a.carves == block {
+				return true
+			}
+
+			parent, ok := parents[block]
+			if !ok {
+				return false
+			}
+			block = parent
+		}
+
+		return false
+	}
+
+	// The allocations are sorted so that the ones containing an allocation come before it
+	sort.Slice(allocations, func(i, j int) bool {
+		x, y := allocations[i].addr, allocations[j].addr
+
+		if x.GetBitCount() != y.GetBitCount() {
+			return x.GetBitCount() < y.GetBitCount()
+		}
+		if c := x.GetValue().Cmp(y.GetValue()); c != 0 {
+			return c < 0
+		}
+
+		return x.GetPrefixLen().Len() < y.GetPrefixLen().Len()
+	})
+
+	// containing is the stack of the allocations containing the current one
+	var containing []*allocation
+	for i := range allocations {
+		a := &allocations[i]
+
+		for len(containing) != 0 && !containing[len(containing)-1].addr.Contains(a.addr) {
+			containing = containing[:len(containing)-1]
+		}
+
+		for _, other := range containing {
+			if other.block != a.block && other.block.Namespace != a.block.Namespace && !shared(other) && !shared(a) {
+				continue
+			}
+
+			if carves(other, a.block) || carves(a, other.block) {
+				continue
+			}
+
+			return fmt.Errorf("%s of CIDRClaim %s overlaps %s of CIDRClaim %s", a.addr, a.claim, other.addr, other.claim)
+		}
+
+		containing = append(containing, a)
+	}
+
+	return nil
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func testClaim(name, block, cidr string) controlplanev1alpha1.CIDRClaim {
+	return controlplanev1alpha1.CIDRClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "tetrapod",
+			Labels: map[string]string{
+				"client.miscord.win/node": name,
+			},
+		},
+		Spec: controlplanev1alpha1.CIDRClaimSpec{
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"type": "pods"},
+			},
+			SizeBit: 4,
+		},
+		Status: controlplanev1alpha1.CIDRClaimStatus{
+			State: controlplanev1alpha1.CIDRClaimStatusStateReady,
+			CIDRs: []controlplanev1alpha1.CIDRClaimFamilyStatus{
+				{Family: controlplanev1alpha1.AddressFamilyIPv4, CIDRBlockName: block, CIDR: cidr, SizeBit: 4},
+			},
+		},
+	}
+}
+
+func TestBackupValidate(t *testing.T) {
+	now := metav1.Now()
+
+	backup := newBackup(&backupObjects{claims: []controlplanev1alpha1.CIDRClaim{
+		testClaim("node-002", "pods", "192.168.1.16/28"),
+		testClaim("node-001", "pods", "192.168.1.0/28"),
+	}}, now)
+
+	if err := backup.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if backup.Claims[0].Name != "node-001" {
+		t.Errorf("claims are not sorted: %+v", backup.Claims)
+	}
+
+	// Claims bound to CIDRBlocks in different namespaces may overlap
+	other := testClaim("node-002", "pods", "192.168.1.0/24")
+	other.Namespace = "other"
+	backup = newBackup(&backupObjects{claims: []controlplanev1alpha1.CIDRClaim{
+		testClaim("node-001", "pods", "192.168.1.0/28"),
+		other,
+	}}, now)
+
+	if err := backup.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Claims bound to different CIDRBlocks in the same namespace may not
+	backup = newBackup(&backupObjects{claims: []controlplanev1alpha1.CIDRClaim{
+		testClaim("node-001", "pods", "192.168.1.0/28"),
+		testClaim("node-002", "other-pods", "192.168.1.0/24"),
+	}}, now)
+
+	err := backup.validate()
+	if err == nil || !strings.Contains(err.Error(), "192.168.1.0/28 of CIDRClaim tetrapod/node-001 overlaps 192.168.1.0/24 of CIDRClaim tetrapod/node-002") {
+		t.Errorf("overlap is not detected: %v", err)
+	}
+
+	// Nor claims bound to a CIDRBlock shared from another namespace
+	shared := testClaim("node-003", "shared-pods", "192.168.1.16/28")
+	shared.Status.CIDRs[0].CIDRBlockNamespace = "infra"
+	backup = newBackup(&backupObjects{claims: []controlplanev1alpha1.CIDRClaim{
+		other,
+		shared,
+	}}, now)
+
+	err = backup.validate()
+	if err == nil || !strings.Contains(err.Error(), "192.168.1.16/28 of CIDRClaim tetrapod/node-003 overlaps 192.168.1.0/24 of CIDRClaim other/node-002") {
+		t.Errorf("overlap with the shared block is not detected: %v", err)
+	}
+
+	// Claims bound to a child CIDRBlock overlap the claim of the CIDRBlockClaim carving it
+	controller := true
+	backup = newBackup(&backupObjects{
+		blocks: []controlplanev1alpha1.CIDRBlock{{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "node-001-pods",
+				Namespace: "tetrapod",
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: controlplanev1alpha1.GroupVersion.String(),
+					Kind:       "CIDRBlockClaim",
+					Name:       "node-001-pods",
+					Controller: &controller,
+				}},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{CIDR: "192.168.1.0/26"},
+		}},
+		claims: []controlplanev1alpha1.CIDRClaim{
+			testClaim("node-001-pods-cidrblockclaim", "pods", "192.168.1.0/26"),
+			testClaim("pod-001", "node-001-pods", "192.168.1.0/28"),
+			testClaim("pod-002", "node-001-pods", "192.168.1.16/28"),
+		},
+	}, now)
+
+	if err := backup.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	backup.Claims = append(backup.Claims, backupClaim{
+		Namespace: "tetrapod",
+		Name:      "node-002",
+		CIDRs:     testClaim("node-002", "pods", "192.168.1.32/28").Status.CIDRs,
+	})
+	err = backup.validate()
+	if err == nil || !strings.Contains(err.Error(), "192.168.1.32/28 of CIDRClaim tetrapod/node-002 overlaps 192.168.1.0/26 of CIDRClaim tetrapod/node-001-pods-cidrblockclaim") {
+		t.Errorf("overlap with the child block is not detected: %v", err)
+	}
+
+	backup.Version = "v0"
+	if err := backup.validate(); err == nil {
+		t.Errorf("unsupported version is not detected")
+	}
+}
+
+func TestImportBackup(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	if err := controlplanev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	dualStack := testClaim("node-002", "pods", "192.168.1.16/28")
+	dualStack.Spec.Families = []controlplanev1alpha1.CIDRClaimFamily{
+		{Family: controlplanev1alpha1.AddressFamilyIPv4, SizeBit: 4},
+		{Family: controlplanev1alpha1.AddressFamilyIPv6, SizeBit: 64},
+	}
+	dualStack.Status.CIDRs = append(dualStack.Status.CIDRs, controlplanev1alpha1.CIDRClaimFamilyStatus{
+		Family: controlplanev1alpha1.AddressFamilyIPv6, CIDRBlockName: "pods-v6", CIDR: "fd00::/64", SizeBit: 64,
+	})
+
+	node := testClaim("node-001", "pods", "192.168.1.0/28")
+	controller := true
+	node.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: controlplanev1alpha1.GroupVersion.String(),
+		Kind:       "PeerNode",
+		Name:       "node-001",
+		UID:        "old-uid",
+		Controller: &controller,
+	}}
+	dualStack.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: controlplanev1alpha1.GroupVersion.String(),
+		Kind:       "PeerNode",
+		Name:       "node-002",
+		UID:        "old-uid",
+		Controller: &controller,
+	}}
+
+	maxClaims := int64(16)
+	exported := newBackup(&backupObjects{
+		reservations: []controlplanev1alpha1.CIDRReservation{{
+			ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "tetrapod"},
+			Spec:       controlplanev1alpha1.CIDRReservationSpec{CIDR: "192.168.1.254/32"},
+		}},
+		quotas: []controlplanev1alpha1.CIDRQuota{{
+			ObjectMeta: metav1.ObjectMeta{Name: "pods", Namespace: "tetrapod"},
+			Spec:       controlplanev1alpha1.CIDRQuotaSpec{MaxClaims: &maxClaims},
+		}},
+		blocks: []controlplanev1alpha1.CIDRBlock{{
+			ObjectMeta: metav1.ObjectMeta{Name: "pods", Namespace: "tetrapod", Labels: map[string]string{"type": "pods"}},
+			Spec:       controlplanev1alpha1.CIDRBlockSpec{CIDR: "192.168.1.0/24"},
+		}},
+		blockClaims: []controlplanev1alpha1.CIDRBlockClaim{{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-001-pods", Namespace: "tetrapod", OwnerReferences: node.OwnerReferences},
+			Spec: controlplanev1alpha1.CIDRBlockClaimSpec{
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"type": "pods"}},
+				SizeBit:  6,
+			},
+		}},
+		claims: []controlplanev1alpha1.CIDRClaim{node, dualStack},
+	}, metav1.Now())
+
+	// The backup is read from the file written by export
+	b, err := yaml.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backup ipamBackup
+	if err := yaml.UnmarshalStrict(b, &backup); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the PeerNode of node-001 has been recreated
+	peerNode := &controlplanev1alpha1.PeerNode{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-001", Namespace: "tetrapod", UID: "new-uid"},
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(peerNode).
+		WithStatusSubresource(&controlplanev1alpha1.CIDRBlock{}, &controlplanev1alpha1.CIDRClaim{}).
+		Build()
+
+	var out bytes.Buffer
+	if err := importBackup(ctx, c, &backup, false, &out); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if strings.Count(out.String(), "created") != 6 ||
+		!strings.Contains(out.String(), "owner PeerNode node-002 of cidrclaim tetrapod/node-002 not found") ||
+		!strings.Contains(out.String(), "cidrblock tetrapod/pods retains 2 prefixes") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+
+	// The CIDRs are retained for the claims in the ledger instead of being requested in the specs
+	var block controlplanev1alpha1.CIDRBlock
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tetrapod", Name: "pods"}, &block); err != nil {
+		t.Fatal(err)
+	}
+	if got := block.Status.ReleasedPrefixes; len(got) != 2 ||
+		got[0].ClaimName != "node-001" || got[0].CIDR != "192.168.1.0/28" || !got[0].Retained ||
+		got[1].ClaimName != "node-002" || got[1].CIDR != "192.168.1.16/28" || !got[1].Retained ||
+		block.Status.LedgerVersion != 1 {
+		t.Errorf("CIDRs are not retained: %+v", block.Status)
+	}
+
+	var claim controlplanev1alpha1.CIDRClaim
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tetrapod", Name: "node-001"}, &claim); err != nil {
+		t.Fatal(err)
+	}
+	if claim.Spec.RequestedCIDR != "" || claim.Spec.SizeBit != 4 || claim.Labels["client.miscord.win/node"] != "node-001" {
+		t.Errorf("claim is not restored: %+v", claim)
+	}
+	if owner := metav1.GetControllerOf(&claim); owner == nil || owner.Name != "node-001" || owner.UID != "new-uid" {
+		t.Errorf("owner is not restored: %+v", claim.OwnerReferences)
+	}
+
+	var blockClaim controlplanev1alpha1.CIDRBlockClaim
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tetrapod", Name: "node-001-pods"}, &blockClaim); err != nil {
+		t.Fatal(err)
+	}
+	if owner := metav1.GetControllerOf(&blockClaim); blockClaim.Spec.SizeBit != 6 || owner == nil || owner.UID != "new-uid" {
+		t.Errorf("cidrblockclaim is not restored: %+v", blockClaim)
+	}
+	var reservation controlplanev1alpha1.CIDRReservation
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tetrapod", Name: "gateway"}, &reservation); err != nil || reservation.Spec.CIDR != "192.168.1.254/32" {
+		t.Errorf("cidrreservation is not restored: %+v, %v", reservation, err)
+	}
+	var quota controlplanev1alpha1.CIDRQuota
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tetrapod", Name: "pods"}, &quota); err != nil || *quota.Spec.MaxClaims != 16 {
+		t.Errorf("cidrquota is not restored: %+v, %v", quota, err)
+	}
+
+	// Importing again changes nothing
+	out.Reset()
+	if err := importBackup(ctx, c, &backup, false, &out); err != nil {
+		t.Fatalf("failed to import again: %v", err)
+	}
+	if strings.Count(out.String(), "unchanged") != 6 || strings.Contains(out.String(), "retains") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+}
+
+func TestImportBackupExistingClaims(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	if err := controlplanev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := newBackup(&backupObjects{
+		blocks: []controlplanev1alpha1.CIDRBlock{{
+			ObjectMeta: metav1.ObjectMeta{Name: "pods", Namespace: "tetrapod", Labels: map[string]string{"type": "pods"}},
+			Spec:       controlplanev1alpha1.CIDRBlockSpec{CIDR: "192.168.1.0/24"},
+		}},
+		claims: []controlplanev1alpha1.CIDRClaim{
+			testClaim("node-001", "pods", "192.168.1.0/28"),
+			testClaim("node-002", "pods", "192.168.1.16/28"),
+			testClaim("node-003", "pods", "192.168.1.32/28"),
+		},
+	}, metav1.Now())
+
+	// The claims have been recreated by tetrad before import
+	pending := testClaim("node-001", "", "")
+	pending.Status = controlplanev1alpha1.CIDRClaimStatus{}
+	bound := testClaim("node-002", "pods", "192.168.1.48/28")
+	block := &controlplanev1alpha1.CIDRBlock{
+		ObjectMeta: metav1.ObjectMeta{Name: "pods", Namespace: "tetrapod"},
+		Spec:       controlplanev1alpha1.CIDRBlockSpec{CIDR: "192.168.1.0/24"},
+		Status: controlplanev1alpha1.CIDRBlockStatus{
+			Allocations: []controlplanev1alpha1.CIDRBlockAllocation{
+				{ClaimName: "node-002", ClaimNamespace: "tetrapod", CIDR: "192.168.1.48/28"},
+				{ClaimName: "node-004", ClaimNamespace: "tetrapod", CIDR: "192.168.1.32/27"},
+			},
+		},
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(block, &pending, &bound).
+		WithStatusSubresource(&controlplanev1alpha1.CIDRBlock{}, &controlplanev1alpha1.CIDRClaim{}).
+		Build()
+
+	var out bytes.Buffer
+	if err := importBackup(ctx, c, backup, false, &out); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	for _, want := range []string{
+		"cidrclaim tetrapod/node-002 is bound to 192.168.1.48/28 instead of 192.168.1.16/28",
+		"192.168.1.32/28 of cidrclaim tetrapod/node-003 is used by node-004 in cidrblock tetrapod/pods",
+		"cidrblock tetrapod/pods retains 1 prefixes",
+		"cidrclaim tetrapod/node-001 unchanged",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q: %s", want, out.String())
+		}
+	}
+
+	// The CIDR is retained for the pending claim recreated by tetrad
+	if err := c.Get(ctx, client.ObjectKeyFromObject(block), block); err != nil {
+		t.Fatal(err)
+	}
+	if got := block.Status.ReleasedPrefixes; len(got) != 1 || got[0].ClaimName != "node-001" || got[0].CIDR != "192.168.1.0/28" {
+		t.Errorf("unexpected released prefixes: %+v", got)
+	}
+}
+
+func TestImportChildBlocks(t *testing.T) {
+	ctx := context.Background()
+
+	scheme := runtime.NewScheme()
+	if err := controlplanev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	controller := true
+	exported := newBackup(&backupObjects{
+		blocks: []controlplanev1alpha1.CIDRBlock{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "pods", Namespace: "tetrapod", Labels: map[string]string{"type": "pods"}},
+				Spec:       controlplanev1alpha1.CIDRBlockSpec{CIDR: "192.168.0.0/16"},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "node-001-pods",
+					Namespace: "tetrapod",
+					Labels:    map[string]string{"type": "node-pods"},
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: controlplanev1alpha1.GroupVersion.String(),
+						Kind:       "CIDRBlockClaim",
+						Name:       "node-001-pods",
+						UID:        "old-uid",
+						Controller: &controller,
+					}},
+				},
+				Spec: controlplanev1alpha1.CIDRBlockSpec{CIDR: "192.168.1.0/24"},
+			},
+		},
+		blockClaims: []controlplanev1alpha1.CIDRBlockClaim{{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-001-pods", Namespace: "tetrapod"},
+			Spec: controlplanev1alpha1.CIDRBlockClaimSpec{
+				Selector: metav1.LabelSelector{MatchLabels: map[string]string{"type": "pods"}},
+				SizeBit:  8,
+			},
+		}},
+	}, metav1.Now())
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithStatusSubresource(&controlplanev1alpha1.CIDRBlock{}).
+		Build()
+
+	// The child waits for the prefix to be allocated to the CIDRBlockClaim
+	var out bytes.Buffer
+	if err := importBackup(ctx, c, exported, false, &out); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if !strings.Contains(out.String(), "cidrblock tetrapod/node-001-pods waits for cidrblockclaim tetrapod/node-001-pods to be bound") ||
+		!strings.Contains(out.String(), "1 cidrblocks wait for their cidrblockclaims") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+
+	var parent controlplanev1alpha1.CIDRBlock
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tetrapod", Name: "pods"}, &parent); err != nil {
+		t.Fatal(err)
+	}
+	parent.Status.Allocations = []controlplanev1alpha1.CIDRBlockAllocation{
+		{ClaimName: controlplanev1alpha1.BlockClaimCIDRClaimName("node-001-pods"), ClaimNamespace: "tetrapod", CIDR: "192.168.1.0/24"},
+	}
+	if err := c.Status().Update(ctx, &parent); err != nil {
+		t.Fatal(err)
+	}
+
+	// The child is created with the CIDRBlockClaim as the controller
+	out.Reset()
+	if err := importBackup(ctx, c, exported, false, &out); err != nil {
+		t.Fatalf("failed to import again: %v", err)
+	}
+	if !strings.Contains(out.String(), "cidrblock tetrapod/node-001-pods created") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+
+	var child controlplanev1alpha1.CIDRBlock
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "tetrapod", Name: "node-001-pods"}, &child); err != nil {
+		t.Fatal(err)
+	}
+	if !child.IsCarvedFrom(&parent) || child.Labels["type"] != "node-pods" {
+		t.Errorf("child is not restored: %+v", child)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func runBlocks(ctx context.Context, c client.Client, opts *options, args []string, w io.Writer) error {
+	namespace := opts.namespace
+
+	var blocks controlplanev1alpha1.CIDRBlockList
+	if err := c.List(ctx, &blocks, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list CIDRBlocks: %w", err)
+	}
+
+	t := newTable(w, namespace, "NAME", "CIDR", "CLAIMS", "ALLOCATED", "FREE", "USED")
+	for i := range blocks.Items {
+		block := &blocks.Items[i]
+
+		t.row(
+			block.Namespace,
+			block.Name,
+			block.Spec.CIDR,
+			strconv.Itoa(block.Status.BoundClaims),
+			block.Status.AllocatedAddresses,
+			block.Status.FreeAddresses,
+			utilization(block),
+		)
+	}
+
+	return t.flush()
+}
+
+// utilization returns the percentage of the addresses in the block allocated to CIDRClaims.
+// It returns an empty string before the status is reported.
+func utilization(block *controlplanev1alpha1.CIDRBlock) string {
+	addr := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+	allocated, ok := new(big.Int).SetString(block.Status.AllocatedAddresses, 10)
+
+	if addr == nil || !ok {
+		return ""
+	}
+
+	used := new(big.Float).Quo(new(big.Float).SetInt(allocated), new(big.Float).SetInt(addr.GetCount()))
+	percent, _ := used.Mul(used, big.NewFloat(100)).Float64()
+
+	return fmt.Sprintf("%.1f%%", percent)
+}
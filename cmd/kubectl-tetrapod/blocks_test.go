@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func TestUtilization(t *testing.T) {
+	for _, tc := range []struct {
+		cidr      string
+		allocated string
+		expected  string
+	}{
+		{cidr: "192.168.1.0/24", allocated: "64", expected: "25.0%"},
+		{cidr: "192.168.1.0/24", allocated: "0", expected: "0.0%"},
+		{cidr: "fd00::/64", allocated: "9223372036854775808", expected: "50.0%"},
+		{cidr: "192.168.1.0/24", allocated: "", expected: ""},
+	} {
+		block := &controlplanev1alpha1.CIDRBlock{
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: tc.cidr,
+			},
+			Status: controlplanev1alpha1.CIDRBlockStatus{
+				AllocatedAddresses: tc.allocated,
+			},
+		}
+
+		if actual := utilization(block); actual != tc.expected {
+			t.Errorf("%s with %s allocated: expected %q but got %q", tc.cidr, tc.allocated, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	tetrapodlabels "github.com/miscord-dev/tetrapod/tetrad/pkg/labels"
+)
+
+func runClaims(ctx context.Context, c client.Client, opts *options, args []string, w io.Writer) error {
+	namespace := opts.namespace
+
+	var peers controlplanev1alpha1.PeerNodeList
+	if err := c.List(ctx, &peers, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list PeerNodes: %w", err)
+	}
+
+	var claims controlplanev1alpha1.CIDRClaimList
+	if err := c.List(ctx, &claims, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list CIDRClaims: %w", err)
+	}
+
+	t := newTable(w, namespace, "NAME", "NODE", "TYPE", "TEMPLATE", "STATE", "CIDR")
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+
+		cidrs := make([]string, 0, len(claim.Status.FamilyStatuses()))
+		for _, s := range claim.Status.FamilyStatuses() {
+			cidrs = append(cidrs, s.CIDR)
+		}
+
+		t.row(
+			claim.Namespace,
+			claim.Name,
+			claimNode(claim, peers.Items),
+			claim.Labels[tetrapodlabels.TypeLabelKey],
+			claim.Labels[tetrapodlabels.TemplateNameLabelKey],
+			string(claim.Status.State),
+			strings.Join(cidrs, ","),
+		)
+	}
+
+	return t.flush()
+}
+
+// claimNode returns the name of the PeerNode whose claimsSelector selects the claim.
+// It falls back to the node label set by tetrad if no PeerNode selects it, e.g. before the node registers.
+func claimNode(claim *controlplanev1alpha1.CIDRClaim, peers []controlplanev1alpha1.PeerNode) string {
+	for i := range peers {
+		peer := &peers[i]
+
+		// An empty selector selects all the claims
+		if peer.Namespace != claim.Namespace ||
+			len(peer.Spec.ClaimsSelector.MatchLabels) == 0 && len(peer.Spec.ClaimsSelector.MatchExpressions) == 0 {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(&peer.Spec.ClaimsSelector)
+		if err != nil {
+			continue
+		}
+
+		if selector.Matches(labels.Set(claim.Labels)) {
+			return peer.Name
+		}
+	}
+
+	return claim.Labels[tetrapodlabels.NodeLabelKey]
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func runExport(ctx context.Context, c client.Client, opts *options, args []string, w io.Writer) error {
+	var reservations controlplanev1alpha1.CIDRReservationList
+	if err := c.List(ctx, &reservations, client.InNamespace(opts.namespace)); err != nil {
+		return fmt.Errorf("failed to list CIDRReservations: %w", err)
+	}
+
+	var quotas controlplanev1alpha1.CIDRQuotaList
+	if err := c.List(ctx, &quotas, client.InNamespace(opts.namespace)); err != nil {
+		return fmt.Errorf("failed to list CIDRQuotas: %w", err)
+	}
+
+	var blocks controlplanev1alpha1.CIDRBlockList
+	if err := c.List(ctx, &blocks, client.InNamespace(opts.namespace)); err != nil {
+		return fmt.Errorf("failed to list CIDRBlocks: %w", err)
+	}
+
+	var blockClaims controlplanev1alpha1.CIDRBlockClaimList
+	if err := c.List(ctx, &blockClaims, client.InNamespace(opts.namespace)); err != nil {
+		return fmt.Errorf("failed to list CIDRBlockClaims: %w", err)
+	}
+
+	var claims controlplanev1alpha1.CIDRClaimList
+	if err := c.List(ctx, &claims, client.InNamespace(opts.namespace)); err != nil {
+		return fmt.Errorf("failed to list CIDRClaims: %w", err)
+	}
+
+	b, err := yaml.Marshal(newBackup(&backupObjects{
+		reservations: reservations.Items,
+		quotas:       quotas.Items,
+		blocks:       blocks.Items,
+		blockClaims:  blockClaims.Items,
+		claims:       claims.Items,
+	}, metav1.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup: %w", err)
+	}
+
+	if _, err := w.Write(b); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	return nil
+}
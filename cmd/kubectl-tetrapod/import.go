@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func runImport(ctx context.Context, c client.Client, opts *options, args []string, w io.Writer) error {
+	var (
+		b   []byte
+		err error
+	)
+	if args[0] == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	var backup ipamBackup
+	if err := yaml.UnmarshalStrict(b, &backup); err != nil {
+		return fmt.Errorf("failed to parse backup: %w", err)
+	}
+
+	return importBackup(ctx, c, &backup, opts.dryRun, w)
+}
+
+// importBackup creates the CIDRReservations, the CIDRQuotas, the CIDRBlocks, the CIDRBlockClaims,
+// the child CIDRBlocks of them and then the CIDRClaims, so that the owners are created before the
+// objects referring to them. The CIDRs bound to the claims before the backup are recorded in the
+// ledgers of the blocks as the prefixes retained for the claims before the claims are created,
+// so that the claims get them back whether they are created by import or by controllers like tetrad.
+// Objects which already exist are left as they are so that it can be retried.
+func importBackup(ctx context.Context, c client.Client, backup *ipamBackup, dryRun bool, w io.Writer) error {
+	if err := backup.validate(); err != nil {
+		return fmt.Errorf("invalid backup: %w", err)
+	}
+
+	var (
+		createOpts []client.CreateOption
+		patchOpts  []client.SubResourcePatchOption
+	)
+	suffix := ""
+	if dryRun {
+		createOpts = append(createOpts, client.DryRunAll)
+		patchOpts = append(patchOpts, client.DryRunAll)
+		suffix = " (dry run)"
+	}
+
+	// get returns false if the object does not exist
+	get := func(obj client.Object, kind string) (bool, error) {
+		err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get %s %s/%s: %w", kind, obj.GetNamespace(), obj.GetName(), err)
+		}
+
+		return true, nil
+	}
+
+	// create creates the object with the references to the owners unless it exists
+	create := func(obj client.Object, kind string, owners []backupOwner) error {
+		exists, err := get(obj.DeepCopyObject().(client.Object), kind)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			fmt.Fprintf(w, "%s %s/%s unchanged\n", kind, obj.GetNamespace(), obj.GetName())
+
+			return nil
+		}
+
+		refs, err := ownerReferences(ctx, c, kind, client.ObjectKeyFromObject(obj), owners, w)
+		if err != nil {
+			return err
+		}
+		obj.SetOwnerReferences(refs)
+
+		if err := c.Create(ctx, obj, createOpts...); err != nil {
+			return fmt.Errorf("failed to create %s %s/%s: %w", kind, obj.GetNamespace(), obj.GetName(), err)
+		}
+		fmt.Fprintf(w, "%s %s/%s created%s\n", kind, obj.GetNamespace(), obj.GetName(), suffix)
+
+		return nil
+	}
+
+	// retain records the CIDRs in the backup bound to the claims in the blocks as the prefixes retained for them
+	retain := func(blocks []backupBlock) error {
+		now := metav1.Now()
+
+		for _, b := range blocks {
+			var block controlplanev1alpha1.CIDRBlock
+			block.Namespace, block.Name = b.Namespace, b.Name
+
+			exists, err := get(&block, "cidrblock")
+			if err != nil {
+				return err
+			}
+			if !exists {
+				continue
+			}
+
+			var retained []controlplanev1alpha1.CIDRBlockReleasedPrefix
+			for i := range backup.Claims {
+				claim := &backup.Claims[i]
+
+				for j := range claim.CIDRs {
+					s := &claim.CIDRs[j]
+
+					if claim.blockKey(s) != b.key() {
+						continue
+					}
+
+					var existing controlplanev1alpha1.CIDRClaim
+					existing.Namespace, existing.Name = claim.Namespace, claim.Name
+
+					exists, err := get(&existing, "cidrclaim")
+					if err != nil {
+						return err
+					}
+
+					if exists {
+						if bound := claim.boundStatus(&existing, s); bound != nil {
+							if bound.CIDR != s.CIDR {
+								fmt.Fprintf(w, "cidrclaim %s is bound to %s instead of %s\n", claim.key(), bound.CIDR, s.CIDR)
+							}
+
+							continue
+						}
+					}
+
+					recorded, usedBy := findInLedger(&block, claim, s.CIDR)
+					if usedBy != "" {
+						fmt.Fprintf(w, "%s of cidrclaim %s is used by %s in cidrblock %s\n", s.CIDR, claim.key(), usedBy, b.key())
+					}
+					if recorded || usedBy != "" {
+						continue
+					}
+
+					retained = append(retained, controlplanev1alpha1.CIDRBlockReleasedPrefix{
+						ClaimName:      claim.Name,
+						ClaimNamespace: claim.Namespace,
+						CIDR:           s.CIDR,
+						ReleasedAt:     now,
+						Retained:       true,
+					})
+				}
+			}
+
+			if len(retained) == 0 {
+				continue
+			}
+
+			base := block.DeepCopy()
+			block.Status.ReleasedPrefixes = append(block.Status.ReleasedPrefixes, retained...)
+			block.Status.LedgerVersion++
+
+			if err := c.Status().Patch(ctx, &block, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}), patchOpts...); err != nil {
+				return fmt.Errorf("failed to retain prefixes in cidrblock %s: %w", b.key(), err)
+			}
+			fmt.Fprintf(w, "cidrblock %s retains %d prefixes%s\n", b.key(), len(retained), suffix)
+		}
+
+		return nil
+	}
+
+	for _, b := range backup.Reservations {
+		reservation := &controlplanev1alpha1.CIDRReservation{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: b.Namespace,
+				Name:      b.Name,
+				Labels:    b.Labels,
+			},
+			Spec: b.Spec,
+		}
+
+		if err := create(reservation, "cidrreservation", nil); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range backup.Quotas {
+		quota := &controlplanev1alpha1.CIDRQuota{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: b.Namespace,
+				Name:      b.Name,
+				Labels:    b.Labels,
+			},
+			Spec: b.Spec,
+		}
+
+		if err := create(quota, "cidrquota", nil); err != nil {
+			return err
+		}
+	}
+
+	var parents, children []backupBlock
+	for _, b := range backup.Blocks {
+		if b.blockClaim() != "" {
+			children = append(children, b)
+
+			continue
+		}
+		parents = append(parents, b)
+
+		if err := create(b.object(), "cidrblock", b.Owners); err != nil {
+			return err
+		}
+	}
+
+	// The prefixes of the CIDRBlockClaims are retained before they are claimed again
+	if err := retain(parents); err != nil {
+		return err
+	}
+
+	for _, b := range backup.BlockClaims {
+		blockClaim := &controlplanev1alpha1.CIDRBlockClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: b.Namespace,
+				Name:      b.Name,
+				Labels:    b.Labels,
+			},
+			Spec: b.Spec,
+		}
+
+		if err := create(blockClaim, "cidrblockclaim", b.Owners); err != nil {
+			return err
+		}
+	}
+
+	// The webhook accepts the child CIDRBlocks of CIDRBlockClaims once their prefixes
+	// are allocated from the parents again
+	waiting := map[types.NamespacedName]bool{}
+	for _, b := range children {
+		block := b.object()
+
+		exists, err := get(block.DeepCopy(), "cidrblock")
+		if err != nil {
+			return err
+		}
+		if exists {
+			fmt.Fprintf(w, "cidrblock %s unchanged\n", b.key())
+
+			continue
+		}
+
+		if block.OwnerReferences, err = ownerReferences(ctx, c, "cidrblock", b.key(), b.Owners, w); err != nil {
+			return err
+		}
+
+		carved, err := isCarved(ctx, c, block, backup.Blocks)
+		if err != nil {
+			return err
+		}
+		if !carved {
+			fmt.Fprintf(w, "cidrblock %s waits for cidrblockclaim %s/%s to be bound\n", b.key(), b.Namespace, b.blockClaim())
+			waiting[b.key()] = true
+
+			continue
+		}
+
+		if err := c.Create(ctx, block, createOpts...); err != nil {
+			return fmt.Errorf("failed to create cidrblock %s: %w", b.key(), err)
+		}
+		fmt.Fprintf(w, "cidrblock %s created%s\n", b.key(), suffix)
+	}
+
+	if err := retain(children); err != nil {
+		return err
+	}
+
+	// The claims bound to the waiting blocks are not created until the prefixes are retained in them
+	for i := range backup.Claims {
+		b := &backup.Claims[i]
+
+		var blocked types.NamespacedName
+		for j := range b.CIDRs {
+			if key := b.blockKey(&b.CIDRs[j]); waiting[key] {
+				blocked = key
+			}
+		}
+
+		if blocked.Name != "" {
+			fmt.Fprintf(w, "cidrclaim %s waits for cidrblock %s\n", b.key(), blocked)
+
+			continue
+		}
+
+		claim := &controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   b.Namespace,
+				Name:        b.Name,
+				Labels:      b.Labels,
+				Annotations: b.Annotations,
+			},
+			Spec: b.Spec,
+		}
+
+		if err := create(claim, "cidrclaim", b.Owners); err != nil {
+			return err
+		}
+	}
+
+	if len(waiting) != 0 {
+		fmt.Fprintf(w, "%d cidrblocks wait for their cidrblockclaims. Run import again after they are bound.\n", len(waiting))
+	}
+
+	return nil
+}
+
+// isCarved returns true if the prefix of the child block is allocated to its CIDRBlockClaim
+// from one of the parent blocks in the backup
+func isCarved(ctx context.Context, c client.Client, block *controlplanev1alpha1.CIDRBlock, blocks []backupBlock) (bool, error) {
+	for _, b := range blocks {
+		if b.key() == client.ObjectKeyFromObject(block) {
+			continue
+		}
+
+		var parent controlplanev1alpha1.CIDRBlock
+		err := c.Get(ctx, b.key(), &parent)
+
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get cidrblock %s: %w", b.key(), err)
+		}
+
+		if block.IsCarvedFrom(&parent) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// ownerReferences returns the references to the owners of the object recreated after the backup.
+// Owners which do not exist are left out. The controllers like tetrad adopt the object again.
+func ownerReferences(ctx context.Context, c client.Client, kind string, key types.NamespacedName, owners []backupOwner, w io.Writer) ([]metav1.OwnerReference, error) {
+	var refs []metav1.OwnerReference
+	for _, o := range owners {
+		var owner metav1.PartialObjectMetadata
+		owner.SetGroupVersionKind(schema.FromAPIVersionAndKind(o.APIVersion, o.Kind))
+
+		err := c.Get(ctx, client.ObjectKey{Namespace: key.Namespace, Name: o.Name}, &owner)
+
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			fmt.Fprintf(w, "owner %s %s of %s %s not found\n", o.Kind, o.Name, kind, key)
+
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get owner %s %s of %s %s: %w", o.Kind, o.Name, kind, key, err)
+		}
+
+		controller, blockOwnerDeletion := o.Controller, o.BlockOwnerDeletion
+		refs = append(refs, metav1.OwnerReference{
+			APIVersion:         o.APIVersion,
+			Kind:               o.Kind,
+			Name:               o.Name,
+			UID:                owner.UID,
+			Controller:         &controller,
+			BlockOwnerDeletion: &blockOwnerDeletion,
+		})
+	}
+
+	return refs, nil
+}
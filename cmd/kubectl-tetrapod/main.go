@@ -0,0 +1,188 @@
+// kubectl-tetrapod is a kubectl plugin to inspect the mesh and IPAM of tetrapod.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+const usage = `kubectl-tetrapod inspects the mesh and IPAM of tetrapod.
+
+Usage:
+  kubectl tetrapod <command> [flags]
+
+Commands:
+  nodes       List PeerNodes with their endpoints, keys and addresses
+  claims      List CIDRClaims with their nodes, templates and CIDRs
+  blocks      List CIDRBlocks with their utilization
+  whois <ip>  Show the CIDRClaims and PeerNodes owning the address
+  export      Write CIDRReservations, CIDRQuotas, CIDRBlocks, CIDRBlockClaims and CIDRClaims
+              with their bound CIDRs to stdout
+  import <f>  Recreate the objects in the file written by export ("-" for stdin) with the CIDRs
+              retained for the CIDRClaims. Existing objects are left as they are. Run it again
+              after the CIDRBlockClaims are bound to restore the CIDRBlocks carved from them.
+
+Flags:
+`
+
+type options struct {
+	kubeconfig    string
+	context       string
+	namespace     string
+	allNamespaces bool
+	dryRun        bool
+}
+
+type command struct {
+	// run prints the result to w. opts.namespace is empty for all namespaces.
+	run func(ctx context.Context, c client.Client, opts *options, args []string, w io.Writer) error
+
+	// args is the number of the positional arguments
+	args int
+}
+
+var commands = map[string]command{
+	"nodes":  {run: runNodes},
+	"claims": {run: runClaims},
+	"blocks": {run: runBlocks},
+	"whois":  {run: runWhois, args: 1},
+	"export": {run: runExport},
+	"import": {run: runImport, args: 1},
+}
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdout, os.Stderr)
+
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	var opts options
+
+	fs := flag.NewFlagSet("kubectl-tetrapod", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&opts.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	fs.StringVar(&opts.context, "context", "", "The name of the kubeconfig context to use")
+	fs.StringVar(&opts.namespace, "namespace", "", "The namespace of PeerNodes and CIDRClaims (default: the namespace of the context)")
+	fs.StringVar(&opts.namespace, "n", "", "Shorthand for -namespace")
+	fs.BoolVar(&opts.allNamespaces, "all-namespaces", false, "List the objects across all namespaces")
+	fs.BoolVar(&opts.allNamespaces, "A", false, "Shorthand for -all-namespaces")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "Only print the objects import would create")
+
+	if len(args) == 0 {
+		fs.Usage()
+
+		return errUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		fs.Usage()
+
+		return errUsage
+	}
+
+	positional, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != cmd.args {
+		fmt.Fprintf(stderr, "%s expects %d positional arguments but got %d\n", args[0], cmd.args, len(positional))
+		fs.Usage()
+
+		return errUsage
+	}
+
+	c, namespace, err := newClient(&opts)
+	if err != nil {
+		return err
+	}
+	opts.namespace = namespace
+
+	return cmd.run(ctx, c, &opts, positional, stdout)
+}
+
+// parseInterspersed parses the flags placed before and after the positional arguments like kubectl
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// newClient returns a client for the kubeconfig and the namespace to inspect
+func newClient(opts *options) (client.Client, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = opts.kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: opts.context,
+	}
+	overrides.Context.Namespace = opts.namespace
+
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	namespace, _, err := config.Namespace()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get namespace: %w", err)
+	}
+	if opts.allNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+
+	scheme := runtime.NewScheme()
+	if err := controlplanev1alpha1.AddToScheme(scheme); err != nil {
+		return nil, "", fmt.Errorf("failed to set up scheme: %w", err)
+	}
+
+	c, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create client: %w", err)
+	}
+
+	return c, namespace, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func runNodes(ctx context.Context, c client.Client, opts *options, args []string, w io.Writer) error {
+	namespace := opts.namespace
+
+	var peers controlplanev1alpha1.PeerNodeList
+	if err := c.List(ctx, &peers, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list PeerNodes: %w", err)
+	}
+
+	var claims controlplanev1alpha1.CIDRClaimList
+	if err := c.List(ctx, &claims, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list CIDRClaims: %w", err)
+	}
+
+	t := newTable(w, namespace, "NAME", "STATE", "ENDPOINTS", "PUBLIC KEY", "DISCO KEY", "ADDRESSES")
+	for i := range peers.Items {
+		peer := &peers.Items[i]
+
+		t.row(
+			peer.Namespace,
+			peer.Name,
+			string(peer.Status.State),
+			strings.Join(peer.Spec.Endpoints, ","),
+			peer.Spec.PublicKey,
+			peer.Spec.PublicDiscoKey,
+			strings.Join(nodeAddresses(peer, claims.Items), ","),
+		)
+	}
+
+	return t.flush()
+}
+
+// nodeAddresses returns the addresses assigned to the wireguard interface of the node
+// in the same way as tetrad configures its peers
+func nodeAddresses(peer *controlplanev1alpha1.PeerNode, claims []controlplanev1alpha1.CIDRClaim) []string {
+	selector, err := metav1.LabelSelectorAsSelector(&peer.Spec.AddressesSelector)
+	if err != nil {
+		return nil
+	}
+
+	var addresses []string
+	for _, claim := range claims {
+		if claim.Namespace != peer.Namespace ||
+			claim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady ||
+			!selector.Matches(labels.Set(claim.Labels)) {
+			continue
+		}
+
+		for _, s := range claim.Status.AllocatedStatuses() {
+			addresses = append(addresses, s.CIDR)
+		}
+	}
+
+	return addresses
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// table prints rows aligned like kubectl get.
+// The NAMESPACE column is prepended when the objects are listed across all namespaces.
+type table struct {
+	w             *tabwriter.Writer
+	withNamespace bool
+}
+
+func newTable(w io.Writer, namespace string, columns ...string) *table {
+	t := &table{
+		w:             tabwriter.NewWriter(w, 0, 8, 3, ' ', 0),
+		withNamespace: namespace == "",
+	}
+
+	t.row("NAMESPACE", columns...)
+
+	return t
+}
+
+func (t *table) row(namespace string, values ...string) {
+	if t.withNamespace {
+		values = append([]string{namespace}, values...)
+	}
+
+	for i := range values {
+		if values[i] == "" {
+			values[i] = "<none>"
+		}
+	}
+
+	fmt.Fprintln(t.w, strings.Join(values, "\t"))
+}
+
+func (t *table) flush() error {
+	return t.w.Flush()
+}
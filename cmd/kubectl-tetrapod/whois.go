@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func runWhois(ctx context.Context, c client.Client, opts *options, args []string, w io.Writer) error {
+	namespace := opts.namespace
+
+	ip, err := ipaddr.NewIPAddressString(args[0]).ToAddress()
+	if err != nil {
+		return fmt.Errorf("invalid IP address %s: %w", args[0], err)
+	}
+
+	var peers controlplanev1alpha1.PeerNodeList
+	if err := c.List(ctx, &peers, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list PeerNodes: %w", err)
+	}
+
+	var claims controlplanev1alpha1.CIDRClaimList
+	if err := c.List(ctx, &claims, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list CIDRClaims: %w", err)
+	}
+
+	owners := whois(ip, claims.Items, peers.Items)
+	if len(owners) == 0 {
+		return fmt.Errorf("no CIDRClaim or PeerNode owns %s", args[0])
+	}
+
+	// Owners may be in other namespaces than the current one with shared CIDRBlocks
+	t := newTable(w, "", "CLAIM", "NODE", "CIDR", "BLOCK", "STATE")
+	for _, o := range owners {
+		t.row(o.namespace, o.claim, o.node, o.cidr, o.block, o.state)
+	}
+
+	return t.flush()
+}
+
+// owner is a CIDRClaim or a static route of a PeerNode whose prefix contains an address
+type owner struct {
+	namespace string
+	claim     string
+	node      string
+	cidr      string
+	block     string
+	state     string
+}
+
+const (
+	ownerStateDraining    = "draining"
+	ownerStateStaticRoute = "staticRoute"
+)
+
+// whois returns the owners of the address including the prefixes being drained
+func whois(ip *ipaddr.IPAddress, claims []controlplanev1alpha1.CIDRClaim, peers []controlplanev1alpha1.PeerNode) []owner {
+	var owners []owner
+
+	for i := range claims {
+		claim := &claims[i]
+
+		add := func(s controlplanev1alpha1.CIDRClaimFamilyStatus, state string) {
+			if !contains(s.CIDR, ip) {
+				return
+			}
+
+			block := s.CIDRBlockName
+			if s.CIDRBlockNamespace != "" {
+				block = s.CIDRBlockNamespace + "/" + block
+			}
+
+			owners = append(owners, owner{
+				namespace: claim.Namespace,
+				claim:     claim.Name,
+				node:      claimNode(claim, peers),
+				cidr:      s.CIDR,
+				block:     block,
+				state:     state,
+			})
+		}
+
+		for _, s := range claim.Status.FamilyStatuses() {
+			add(s, string(claim.Status.State))
+		}
+		for _, d := range claim.Status.Draining {
+			add(d.CIDRClaimFamilyStatus, ownerStateDraining)
+		}
+	}
+
+	for _, peer := range peers {
+		for _, route := range peer.Spec.StaticRoutes {
+			if !contains(route, ip) {
+				continue
+			}
+
+			owners = append(owners, owner{
+				namespace: peer.Namespace,
+				node:      peer.Name,
+				cidr:      route,
+				state:     ownerStateStaticRoute,
+			})
+		}
+	}
+
+	return owners
+}
+
+// contains returns true if the prefix contains the address. Addresses of other families are not contained.
+func contains(prefix string, ip *ipaddr.IPAddress) bool {
+	addr := ipaddr.NewIPAddressString(prefix).GetAddress()
+
+	return addr != nil && addr.ToPrefixBlock().Contains(ip)
+}
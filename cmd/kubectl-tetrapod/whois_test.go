@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	tetrapodlabels "github.com/miscord-dev/tetrapod/tetrad/pkg/labels"
+)
+
+func TestWhois(t *testing.T) {
+	peers := []controlplanev1alpha1.PeerNode{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-node-001", Namespace: "tetrapod"},
+			Spec: controlplanev1alpha1.PeerNodeSpec{
+				ClaimsSelector: metav1.LabelSelector{
+					MatchLabels: tetrapodlabels.ForNode("cluster", "node-001"),
+				},
+				StaticRoutes: []string{"10.0.0.0/24"},
+			},
+		},
+	}
+
+	claims := []controlplanev1alpha1.CIDRClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "node-001-pod-cidr",
+				Namespace: "tetrapod",
+				Labels:    tetrapodlabels.PodCIDRTypeForNode("cluster", "node-001", ""),
+			},
+			Status: controlplanev1alpha1.CIDRClaimStatus{
+				State: controlplanev1alpha1.CIDRClaimStatusStateReady,
+				CIDRs: []controlplanev1alpha1.CIDRClaimFamilyStatus{
+					{Family: controlplanev1alpha1.AddressFamilyIPv4, CIDRBlockName: "pods", CIDR: "192.168.1.0/28"},
+					{Family: controlplanev1alpha1.AddressFamilyIPv6, CIDRBlockName: "pods-v6", CIDRBlockNamespace: "shared", CIDR: "fd00::/64"},
+				},
+				Draining: []controlplanev1alpha1.CIDRClaimDrainingStatus{
+					{
+						CIDRClaimFamilyStatus: controlplanev1alpha1.CIDRClaimFamilyStatus{
+							Family: controlplanev1alpha1.AddressFamilyIPv4, CIDRBlockName: "pods", CIDR: "192.168.2.0/28",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		ip       string
+		expected []owner
+	}{
+		{
+			ip: "192.168.1.15",
+			expected: []owner{
+				{namespace: "tetrapod", claim: "node-001-pod-cidr", node: "cluster-node-001", cidr: "192.168.1.0/28", block: "pods", state: "ready"},
+			},
+		},
+		{
+			ip: "fd00::1",
+			expected: []owner{
+				{namespace: "tetrapod", claim: "node-001-pod-cidr", node: "cluster-node-001", cidr: "fd00::/64", block: "shared/pods-v6", state: "ready"},
+			},
+		},
+		{
+			ip: "192.168.2.1",
+			expected: []owner{
+				{namespace: "tetrapod", claim: "node-001-pod-cidr", node: "cluster-node-001", cidr: "192.168.2.0/28", block: "pods", state: ownerStateDraining},
+			},
+		},
+		{
+			ip: "10.0.0.1",
+			expected: []owner{
+				{namespace: "tetrapod", node: "cluster-node-001", cidr: "10.0.0.0/24", state: ownerStateStaticRoute},
+			},
+		},
+		{
+			ip: "192.168.1.16",
+		},
+	} {
+		owners := whois(ipaddr.NewIPAddressString(tc.ip).GetAddress(), claims, peers)
+
+		if len(owners) != len(tc.expected) {
+			t.Errorf("%s: expected %+v but got %+v", tc.ip, tc.expected, owners)
+
+			continue
+		}
+		for i := range owners {
+			if owners[i] != tc.expected[i] {
+				t.Errorf("%s: expected %+v but got %+v", tc.ip, tc.expected[i], owners[i])
+			}
+		}
+	}
+}
+
+func TestClaimNode(t *testing.T) {
+	claim := &controlplanev1alpha1.CIDRClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "node-002",
+			Namespace: "tetrapod",
+			Labels:    tetrapodlabels.NodeTypeForNode("cluster", "node-002", ""),
+		},
+	}
+
+	peers := []controlplanev1alpha1.PeerNode{
+		{
+			// An empty selector is not regarded as selecting the claim
+			ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "tetrapod"},
+		},
+	}
+
+	if node := claimNode(claim, peers); node != "node-002" {
+		t.Errorf("expected the node label but got %s", node)
+	}
+
+	peers = append(peers, controlplanev1alpha1.PeerNode{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-node-002", Namespace: "tetrapod"},
+		Spec: controlplanev1alpha1.PeerNodeSpec{
+			ClaimsSelector: metav1.LabelSelector{
+				MatchLabels: tetrapodlabels.ForNode("cluster", "node-002"),
+			},
+		},
+	})
+
+	if node := claimNode(claim, peers); node != "cluster-node-002" {
+		t.Errorf("expected the PeerNode but got %s", node)
+	}
+}
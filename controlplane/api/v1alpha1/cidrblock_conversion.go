@@ -0,0 +1,111 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/miscord-dev/tetrapod/controlplane/api/v1beta1"
+)
+
+// ConvertTo converts this CIDRBlock to the Hub version (v1beta1).
+func (src *CIDRBlock) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.CIDRBlock)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1beta1.CIDRBlockSpec{
+		CIDR:               src.Spec.CIDR,
+		AllocationStrategy: v1beta1.AllocationStrategy(src.Spec.AllocationStrategy),
+		Excludes:           src.Spec.Excludes,
+		QuarantinePeriod:   src.Spec.QuarantinePeriod,
+		NamespaceSelector:  src.Spec.NamespaceSelector,
+	}
+	dst.Status = v1beta1.CIDRBlockStatus{
+		Allocations: convertSlice(src.Status.Allocations, func(a CIDRBlockAllocation) v1beta1.CIDRBlockAllocation {
+			return v1beta1.CIDRBlockAllocation(a)
+		}),
+		ReleasedPrefixes: convertSlice(src.Status.ReleasedPrefixes, func(p CIDRBlockReleasedPrefix) v1beta1.CIDRBlockReleasedPrefix {
+			return v1beta1.CIDRBlockReleasedPrefix(p)
+		}),
+		LedgerVersion:      src.Status.LedgerVersion,
+		ReservedCIDRs:      src.Status.ReservedCIDRs,
+		BoundClaims:        src.Status.BoundClaims,
+		AllocatedAddresses: src.Status.AllocatedAddresses,
+		FreeAddresses:      src.Status.FreeAddresses,
+		LargestFreePrefixes: convertSlice(src.Status.LargestFreePrefixes, func(c FamilyCIDR) v1beta1.FamilyCIDR {
+			return v1beta1.FamilyCIDR{
+				Family: v1beta1.AddressFamily(c.Family),
+				CIDR:   c.CIDR,
+			}
+		}),
+		FreePrefixes:   src.Status.FreePrefixes,
+		BlockingClaims: src.Status.BlockingClaims,
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version.
+func (dst *CIDRBlock) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.CIDRBlock)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = CIDRBlockSpec{
+		CIDR:               src.Spec.CIDR,
+		AllocationStrategy: AllocationStrategy(src.Spec.AllocationStrategy),
+		Excludes:           src.Spec.Excludes,
+		QuarantinePeriod:   src.Spec.QuarantinePeriod,
+		NamespaceSelector:  src.Spec.NamespaceSelector,
+	}
+	dst.Status = CIDRBlockStatus{
+		Allocations: convertSlice(src.Status.Allocations, func(a v1beta1.CIDRBlockAllocation) CIDRBlockAllocation {
+			return CIDRBlockAllocation(a)
+		}),
+		ReleasedPrefixes: convertSlice(src.Status.ReleasedPrefixes, func(p v1beta1.CIDRBlockReleasedPrefix) CIDRBlockReleasedPrefix {
+			return CIDRBlockReleasedPrefix(p)
+		}),
+		LedgerVersion:      src.Status.LedgerVersion,
+		ReservedCIDRs:      src.Status.ReservedCIDRs,
+		BoundClaims:        src.Status.BoundClaims,
+		AllocatedAddresses: src.Status.AllocatedAddresses,
+		FreeAddresses:      src.Status.FreeAddresses,
+		LargestFreePrefixes: convertSlice(src.Status.LargestFreePrefixes, func(c v1beta1.FamilyCIDR) FamilyCIDR {
+			return FamilyCIDR{
+				Family: AddressFamily(c.Family),
+				CIDR:   c.CIDR,
+			}
+		}),
+		FreePrefixes:   src.Status.FreePrefixes,
+		BlockingClaims: src.Status.BlockingClaims,
+	}
+
+	return nil
+}
+
+// convertSlice converts each item of s keeping nil slices nil so that conversions round-trip
+func convertSlice[T, U any](s []T, convert func(T) U) []U {
+	if s == nil {
+		return nil
+	}
+
+	converted := make([]U, 0, len(s))
+	for _, item := range s {
+		converted = append(converted, convert(item))
+	}
+
+	return converted
+}
@@ -17,7 +17,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -30,6 +33,112 @@ type CIDRBlockSpec struct {
 
 	// CIDR represents the block of asiggned addresses like 192.168.1.0/24, [fe80::]/32
 	CIDR string `json:"cidr"`
+
+	// AllocationStrategy decides which free prefix is allocated to CIDRClaims
+	// +kubebuilder:default=FirstFit
+	// +optional
+	AllocationStrategy AllocationStrategy `json:"allocationStrategy,omitempty"`
+
+	// Excludes lists the CIDRs in the block never allocated to CIDRClaims like gateways and VIPs
+	// +optional
+	Excludes []string `json:"excludes,omitempty"`
+
+	// QuarantinePeriod is how long released prefixes are kept from being allocated again
+	// so that stale routes and caches referring to them expire first
+	// +optional
+	QuarantinePeriod *metav1.Duration `json:"quarantinePeriod,omitempty"`
+
+	// NamespaceSelector shares the block with CIDRClaims in the namespaces whose labels match it.
+	// The block serves only CIDRClaims in its own namespace if it is not set. An empty selector
+	// matches all namespaces. Shared blocks must not overlap CIDRBlocks in any namespace.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// AllocationStrategy represents how prefixes are picked from free blocks
+// +kubebuilder:validation:Enum=FirstFit;BestFit;Sequential;Random
+type AllocationStrategy string
+
+const (
+	// AllocationStrategyFirstFit picks the first free prefix
+	AllocationStrategyFirstFit AllocationStrategy = "FirstFit"
+
+	// AllocationStrategyBestFit picks a prefix from the smallest free block that fits
+	AllocationStrategyBestFit AllocationStrategy = "BestFit"
+
+	// AllocationStrategySequential picks the next free prefix after the last allocated one
+	AllocationStrategySequential AllocationStrategy = "Sequential"
+
+	// AllocationStrategyRandom picks a free prefix at random
+	AllocationStrategyRandom AllocationStrategy = "Random"
+)
+
+// AddressFamily represents the family of IP addresses
+// +kubebuilder:validation:Enum=IPv4;IPv6
+type AddressFamily string
+
+const (
+	// AddressFamilyIPv4 represents IPv4
+	AddressFamilyIPv4 AddressFamily = "IPv4"
+
+	// AddressFamilyIPv6 represents IPv6
+	AddressFamilyIPv6 AddressFamily = "IPv6"
+)
+
+// FamilyCIDR is a CIDR with its address family
+type FamilyCIDR struct {
+	// Family is the address family of CIDR
+	Family AddressFamily `json:"family"`
+
+	// CIDR represents the block of addresses like 192.168.1.0/24, [fe80::]/32
+	CIDR string `json:"cidr"`
+}
+
+// CIDRBlockAllocation is a record of a prefix allocated from a CIDRBlock
+type CIDRBlockAllocation struct {
+	// ClaimName is the name of the CIDRClaim the prefix is allocated to
+	ClaimName string `json:"claimName"`
+
+	// ClaimNamespace is the namespace of the CIDRClaim the prefix is allocated to.
+	// It is empty for allocations recorded before CIDRBlocks were shared across namespaces.
+	// +optional
+	ClaimNamespace string `json:"claimNamespace,omitempty"`
+
+	// ClaimUID is the UID of the CIDRClaim the prefix is allocated to
+	ClaimUID types.UID `json:"claimUID,omitempty"`
+
+	// CIDR is the allocated prefix
+	CIDR string `json:"cidr"`
+
+	// ExternalID is the ID of the prefix reserved in the external IPAM
+	// +optional
+	ExternalID string `json:"externalID,omitempty"`
+}
+
+// CIDRBlockReleasedPrefix is a record of a prefix released by a CIDRClaim which is
+// not allocatable yet
+type CIDRBlockReleasedPrefix struct {
+	// ClaimName is the name of the CIDRClaim the prefix was allocated to
+	ClaimName string `json:"claimName"`
+
+	// ClaimNamespace is the namespace of the CIDRClaim the prefix was allocated to
+	// +optional
+	ClaimNamespace string `json:"claimNamespace,omitempty"`
+
+	// ClaimLabels are the labels of the CIDRClaim the prefix was allocated to
+	// +optional
+	ClaimLabels map[string]string `json:"claimLabels,omitempty"`
+
+	// CIDR is the released prefix
+	CIDR string `json:"cidr"`
+
+	// ReleasedAt is when the prefix was released
+	ReleasedAt metav1.Time `json:"releasedAt"`
+
+	// Retained is true if the prefix is reserved for a CIDRClaim with the same name or labels
+	// until it is claimed again or the record is removed
+	// +optional
+	Retained bool `json:"retained,omitempty"`
 }
 
 // CIDRBlockStatus defines the observed state of CIDRBlock
@@ -37,11 +146,49 @@ type CIDRBlockStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
+	// Allocations is the authoritative ledger of prefixes allocated from the block.
+	// It is only updated with optimistic concurrency so that a prefix is never
+	// allocated twice.
+	Allocations []CIDRBlockAllocation `json:"allocations,omitempty"`
+
+	// ReleasedPrefixes are the prefixes released by CIDRClaims which are quarantined
+	// or retained. They are not allocated to other CIDRClaims.
+	ReleasedPrefixes []CIDRBlockReleasedPrefix `json:"releasedPrefixes,omitempty"`
+
+	// LedgerVersion is incremented each time Allocations or ReleasedPrefixes are
+	// updated so that changes of the ledger can be told from other status updates.
+	// +optional
+	LedgerVersion int64 `json:"ledgerVersion,omitempty"`
+
+	// ReservedCIDRs lists the CIDRs in the block excluded by the spec or CIDRReservations
+	ReservedCIDRs []string `json:"reservedCIDRs,omitempty"`
+
+	// BoundClaims is the number of CIDRClaims bound to the block
+	BoundClaims int `json:"boundClaims"`
+
+	// AllocatedAddresses is the number of addresses allocated to CIDRClaims.
+	// It is a decimal string because IPv6 blocks can exceed int64.
+	AllocatedAddresses string `json:"allocatedAddresses,omitempty"`
+
+	// FreeAddresses is the number of addresses neither allocated nor reserved.
+	// It is a decimal string because IPv6 blocks can exceed int64.
+	FreeAddresses string `json:"freeAddresses,omitempty"`
+
+	// LargestFreePrefixes are the largest free prefixes per address family
+	LargestFreePrefixes []FamilyCIDR `json:"largestFreePrefixes,omitempty"`
+
+	// FreePrefixes are the unallocated prefixes in the block
+	FreePrefixes []string `json:"freePrefixes,omitempty"`
+
+	// BlockingClaims lists the CIDRClaims still bound to the block while it is being deleted
+	BlockingClaims []string `json:"blockingClaims,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="CIDR",type=string,JSONPath=`.spec.cidr`
+//+kubebuilder:printcolumn:name="Claims",type=integer,JSONPath=`.status.boundClaims`
+//+kubebuilder:printcolumn:name="Free",type=string,JSONPath=`.status.freeAddresses`
 
 // CIDRBlock is the Schema for the cidrblocks API
 type CIDRBlock struct {
@@ -52,6 +199,59 @@ type CIDRBlock struct {
 	Status CIDRBlockStatus `json:"status,omitempty"`
 }
 
+// IsCarvedFrom returns true if the CIDRBlock is the child of a CIDRBlockClaim whose prefix
+// is allocated from the parent, i.e. the ledger of the parent has an allocation to the CIDRClaim
+// of the CIDRBlockClaim containing the block. The CIDRClaims created before they were named by
+// BlockClaimCIDRClaimName have the same name as the CIDRBlockClaims.
+func (r *CIDRBlock) IsCarvedFrom(parent *CIDRBlock) bool {
+	owner := metav1.GetControllerOf(r)
+
+	if owner == nil || owner.Kind != "CIDRBlockClaim" || owner.APIVersion != GroupVersion.String() {
+		return false
+	}
+
+	addr, err := parsePrefix(r.Spec.CIDR)
+	if err != nil {
+		return false
+	}
+
+	for _, a := range parent.Status.Allocations {
+		namespace := a.ClaimNamespace
+		if namespace == "" {
+			namespace = parent.Namespace
+		}
+
+		if (a.ClaimName != owner.Name && a.ClaimName != BlockClaimCIDRClaimName(owner.Name)) || namespace != r.Namespace {
+			continue
+		}
+
+		if allocated, err := parsePrefix(a.CIDR); err == nil && allocated.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ServesNamespace returns true if the block serves CIDRClaims in the namespace, i.e. the block
+// is in the namespace or shared with it by the namespace selector
+func (r *CIDRBlock) ServesNamespace(namespace *corev1.Namespace) bool {
+	if r.Namespace == namespace.Name {
+		return true
+	}
+
+	if r.Spec.NamespaceSelector == nil {
+		return false
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(r.Spec.NamespaceSelector)
+	if err != nil {
+		return false
+	}
+
+	return selector.Matches(labels.Set(namespace.Labels))
+}
+
 //+kubebuilder:object:root=true
 
 // CIDRBlockList contains a list of CIDRBlock
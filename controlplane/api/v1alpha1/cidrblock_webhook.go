@@ -0,0 +1,170 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager sets up the webhooks with the Manager.
+func (r *CIDRBlock) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, r).
+		WithDefaulter(&CIDRBlockDefaulter{}).
+		WithValidator(&CIDRBlockValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-controlplane-miscord-win-v1alpha1-cidrblock,mutating=true,failurePolicy=fail,sideEffects=None,groups=controlplane.miscord.win,resources=cidrblocks,verbs=create;update,versions=v1alpha1,name=mcidrblock.kb.io,admissionReviewVersions=v1
+
+// CIDRBlockDefaulter sets the defaults of CIDRBlocks
+// +kubebuilder:object:generate=false
+type CIDRBlockDefaulter struct{}
+
+var _ admission.Defaulter[*CIDRBlock] = &CIDRBlockDefaulter{}
+
+// Default implements admission.Defaulter
+func (d *CIDRBlockDefaulter) Default(ctx context.Context, r *CIDRBlock) error {
+	if r.Spec.AllocationStrategy == "" {
+		r.Spec.AllocationStrategy = AllocationStrategyFirstFit
+	}
+
+	r.Spec.CIDR = normalizePrefix(r.Spec.CIDR)
+	for i := range r.Spec.Excludes {
+		r.Spec.Excludes[i] = normalizePrefix(r.Spec.Excludes[i])
+	}
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-controlplane-miscord-win-v1alpha1-cidrblock,mutating=false,failurePolicy=fail,sideEffects=None,groups=controlplane.miscord.win,resources=cidrblocks,verbs=create;update,versions=v1alpha1,name=vcidrblock.kb.io,admissionReviewVersions=v1
+
+// CIDRBlockValidator validates CIDRBlocks
+// +kubebuilder:object:generate=false
+type CIDRBlockValidator struct {
+	// Client is used to find other CIDRBlocks overlapping the block. It should read from
+	// the API server rather than a cache so that concurrent creations are not missed.
+	Client client.Reader
+}
+
+var _ admission.Validator[*CIDRBlock] = &CIDRBlockValidator{}
+
+// ValidateCreate implements admission.Validator
+func (v *CIDRBlockValidator) ValidateCreate(ctx context.Context, r *CIDRBlock) (admission.Warnings, error) {
+	return nil, v.validate(ctx, r)
+}
+
+// ValidateUpdate implements admission.Validator
+func (v *CIDRBlockValidator) ValidateUpdate(ctx context.Context, old, r *CIDRBlock) (admission.Warnings, error) {
+	return nil, v.validate(ctx, r)
+}
+
+// ValidateDelete implements admission.Validator
+func (v *CIDRBlockValidator) ValidateDelete(ctx context.Context, r *CIDRBlock) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *CIDRBlockValidator) validate(ctx context.Context, r *CIDRBlock) error {
+	path := field.NewPath("spec")
+
+	addr, err := validatePrefix(path.Child("cidr"), r.Spec.CIDR)
+	if err != nil {
+		return invalid("CIDRBlock", r.Name, field.ErrorList{err})
+	}
+
+	var errs field.ErrorList
+	for i, exclude := range r.Spec.Excludes {
+		path := path.Child("excludes").Index(i)
+
+		excluded, err := validatePrefix(path, exclude)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		if !addr.ToPrefixBlock().Contains(excluded) {
+			errs = append(errs, field.Invalid(path, exclude, fmt.Sprintf("must be in %s", addr)))
+		}
+	}
+
+	if q := r.Spec.QuarantinePeriod; q != nil && q.Duration < 0 {
+		errs = append(errs, field.Invalid(path.Child("quarantinePeriod"), q.Duration.String(), "must not be negative"))
+	}
+
+	errs = append(errs, validateSelector(path.Child("namespaceSelector"), r.Spec.NamespaceSelector)...)
+
+	// Shared blocks are checked against the blocks in all namespaces
+	var blocks CIDRBlockList
+	if err := v.Client.List(ctx, &blocks); err != nil {
+		return fmt.Errorf("failed to list CIDRBlocks: %w", err)
+	}
+
+	for _, block := range blocks.Items {
+		if block.Namespace == r.Namespace && block.Name == r.Name {
+			continue
+		}
+
+		if block.Namespace != r.Namespace && block.Spec.NamespaceSelector == nil && r.Spec.NamespaceSelector == nil {
+			continue
+		}
+
+		other, err := parsePrefix(block.Spec.CIDR)
+		if err != nil || !other.Overlaps(addr) {
+			continue
+		}
+
+		// Child blocks are carved from their parents by CIDRBlockClaims
+		if r.IsCarvedFrom(&block) && v.hasOwner(ctx, r) || block.IsCarvedFrom(r) {
+			continue
+		}
+
+		errs = append(errs, field.Invalid(
+			path.Child("cidr"), r.Spec.CIDR,
+			fmt.Sprintf("overlaps %s of CIDRBlock %s", other, blockName(&block, r.Namespace)),
+		))
+	}
+
+	return invalid("CIDRBlock", r.Name, errs)
+}
+
+// blockName returns the name of the block qualified with its namespace if it differs from namespace
+func blockName(block *CIDRBlock, namespace string) string {
+	if block.Namespace == namespace {
+		return block.Name
+	}
+
+	return block.Namespace + "/" + block.Name
+}
+
+// hasOwner returns true if the CIDRBlockClaim controlling the block exists
+func (v *CIDRBlockValidator) hasOwner(ctx context.Context, r *CIDRBlock) bool {
+	owner := metav1.GetControllerOf(r)
+
+	var blockClaim CIDRBlockClaim
+	if err := v.Client.Get(ctx, client.ObjectKey{Namespace: r.Namespace, Name: owner.Name}, &blockClaim); err != nil {
+		return false
+	}
+
+	return blockClaim.UID == owner.UID
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// CIDRBlockClaimSpec defines the desired state of CIDRBlockClaim
+type CIDRBlockClaimSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Selector is a labal selector of the parent CIDRBlocks
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// SizeBit is log2(the number of addresses in the child CIDRBlock)
+	// +kubebuilder:default=0
+	SizeBit int `json:"sizeBit"`
+
+	// Template is the template of the child CIDRBlock
+	Template CIDRBlockTemplate `json:"template,omitempty"`
+}
+
+// CIDRBlockTemplate describes the CIDRBlock created from a CIDRBlockClaim
+type CIDRBlockTemplate struct {
+	// Labels are the labels of the child CIDRBlock
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// AllocationStrategy decides which free prefix in the child CIDRBlock is allocated to CIDRClaims
+	// +optional
+	AllocationStrategy AllocationStrategy `json:"allocationStrategy,omitempty"`
+
+	// Excludes lists the CIDRs in the child CIDRBlock never allocated to CIDRClaims
+	// +optional
+	Excludes []string `json:"excludes,omitempty"`
+}
+
+// CIDRBlockClaimStatus defines the observed state of CIDRBlockClaim
+type CIDRBlockClaimStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// ObservedGeneration is the observed generation
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// State represents the current state
+	State CIDRClaimStatusState `json:"state"`
+
+	// Message is the error message
+	Message string `json:"message,omitempty"`
+
+	// CIDR is the prefix allocated from the parent CIDRBlock
+	CIDR string `json:"cidr,omitempty"`
+
+	// CIDRBlockName is the name of the child CIDRBlock
+	CIDRBlockName string `json:"name,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="CIDR",type=string,JSONPath=`.status.cidr`
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+
+// CIDRBlockClaim is the Schema for the cidrblockclaims API.
+// It allocates a prefix from parent CIDRBlocks and creates a child CIDRBlock with the prefix.
+type CIDRBlockClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CIDRBlockClaimSpec   `json:"spec,omitempty"`
+	Status CIDRBlockClaimStatus `json:"status,omitempty"`
+}
+
+// BlockClaimCIDRClaimName returns the name of the CIDRClaim allocating the prefix of the CIDRBlockClaim.
+// It differs from the name of the CIDRBlockClaim so that CIDRClaims of users are not taken over.
+func BlockClaimCIDRClaimName(blockClaimName string) string {
+	return blockClaimName + "-cidrblockclaim"
+}
+
+//+kubebuilder:object:root=true
+
+// CIDRBlockClaimList contains a list of CIDRBlockClaim
+type CIDRBlockClaimList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CIDRBlockClaim `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CIDRBlockClaim{}, &CIDRBlockClaimList{})
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/miscord-dev/tetrapod/controlplane/api/v1beta1"
+)
+
+// ConvertTo converts this CIDRClaim to the Hub version (v1beta1).
+func (src *CIDRClaim) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.CIDRClaim)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1beta1.CIDRClaimSpec{
+		Selector:      src.Spec.Selector,
+		SizeBit:       src.Spec.SizeBit,
+		MinSizeBit:    src.Spec.MinSizeBit,
+		RequestedCIDR: src.Spec.RequestedCIDR,
+		Families:      convertSlice(src.Spec.Families, claimFamilyToHub),
+		Preferred:     convertSlice(src.Spec.Preferred, preferredTermToHub),
+		ReclaimPolicy: v1beta1.ReclaimPolicy(src.Spec.ReclaimPolicy),
+		DrainPeriod:   src.Spec.DrainPeriod,
+	}
+	dst.Status = v1beta1.CIDRClaimStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		State:              v1beta1.CIDRClaimStatusState(src.Status.State),
+		Message:            src.Status.Message,
+		CIDRBlockName:      src.Status.CIDRBlockName,
+		CIDRBlockNamespace: src.Status.CIDRBlockNamespace,
+		CIDR:               src.Status.CIDR,
+		SizeBit:            src.Status.SizeBit,
+		CIDRs:              convertSlice(src.Status.CIDRs, familyStatusToHub),
+		Draining: convertSlice(src.Status.Draining, func(d CIDRClaimDrainingStatus) v1beta1.CIDRClaimDrainingStatus {
+			return v1beta1.CIDRClaimDrainingStatus{
+				CIDRClaimFamilyStatus: familyStatusToHub(d.CIDRClaimFamilyStatus),
+				ReleaseAt:             d.ReleaseAt,
+			}
+		}),
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version.
+func (dst *CIDRClaim) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.CIDRClaim)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = CIDRClaimSpec{
+		Selector:      src.Spec.Selector,
+		SizeBit:       src.Spec.SizeBit,
+		MinSizeBit:    src.Spec.MinSizeBit,
+		RequestedCIDR: src.Spec.RequestedCIDR,
+		Families:      convertSlice(src.Spec.Families, claimFamilyFromHub),
+		Preferred:     convertSlice(src.Spec.Preferred, preferredTermFromHub),
+		ReclaimPolicy: ReclaimPolicy(src.Spec.ReclaimPolicy),
+		DrainPeriod:   src.Spec.DrainPeriod,
+	}
+	dst.Status = CIDRClaimStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		State:              CIDRClaimStatusState(src.Status.State),
+		Message:            src.Status.Message,
+		CIDRBlockName:      src.Status.CIDRBlockName,
+		CIDRBlockNamespace: src.Status.CIDRBlockNamespace,
+		CIDR:               src.Status.CIDR,
+		SizeBit:            src.Status.SizeBit,
+		CIDRs:              convertSlice(src.Status.CIDRs, familyStatusFromHub),
+		Draining: convertSlice(src.Status.Draining, func(d v1beta1.CIDRClaimDrainingStatus) CIDRClaimDrainingStatus {
+			return CIDRClaimDrainingStatus{
+				CIDRClaimFamilyStatus: familyStatusFromHub(d.CIDRClaimFamilyStatus),
+				ReleaseAt:             d.ReleaseAt,
+			}
+		}),
+	}
+
+	return nil
+}
+
+func claimFamilyToHub(f CIDRClaimFamily) v1beta1.CIDRClaimFamily {
+	return v1beta1.CIDRClaimFamily{
+		Family:        v1beta1.AddressFamily(f.Family),
+		Selector:      f.Selector,
+		SizeBit:       f.SizeBit,
+		MinSizeBit:    f.MinSizeBit,
+		RequestedCIDR: f.RequestedCIDR,
+		Preferred:     convertSlice(f.Preferred, preferredTermToHub),
+	}
+}
+
+func claimFamilyFromHub(f v1beta1.CIDRClaimFamily) CIDRClaimFamily {
+	return CIDRClaimFamily{
+		Family:        AddressFamily(f.Family),
+		Selector:      f.Selector,
+		SizeBit:       f.SizeBit,
+		MinSizeBit:    f.MinSizeBit,
+		RequestedCIDR: f.RequestedCIDR,
+		Preferred:     convertSlice(f.Preferred, preferredTermFromHub),
+	}
+}
+
+func preferredTermToHub(t PreferredCIDRBlockTerm) v1beta1.PreferredCIDRBlockTerm {
+	return v1beta1.PreferredCIDRBlockTerm(t)
+}
+
+func preferredTermFromHub(t v1beta1.PreferredCIDRBlockTerm) PreferredCIDRBlockTerm {
+	return PreferredCIDRBlockTerm(t)
+}
+
+func familyStatusToHub(s CIDRClaimFamilyStatus) v1beta1.CIDRClaimFamilyStatus {
+	return v1beta1.CIDRClaimFamilyStatus{
+		Family:             v1beta1.AddressFamily(s.Family),
+		CIDRBlockName:      s.CIDRBlockName,
+		CIDRBlockNamespace: s.CIDRBlockNamespace,
+		CIDR:               s.CIDR,
+		SizeBit:            s.SizeBit,
+		ExternalID:         s.ExternalID,
+	}
+}
+
+func familyStatusFromHub(s v1beta1.CIDRClaimFamilyStatus) CIDRClaimFamilyStatus {
+	return CIDRClaimFamilyStatus{
+		Family:             AddressFamily(s.Family),
+		CIDRBlockName:      s.CIDRBlockName,
+		CIDRBlockNamespace: s.CIDRBlockNamespace,
+		CIDR:               s.CIDR,
+		SizeBit:            s.SizeBit,
+		ExternalID:         s.ExternalID,
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -31,9 +33,105 @@ type CIDRClaimSpec struct {
 	// Selector is a labal selector of CIDRBlock
 	Selector metav1.LabelSelector `json:"selector"`
 
-	// SizeBit is log2(the number of requested addresses)
+	// SizeBit is log2(the number of requested addresses).
+	// It is the largest acceptable size if MinSizeBit is set.
+	// +kubebuilder:default=0
+	SizeBit int `json:"sizeBit"`
+
+	// MinSizeBit is log2(the smallest acceptable number of addresses).
+	// The largest available prefix between MinSizeBit and SizeBit is allocated
+	// and the granted size is reported in the status.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinSizeBit *int `json:"minSizeBit,omitempty"`
+
+	// RequestedCIDR pins the allocation to the prefix like 192.168.1.1/32, [fe80::]/64.
+	// It is allocated only if it is free in a matching CIDRBlock.
+	// SizeBit is ignored when it is set.
+	// +optional
+	RequestedCIDR string `json:"requestedCIDR,omitempty"`
+
+	// Families requests one allocation per address family.
+	// Selector, SizeBit and RequestedCIDR are ignored when it is set.
+	// +optional
+	Families []CIDRClaimFamily `json:"families,omitempty"`
+
+	// Preferred lists the preferences of CIDRBlocks matching Selector.
+	// The prefix is allocated from the block with the highest sum of the weights of
+	// the matching terms first. Blocks with the same score are tried in the order of their names.
+	// +optional
+	Preferred []PreferredCIDRBlockTerm `json:"preferred,omitempty"`
+
+	// ReclaimPolicy decides what happens to the allocated prefixes when they are released
+	// +kubebuilder:default=Delete
+	// +optional
+	ReclaimPolicy ReclaimPolicy `json:"reclaimPolicy,omitempty"`
+
+	// DrainPeriod enables renumbering without breaking live connections.
+	// When the allocation is changed, e.g. by an update of the selector or SizeBit, the previous
+	// prefix is kept allocated and advertised alongside the new one for the period before it is released.
+	// The previous prefix is released at once if it is not set.
+	// +optional
+	DrainPeriod *metav1.Duration `json:"drainPeriod,omitempty"`
+}
+
+// ReclaimPolicy represents how released prefixes are reclaimed
+// +kubebuilder:validation:Enum=Delete;Retain
+type ReclaimPolicy string
+
+const (
+	// ReclaimPolicyDelete returns released prefixes to the CIDRBlock after its quarantine period
+	ReclaimPolicyDelete ReclaimPolicy = "Delete"
+
+	// ReclaimPolicyRetain keeps released prefixes reserved for a CIDRClaim with the same name or labels
+	ReclaimPolicyRetain ReclaimPolicy = "Retain"
+)
+
+// CIDRClaimFamily is a request of an allocation from CIDRBlocks of the address family
+type CIDRClaimFamily struct {
+	// Family is the address family of the allocation
+	Family AddressFamily `json:"family"`
+
+	// Selector is a labal selector of CIDRBlock
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// SizeBit is log2(the number of requested addresses).
+	// It is the largest acceptable size if MinSizeBit is set.
 	// +kubebuilder:default=0
 	SizeBit int `json:"sizeBit"`
+
+	// MinSizeBit is log2(the smallest acceptable number of addresses).
+	// The largest available prefix between MinSizeBit and SizeBit is allocated
+	// and the granted size is reported in the status.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinSizeBit *int `json:"minSizeBit,omitempty"`
+
+	// RequestedCIDR pins the allocation to the prefix like 192.168.1.1/32, [fe80::]/64.
+	// SizeBit is ignored when it is set.
+	// +optional
+	RequestedCIDR string `json:"requestedCIDR,omitempty"`
+
+	// Preferred lists the preferences of CIDRBlocks matching Selector
+	// +optional
+	Preferred []PreferredCIDRBlockTerm `json:"preferred,omitempty"`
+}
+
+// PreferredCIDRBlockTerm is a weighted preference of CIDRBlocks like the preferred node affinity of Pods
+type PreferredCIDRBlockTerm struct {
+	// Weight is added to the score of the CIDRBlocks matching Preference
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+
+	// Preference is a label selector of the preferred CIDRBlocks
+	Preference metav1.LabelSelector `json:"preference"`
+
+	// PeerNodeLabelKeys are the keys of the labels copied from the PeerNode owning the
+	// CIDRClaim to Preference, e.g. topology.kubernetes.io/zone to prefer the CIDRBlocks
+	// in the zone of the node. The term matches nothing if the PeerNode lacks any of them.
+	// +optional
+	PeerNodeLabelKeys []string `json:"peerNodeLabelKeys,omitempty"`
 }
 
 type CIDRClaimStatusState string
@@ -47,6 +145,12 @@ const (
 
 	// CIDRClaimStatusStateBindingError represents the updating state
 	CIDRClaimStatusStateBindingError CIDRClaimStatusState = "bindingError"
+
+	// CIDRClaimStatusStateQuotaExceeded represents the state which cannot be bound within CIDRQuotas
+	CIDRClaimStatusStateQuotaExceeded CIDRClaimStatusState = "quotaExceeded"
+
+	// CIDRClaimStatusStateReleasing represents the state releasing the allocations before deletion
+	CIDRClaimStatusStateReleasing CIDRClaimStatusState = "releasing"
 )
 
 // CIDRClaimStatus defines the observed state of CIDRClaim
@@ -66,11 +170,89 @@ type CIDRClaimStatus struct {
 	// Name of the CIDRBlock
 	CIDRBlockName string `json:"name,omitempty"`
 
+	// Namespace of the CIDRBlock if it is shared from another namespace
+	CIDRBlockNamespace string `json:"namespace,omitempty"`
+
 	// CIDR represents the block of asiggned addresses like 192.168.1.0/24, [fe80::]/32
 	CIDR string `json:"cidr,omitempty"`
 
 	// SizeBit is log2(the number of requested addresses)
 	SizeBit int `json:"sizeBit,omitempty"`
+
+	// CIDRs lists the allocation for each address family.
+	// CIDRBlockName, CIDRBlockNamespace, CIDR and SizeBit mirror the first one.
+	CIDRs []CIDRClaimFamilyStatus `json:"cidrs,omitempty"`
+
+	// Draining lists the previous allocations kept until the end of the drain period after renumbering
+	Draining []CIDRClaimDrainingStatus `json:"draining,omitempty"`
+}
+
+// CIDRClaimFamilyStatus is an allocation bound to the CIDRClaim
+type CIDRClaimFamilyStatus struct {
+	// Family is the address family of CIDR
+	Family AddressFamily `json:"family"`
+
+	// Name of the CIDRBlock
+	CIDRBlockName string `json:"name"`
+
+	// Namespace of the CIDRBlock if it is shared from another namespace
+	CIDRBlockNamespace string `json:"namespace,omitempty"`
+
+	// CIDR represents the block of asiggned addresses like 192.168.1.0/24, [fe80::]/32
+	CIDR string `json:"cidr"`
+
+	// SizeBit is log2(the number of requested addresses)
+	SizeBit int `json:"sizeBit,omitempty"`
+
+	// ExternalID is the ID of the prefix reserved in the external IPAM
+	// +optional
+	ExternalID string `json:"externalID,omitempty"`
+}
+
+// CIDRClaimDrainingStatus is a previous allocation of the CIDRClaim being drained
+type CIDRClaimDrainingStatus struct {
+	CIDRClaimFamilyStatus `json:",inline"`
+
+	// ReleaseAt is the time when the prefix is released
+	ReleaseAt metav1.Time `json:"releaseAt"`
+}
+
+// FamilyStatuses returns the allocations bound to the CIDRClaim.
+// It falls back to CIDRBlockName and CIDR for statuses written before CIDRs was introduced.
+func (s *CIDRClaimStatus) FamilyStatuses() []CIDRClaimFamilyStatus {
+	if len(s.CIDRs) != 0 {
+		return s.CIDRs
+	}
+
+	if s.CIDR == "" {
+		return nil
+	}
+
+	family := AddressFamilyIPv4
+	if strings.Contains(s.CIDR, ":") {
+		family = AddressFamilyIPv6
+	}
+
+	return []CIDRClaimFamilyStatus{
+		{
+			Family:             family,
+			CIDRBlockName:      s.CIDRBlockName,
+			CIDRBlockNamespace: s.CIDRBlockNamespace,
+			CIDR:               s.CIDR,
+			SizeBit:            s.SizeBit,
+		},
+	}
+}
+
+// AllocatedStatuses returns the allocations bound to the CIDRClaim followed by the ones being drained.
+// All of them are advertised to the peers.
+func (s *CIDRClaimStatus) AllocatedStatuses() []CIDRClaimFamilyStatus {
+	statuses := append([]CIDRClaimFamilyStatus{}, s.FamilyStatuses()...)
+	for _, d := range s.Draining {
+		statuses = append(statuses, d.CIDRClaimFamilyStatus)
+	}
+
+	return statuses
 }
 
 //+kubebuilder:object:root=true
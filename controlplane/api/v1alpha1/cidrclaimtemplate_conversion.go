@@ -0,0 +1,63 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/miscord-dev/tetrapod/controlplane/api/v1beta1"
+)
+
+// ConvertTo converts this CIDRClaimTemplate to the Hub version (v1beta1).
+func (src *CIDRClaimTemplate) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.CIDRClaimTemplate)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1beta1.CIDRClaimTemplateSpec{
+		Selector:      src.Spec.Selector,
+		SizeBit:       src.Spec.SizeBit,
+		MinSizeBit:    src.Spec.MinSizeBit,
+		RequestedCIDR: src.Spec.RequestedCIDR,
+		Families:      convertSlice(src.Spec.Families, claimFamilyToHub),
+		Preferred:     convertSlice(src.Spec.Preferred, preferredTermToHub),
+		ReclaimPolicy: v1beta1.ReclaimPolicy(src.Spec.ReclaimPolicy),
+		DrainPeriod:   src.Spec.DrainPeriod,
+	}
+	dst.Status = v1beta1.CIDRClaimTemplateStatus(src.Status)
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version.
+func (dst *CIDRClaimTemplate) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.CIDRClaimTemplate)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = CIDRClaimTemplateSpec{
+		Selector:      src.Spec.Selector,
+		SizeBit:       src.Spec.SizeBit,
+		MinSizeBit:    src.Spec.MinSizeBit,
+		RequestedCIDR: src.Spec.RequestedCIDR,
+		Families:      convertSlice(src.Spec.Families, claimFamilyFromHub),
+		Preferred:     convertSlice(src.Spec.Preferred, preferredTermFromHub),
+		ReclaimPolicy: ReclaimPolicy(src.Spec.ReclaimPolicy),
+		DrainPeriod:   src.Spec.DrainPeriod,
+	}
+	dst.Status = CIDRClaimTemplateStatus(src.Status)
+
+	return nil
+}
@@ -31,9 +31,41 @@ type CIDRClaimTemplateSpec struct {
 	// Selector is a labal selector of CIDRBlock
 	Selector metav1.LabelSelector `json:"selector"`
 
-	// SizeBit is log2(the number of requested addresses)
+	// SizeBit is log2(the number of requested addresses).
+	// It is the largest acceptable size if MinSizeBit is set.
 	// +kubebuilder:default=0
 	SizeBit int `json:"sizeBit"`
+
+	// MinSizeBit is log2(the smallest acceptable number of addresses).
+	// The largest available prefix between MinSizeBit and SizeBit is allocated
+	// and the granted size is reported in the status.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinSizeBit *int `json:"minSizeBit,omitempty"`
+
+	// RequestedCIDR pins the allocation to the prefix like 192.168.1.1/32, [fe80::]/64.
+	// It is allocated only if it is free in a matching CIDRBlock.
+	// SizeBit is ignored when it is set.
+	// +optional
+	RequestedCIDR string `json:"requestedCIDR,omitempty"`
+
+	// Families requests one allocation per address family.
+	// Selector, SizeBit and RequestedCIDR are ignored when it is set.
+	// +optional
+	Families []CIDRClaimFamily `json:"families,omitempty"`
+
+	// Preferred lists the preferences of CIDRBlocks matching Selector
+	// +optional
+	Preferred []PreferredCIDRBlockTerm `json:"preferred,omitempty"`
+
+	// ReclaimPolicy decides what happens to the allocated prefixes when they are released
+	// +kubebuilder:default=Delete
+	// +optional
+	ReclaimPolicy ReclaimPolicy `json:"reclaimPolicy,omitempty"`
+
+	// DrainPeriod keeps the previous prefix of renumbered CIDRClaims advertised for the period
+	// +optional
+	DrainPeriod *metav1.Duration `json:"drainPeriod,omitempty"`
 }
 
 // CIDRClaimTemplateStatus defines the observed state of CIDRClaimTemplate
@@ -0,0 +1,101 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager sets up the webhooks with the Manager.
+func (r *CIDRClaimTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, r).
+		WithDefaulter(&CIDRClaimTemplateDefaulter{}).
+		WithValidator(&CIDRClaimTemplateValidator{Client: mgr.GetClient()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-controlplane-miscord-win-v1alpha1-cidrclaimtemplate,mutating=true,failurePolicy=fail,sideEffects=None,groups=controlplane.miscord.win,resources=cidrclaimtemplates,verbs=create;update,versions=v1alpha1,name=mcidrclaimtemplate.kb.io,admissionReviewVersions=v1
+
+// CIDRClaimTemplateDefaulter sets the defaults of CIDRClaimTemplates
+// +kubebuilder:object:generate=false
+type CIDRClaimTemplateDefaulter struct{}
+
+var _ admission.Defaulter[*CIDRClaimTemplate] = &CIDRClaimTemplateDefaulter{}
+
+// Default implements admission.Defaulter
+func (d *CIDRClaimTemplateDefaulter) Default(ctx context.Context, r *CIDRClaimTemplate) error {
+	if r.Spec.ReclaimPolicy == "" {
+		r.Spec.ReclaimPolicy = ReclaimPolicyDelete
+	}
+
+	if r.Spec.RequestedCIDR != "" {
+		r.Spec.RequestedCIDR = normalizePrefix(r.Spec.RequestedCIDR)
+	}
+
+	for i := range r.Spec.Families {
+		if r.Spec.Families[i].RequestedCIDR != "" {
+			r.Spec.Families[i].RequestedCIDR = normalizePrefix(r.Spec.Families[i].RequestedCIDR)
+		}
+	}
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-controlplane-miscord-win-v1alpha1-cidrclaimtemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=controlplane.miscord.win,resources=cidrclaimtemplates,verbs=create;update,versions=v1alpha1,name=vcidrclaimtemplate.kb.io,admissionReviewVersions=v1
+
+// CIDRClaimTemplateValidator validates CIDRClaimTemplates
+// +kubebuilder:object:generate=false
+type CIDRClaimTemplateValidator struct {
+	// Client is used to check the size against the matching CIDRBlocks
+	Client client.Reader
+}
+
+var _ admission.Validator[*CIDRClaimTemplate] = &CIDRClaimTemplateValidator{}
+
+// ValidateCreate implements admission.Validator
+func (v *CIDRClaimTemplateValidator) ValidateCreate(ctx context.Context, r *CIDRClaimTemplate) (admission.Warnings, error) {
+	return nil, v.validate(ctx, r)
+}
+
+// ValidateUpdate implements admission.Validator
+func (v *CIDRClaimTemplateValidator) ValidateUpdate(ctx context.Context, old, r *CIDRClaimTemplate) (admission.Warnings, error) {
+	return nil, v.validate(ctx, r)
+}
+
+// ValidateDelete implements admission.Validator
+func (v *CIDRClaimTemplateValidator) ValidateDelete(ctx context.Context, r *CIDRClaimTemplate) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *CIDRClaimTemplateValidator) validate(ctx context.Context, r *CIDRClaimTemplate) error {
+	errs, err := validateClaimSpec(
+		ctx, v.Client, r.Namespace, field.NewPath("spec"),
+		&r.Spec.Selector, r.Spec.SizeBit, r.Spec.MinSizeBit, r.Spec.RequestedCIDR, r.Spec.Preferred, r.Spec.Families,
+	)
+
+	if err != nil {
+		return err
+	}
+	errs = append(errs, validateDrainPeriod(field.NewPath("spec", "drainPeriod"), r.Spec.DrainPeriod)...)
+
+	return invalid("CIDRClaimTemplate", r.Name, errs)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// CIDRQuotaSpec defines the desired state of CIDRQuota
+type CIDRQuotaSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Selector is a labal selector of CIDRBlock.
+	// The quota applies to all CIDRBlocks including the ones shared from other namespaces if it is not set.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// MaxClaims is the maximum number of CIDRClaims in the namespace bound to the CIDRBlocks
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxClaims *int64 `json:"maxClaims,omitempty"`
+
+	// MaxAddresses is the maximum number of addresses allocated from the CIDRBlocks to the CIDRClaims in the namespace.
+	// It is a decimal string because IPv6 blocks can exceed int64.
+	// +kubebuilder:validation:Pattern=`^[0-9]+$`
+	// +optional
+	MaxAddresses string `json:"maxAddresses,omitempty"`
+}
+
+// CIDRQuotaStatus defines the observed state of CIDRQuota
+type CIDRQuotaStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// ObservedGeneration is the observed generation
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// UsedClaims is the number of CIDRClaims in the namespace bound to the CIDRBlocks
+	UsedClaims int64 `json:"usedClaims"`
+
+	// UsedAddresses is the number of addresses allocated from the CIDRBlocks to the CIDRClaims in the namespace.
+	// It is a decimal string because IPv6 blocks can exceed int64.
+	UsedAddresses string `json:"usedAddresses,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Claims",type=integer,JSONPath=`.status.usedClaims`
+//+kubebuilder:printcolumn:name="Max Claims",type=integer,JSONPath=`.spec.maxClaims`
+//+kubebuilder:printcolumn:name="Addresses",type=string,JSONPath=`.status.usedAddresses`
+//+kubebuilder:printcolumn:name="Max Addresses",type=string,JSONPath=`.spec.maxAddresses`
+
+// CIDRQuota is the Schema for the cidrquotas API.
+// CIDRClaims in the namespace of the quota are not bound to the CIDRBlocks selected by it
+// beyond its limits, whichever namespace the CIDRBlocks are in.
+type CIDRQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CIDRQuotaSpec   `json:"spec,omitempty"`
+	Status CIDRQuotaStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CIDRQuotaList contains a list of CIDRQuota
+type CIDRQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CIDRQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CIDRQuota{}, &CIDRQuotaList{})
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// CIDRReservationSpec defines the desired state of CIDRReservation
+type CIDRReservationSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// CIDR represents the reserved addresses like 192.168.1.0/24, [fe80::]/32.
+	// It is never allocated to CIDRClaims from any CIDRBlock it overlaps.
+	CIDR string `json:"cidr"`
+
+	// Selector is a labal selector of CIDRBlock.
+	// The reservation applies to all CIDRBlocks in the namespace if it is not set.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// CIDRReservationStatus defines the observed state of CIDRReservation
+type CIDRReservationStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="CIDR",type=string,JSONPath=`.spec.cidr`
+
+// CIDRReservation is the Schema for the cidrreservations API
+type CIDRReservation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CIDRReservationSpec   `json:"spec,omitempty"`
+	Status CIDRReservationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CIDRReservationList contains a list of CIDRReservation
+type CIDRReservationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CIDRReservation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CIDRReservation{}, &CIDRReservationList{})
+}
@@ -0,0 +1,153 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	"sigs.k8s.io/randfill"
+
+	"github.com/miscord-dev/tetrapod/controlplane/api/v1beta1"
+)
+
+func TestConversionRoundTrip(t *testing.T) {
+	filler := randfill.New().NilChance(0.2).NumElements(0, 3)
+
+	for _, tc := range []struct {
+		name  string
+		spoke func() conversion.Convertible
+		hub   func() conversion.Hub
+	}{
+		{
+			name:  "PeerNode",
+			spoke: func() conversion.Convertible { return &PeerNode{} },
+			hub:   func() conversion.Hub { return &v1beta1.PeerNode{} },
+		},
+		{
+			name:  "CIDRBlock",
+			spoke: func() conversion.Convertible { return &CIDRBlock{} },
+			hub:   func() conversion.Hub { return &v1beta1.CIDRBlock{} },
+		},
+		{
+			name:  "CIDRClaim",
+			spoke: func() conversion.Convertible { return &CIDRClaim{} },
+			hub:   func() conversion.Hub { return &v1beta1.CIDRClaim{} },
+		},
+		{
+			name:  "CIDRClaimTemplate",
+			spoke: func() conversion.Convertible { return &CIDRClaimTemplate{} },
+			hub:   func() conversion.Hub { return &v1beta1.CIDRClaimTemplate{} },
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				// v1alpha1 -> v1beta1 -> v1alpha1
+				spoke := tc.spoke()
+				filler.Fill(spoke)
+				// TypeMeta is set by the API server
+				spoke.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+
+				hub := tc.hub()
+				if err := spoke.ConvertTo(hub); err != nil {
+					t.Fatalf("failed to convert to v1beta1: %v", err)
+				}
+
+				converted := tc.spoke()
+				if err := converted.ConvertFrom(hub); err != nil {
+					t.Fatalf("failed to convert from v1beta1: %v", err)
+				}
+
+				if !equality.Semantic.DeepEqual(spoke, converted) {
+					t.Fatalf("v1alpha1 changed after round trip:\n%+v\n%+v", spoke, converted)
+				}
+
+				// v1beta1 -> v1alpha1 -> v1beta1
+				hub = tc.hub()
+				filler.Fill(hub)
+				hub.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+
+				spoke = tc.spoke()
+				if err := spoke.ConvertFrom(hub); err != nil {
+					t.Fatalf("failed to convert from v1beta1: %v", err)
+				}
+
+				convertedHub := tc.hub()
+				if err := spoke.ConvertTo(convertedHub); err != nil {
+					t.Fatalf("failed to convert to v1beta1: %v", err)
+				}
+
+				if !equality.Semantic.DeepEqual(hub, convertedHub) {
+					t.Fatalf("v1beta1 changed after round trip:\n%+v\n%+v", hub, convertedHub)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertCIDRClaimStatus(t *testing.T) {
+	claim := &CIDRClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "claim",
+			Namespace: "test",
+		},
+		Status: CIDRClaimStatus{
+			State:              CIDRClaimStatusStateReady,
+			CIDRBlockName:      "block",
+			CIDRBlockNamespace: "shared",
+			CIDR:               "192.168.1.0/28",
+			SizeBit:            4,
+			CIDRs: []CIDRClaimFamilyStatus{
+				{
+					Family:             AddressFamilyIPv4,
+					CIDRBlockName:      "block",
+					CIDRBlockNamespace: "shared",
+					CIDR:               "192.168.1.0/28",
+					SizeBit:            4,
+				},
+			},
+		},
+	}
+
+	var hub v1beta1.CIDRClaim
+	if err := claim.ConvertTo(&hub); err != nil {
+		t.Fatalf("failed to convert to v1beta1: %v", err)
+	}
+
+	b, err := json.Marshal(&hub.Status)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	for _, field := range []string{
+		`"cidrBlockName":"block"`,
+		`"cidrBlockNamespace":"shared"`,
+		`"cidrs":[{"family":"IPv4","cidrBlockName":"block","cidrBlockNamespace":"shared"`,
+	} {
+		if !strings.Contains(string(b), field) {
+			t.Errorf("%s is missing in %s", field, b)
+		}
+	}
+	if strings.Contains(string(b), `"name"`) {
+		t.Errorf("name is serialised in %s", b)
+	}
+}
+
+func TestConvertPeerNodeWithoutHostName(t *testing.T) {
+	var hub v1beta1.PeerNode
+	if err := (&PeerNode{}).ConvertTo(&hub); err != nil {
+		t.Fatalf("failed to convert to v1beta1: %v", err)
+	}
+
+	b, err := json.Marshal(&hub.Spec.Attributes)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+
+	if string(b) != "{}" {
+		t.Errorf("unexpected attributes: %s", b)
+	}
+}
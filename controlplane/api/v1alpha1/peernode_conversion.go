@@ -0,0 +1,69 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/miscord-dev/tetrapod/controlplane/api/v1beta1"
+)
+
+// ConvertTo converts this PeerNode to the Hub version (v1beta1).
+func (src *PeerNode) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.PeerNode)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1beta1.PeerNodeSpec{
+		PublicKey:         src.Spec.PublicKey,
+		PublicDiscoKey:    src.Spec.PublicDiscoKey,
+		Attributes:        v1beta1.Attributes(src.Spec.Attributes),
+		Endpoints:         src.Spec.Endpoints,
+		StaticRoutes:      src.Spec.StaticRoutes,
+		ClaimsSelector:    src.Spec.ClaimsSelector,
+		AddressesSelector: src.Spec.AddressesSelector,
+	}
+	dst.Status = v1beta1.PeerNodeStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		State:              v1beta1.PeerNodeStatusState(src.Status.State),
+		Message:            src.Status.Message,
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version.
+func (dst *PeerNode) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.PeerNode)
+
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = PeerNodeSpec{
+		PublicKey:         src.Spec.PublicKey,
+		PublicDiscoKey:    src.Spec.PublicDiscoKey,
+		Attributes:        Attributes(src.Spec.Attributes),
+		Endpoints:         src.Spec.Endpoints,
+		StaticRoutes:      src.Spec.StaticRoutes,
+		ClaimsSelector:    src.Spec.ClaimsSelector,
+		AddressesSelector: src.Spec.AddressesSelector,
+	}
+	dst.Status = PeerNodeStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		State:              PeerNodeStatusState(src.Status.State),
+		Message:            src.Status.Message,
+	}
+
+	return nil
+}
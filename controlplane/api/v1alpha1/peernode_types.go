@@ -61,6 +61,23 @@ type Attributes struct {
 	Arch string `json:"arch,omitempty"`
 }
 
+// PeerNodeLeaseLabelKey is the label of the Lease renewed by a node as the heartbeats of its PeerNode.
+// Its value is the name of the PeerNode. The control plane only watches the Leases with the label.
+const PeerNodeLeaseLabelKey = "controlplane.miscord.win/peer-node"
+
+type PeerNodeStatusState string
+
+const (
+	// PeerNodeStatusStateUnknown represents the state of the node without heartbeats
+	PeerNodeStatusStateUnknown PeerNodeStatusState = ""
+
+	// PeerNodeStatusStateOnline represents the state of the node renewing its Lease
+	PeerNodeStatusStateOnline PeerNodeStatusState = "online"
+
+	// PeerNodeStatusStateOffline represents the state of the node which stopped renewing its Lease
+	PeerNodeStatusStateOffline PeerNodeStatusState = "offline"
+)
+
 // PeerNodeStatus defines the observed state of PeerNode
 type PeerNodeStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -69,14 +86,19 @@ type PeerNodeStatus struct {
 	// ObservedGeneration is the observed generation
 	ObservedGeneration int64 `json:"observedGeneration"`
 
+	// State represents the liveness of the node
+	State PeerNodeStatusState `json:"state,omitempty"`
+
 	// Message is the error message
 	Message string `json:"message,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
 
-// PeerNode is the Schema for the peernodes API
+// PeerNode is the Schema for the peernodes API.
+// The node renews a coordination.k8s.io Lease with the same name as a heartbeat.
 type PeerNode struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
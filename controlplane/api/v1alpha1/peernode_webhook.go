@@ -0,0 +1,95 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"net/netip"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager sets up the webhooks with the Manager.
+func (r *PeerNode) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, r).
+		WithValidator(&PeerNodeValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-controlplane-miscord-win-v1alpha1-peernode,mutating=false,failurePolicy=fail,sideEffects=None,groups=controlplane.miscord.win,resources=peernodes,verbs=create;update,versions=v1alpha1,name=vpeernode.kb.io,admissionReviewVersions=v1
+
+// PeerNodeValidator validates PeerNodes
+// +kubebuilder:object:generate=false
+type PeerNodeValidator struct{}
+
+var _ admission.Validator[*PeerNode] = &PeerNodeValidator{}
+
+// ValidateCreate implements admission.Validator
+func (v *PeerNodeValidator) ValidateCreate(ctx context.Context, r *PeerNode) (admission.Warnings, error) {
+	return nil, v.validate(r)
+}
+
+// ValidateUpdate implements admission.Validator
+func (v *PeerNodeValidator) ValidateUpdate(ctx context.Context, old, r *PeerNode) (admission.Warnings, error) {
+	return nil, v.validate(r)
+}
+
+// ValidateDelete implements admission.Validator
+func (v *PeerNodeValidator) ValidateDelete(ctx context.Context, r *PeerNode) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *PeerNodeValidator) validate(r *PeerNode) error {
+	path := field.NewPath("spec")
+
+	var errs field.ErrorList
+	for i, endpoint := range r.Spec.Endpoints {
+		path := path.Child("endpoints").Index(i)
+
+		addrPort, err := netip.ParseAddrPort(endpoint)
+		if err != nil {
+			errs = append(errs, field.Invalid(path, endpoint, "must be an address and a port like 192.0.2.1:51820 or [2001:db8::1]:51820"))
+
+			continue
+		}
+
+		addr := addrPort.Addr()
+		switch {
+		case addr.IsLoopback():
+			errs = append(errs, field.Invalid(path, endpoint, "must not be a loopback address"))
+		case addr.IsUnspecified():
+			errs = append(errs, field.Invalid(path, endpoint, "must not be an unspecified address"))
+		case addr.IsMulticast():
+			errs = append(errs, field.Invalid(path, endpoint, "must not be a multicast address"))
+		case addrPort.Port() == 0:
+			errs = append(errs, field.Invalid(path, endpoint, "must have a non-zero port"))
+		}
+	}
+
+	for i, route := range r.Spec.StaticRoutes {
+		if _, err := validatePrefix(path.Child("staticRoutes").Index(i), route); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, validateSelector(path.Child("claimsSelector"), &r.Spec.ClaimsSelector)...)
+	errs = append(errs, validateSelector(path.Child("addressesSelector"), &r.Spec.AddressesSelector)...)
+
+	return invalid("PeerNode", r.Name, errs)
+}
@@ -0,0 +1,262 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// parsePrefix parses s as a prefix aligned to its prefix length.
+// A bare address is parsed as a prefix of the single address.
+func parsePrefix(s string) (*ipaddr.IPAddress, error) {
+	if s == "" {
+		return nil, fmt.Errorf("must not be empty")
+	}
+
+	addr, err := ipaddr.NewIPAddressString(s).ToAddress()
+
+	if err != nil {
+		return nil, fmt.Errorf("must be a CIDR like 192.168.1.0/24 or fe80::/64: %w", err)
+	}
+	if addr.GetPrefixLen() == nil {
+		addr = addr.SetPrefixLen(addr.GetBitCount())
+	}
+	if !addr.IsPrefixBlock() {
+		return nil, fmt.Errorf("must be aligned to its prefix length like %s", addr.ToPrefixBlock())
+	}
+
+	return addr, nil
+}
+
+func validatePrefix(path *field.Path, s string) (*ipaddr.IPAddress, *field.Error) {
+	addr, err := parsePrefix(s)
+
+	if err != nil {
+		return nil, field.Invalid(path, s, err.Error())
+	}
+
+	return addr, nil
+}
+
+func familyOf(addr *ipaddr.IPAddress) AddressFamily {
+	if addr.IsIPv6() {
+		return AddressFamilyIPv6
+	}
+
+	return AddressFamilyIPv4
+}
+
+func validateSelector(path *field.Path, selector *metav1.LabelSelector) field.ErrorList {
+	return metav1validation.ValidateLabelSelector(selector, metav1validation.LabelSelectorValidationOptions{}, path)
+}
+
+// validateAllocationRequest validates a request of an allocation in CIDRClaims and CIDRClaimTemplates.
+// The size is checked against the CIDRBlocks matching the selector if c is not nil.
+func validateAllocationRequest(
+	ctx context.Context,
+	c client.Reader,
+	namespace string,
+	path *field.Path,
+	family AddressFamily,
+	selector *metav1.LabelSelector,
+	sizeBit int,
+	minSizeBit *int,
+	requestedCIDR string,
+) (field.ErrorList, error) {
+	errs := validateSelector(path.Child("selector"), selector)
+
+	if requestedCIDR != "" {
+		addr, err := validatePrefix(path.Child("requestedCIDR"), requestedCIDR)
+
+		if err != nil {
+			return append(errs, err), nil
+		}
+
+		if family != "" && familyOf(addr) != family {
+			errs = append(errs, field.Invalid(path.Child("requestedCIDR"), requestedCIDR, fmt.Sprintf("must be an %s CIDR", family)))
+		}
+
+		return errs, nil
+	}
+
+	maxSizeBit := ipaddr.IPv6BitCount
+	if family == AddressFamilyIPv4 {
+		maxSizeBit = ipaddr.IPv4BitCount
+	}
+
+	if sizeBit < 0 || sizeBit > maxSizeBit {
+		return append(errs, field.Invalid(path.Child("sizeBit"), sizeBit, fmt.Sprintf("must be between 0 and %d", maxSizeBit))), nil
+	}
+
+	// The request is satisfiable if the smallest acceptable size fits in a matching block
+	smallest, smallestPath := sizeBit, path.Child("sizeBit")
+	if minSizeBit != nil {
+		if *minSizeBit < 0 || *minSizeBit > sizeBit {
+			return append(errs, field.Invalid(path.Child("minSizeBit"), *minSizeBit, fmt.Sprintf("must be between 0 and sizeBit %d", sizeBit))), nil
+		}
+
+		smallest, smallestPath = *minSizeBit, path.Child("minSizeBit")
+	}
+
+	if c == nil || len(errs) != 0 {
+		return errs, nil
+	}
+
+	s, err := metav1.LabelSelectorAsSelector(selector)
+
+	if err != nil {
+		return append(errs, field.Invalid(path.Child("selector"), selector, err.Error())), nil
+	}
+
+	// CIDRBlocks in other namespaces match if they are shared with the namespace
+	var ns corev1.Namespace
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, &ns); client.IgnoreNotFound(err) != nil {
+		return nil, fmt.Errorf("failed to get Namespace: %w", err)
+	}
+	ns.Name = namespace
+
+	var blocks CIDRBlockList
+	if err := c.List(ctx, &blocks, &client.ListOptions{
+		LabelSelector: s,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list CIDRBlocks: %w", err)
+	}
+
+	matched := 0
+	for _, block := range blocks.Items {
+		if !block.ServesNamespace(&ns) {
+			continue
+		}
+
+		addr, err := parsePrefix(block.Spec.CIDR)
+
+		if err != nil || (family != "" && familyOf(addr) != family) {
+			continue
+		}
+		matched++
+
+		if addr.GetBitCount()-addr.GetPrefixLen().Len() >= smallest {
+			return errs, nil
+		}
+	}
+
+	if matched != 0 {
+		errs = append(errs, field.Invalid(smallestPath, smallest, "must not be larger than the matching CIDRBlocks"))
+	}
+
+	return errs, nil
+}
+
+// validateClaimSpec validates the allocation requests shared by CIDRClaims and CIDRClaimTemplates
+func validateClaimSpec(
+	ctx context.Context,
+	c client.Reader,
+	namespace string,
+	path *field.Path,
+	selector *metav1.LabelSelector,
+	sizeBit int,
+	minSizeBit *int,
+	requestedCIDR string,
+	preferred []PreferredCIDRBlockTerm,
+	families []CIDRClaimFamily,
+) (field.ErrorList, error) {
+	if len(families) == 0 {
+		errs, err := validateAllocationRequest(ctx, c, namespace, path, "", selector, sizeBit, minSizeBit, requestedCIDR)
+
+		return append(errs, validatePreferred(path.Child("preferred"), preferred)...), err
+	}
+
+	var errs field.ErrorList
+	seen := map[AddressFamily]bool{}
+	for i := range families {
+		f := &families[i]
+		path := path.Child("families").Index(i)
+
+		if seen[f.Family] {
+			errs = append(errs, field.Duplicate(path.Child("family"), f.Family))
+		}
+		seen[f.Family] = true
+
+		familyErrs, err := validateAllocationRequest(ctx, c, namespace, path, f.Family, &f.Selector, f.SizeBit, f.MinSizeBit, f.RequestedCIDR)
+
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, familyErrs...)
+		errs = append(errs, validatePreferred(path.Child("preferred"), f.Preferred)...)
+	}
+
+	return errs, nil
+}
+
+// validateDrainPeriod validates the drain period of renumbered CIDRClaims
+func validateDrainPeriod(path *field.Path, drainPeriod *metav1.Duration) field.ErrorList {
+	if drainPeriod == nil || drainPeriod.Duration >= 0 {
+		return nil
+	}
+
+	return field.ErrorList{field.Invalid(path, drainPeriod.Duration.String(), "must not be negative")}
+}
+
+func validatePreferred(path *field.Path, preferred []PreferredCIDRBlockTerm) field.ErrorList {
+	var errs field.ErrorList
+	for i := range preferred {
+		term := &preferred[i]
+		path := path.Index(i)
+
+		if term.Weight < 1 || term.Weight > 100 {
+			errs = append(errs, field.Invalid(path.Child("weight"), term.Weight, "must be between 1 and 100"))
+		}
+
+		errs = append(errs, validateSelector(path.Child("preference"), &term.Preference)...)
+
+		for j, key := range term.PeerNodeLabelKeys {
+			errs = append(errs, metav1validation.ValidateLabelName(key, path.Child("peerNodeLabelKeys").Index(j))...)
+		}
+	}
+
+	return errs
+}
+
+// normalizePrefix returns the canonical form of s if it is a valid prefix
+func normalizePrefix(s string) string {
+	addr, err := parsePrefix(s)
+
+	if err != nil {
+		return s
+	}
+
+	return addr.String()
+}
+
+func invalid(kind, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: kind}, name, errs)
+}
@@ -22,6 +22,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -45,8 +46,8 @@ func (in *CIDRBlock) DeepCopyInto(out *CIDRBlock) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRBlock.
@@ -67,6 +68,112 @@ func (in *CIDRBlock) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRBlockAllocation) DeepCopyInto(out *CIDRBlockAllocation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRBlockAllocation.
+func (in *CIDRBlockAllocation) DeepCopy() *CIDRBlockAllocation {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRBlockAllocation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRBlockClaim) DeepCopyInto(out *CIDRBlockClaim) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRBlockClaim.
+func (in *CIDRBlockClaim) DeepCopy() *CIDRBlockClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRBlockClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CIDRBlockClaim) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRBlockClaimList) DeepCopyInto(out *CIDRBlockClaimList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CIDRBlockClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRBlockClaimList.
+func (in *CIDRBlockClaimList) DeepCopy() *CIDRBlockClaimList {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRBlockClaimList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CIDRBlockClaimList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRBlockClaimSpec) DeepCopyInto(out *CIDRBlockClaimSpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRBlockClaimSpec.
+func (in *CIDRBlockClaimSpec) DeepCopy() *CIDRBlockClaimSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRBlockClaimSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRBlockClaimStatus) DeepCopyInto(out *CIDRBlockClaimStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRBlockClaimStatus.
+func (in *CIDRBlockClaimStatus) DeepCopy() *CIDRBlockClaimStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRBlockClaimStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CIDRBlockList) DeepCopyInto(out *CIDRBlockList) {
 	*out = *in
@@ -99,9 +206,47 @@ func (in *CIDRBlockList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRBlockReleasedPrefix) DeepCopyInto(out *CIDRBlockReleasedPrefix) {
+	*out = *in
+	if in.ClaimLabels != nil {
+		in, out := &in.ClaimLabels, &out.ClaimLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.ReleasedAt.DeepCopyInto(&out.ReleasedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRBlockReleasedPrefix.
+func (in *CIDRBlockReleasedPrefix) DeepCopy() *CIDRBlockReleasedPrefix {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRBlockReleasedPrefix)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CIDRBlockSpec) DeepCopyInto(out *CIDRBlockSpec) {
 	*out = *in
+	if in.Excludes != nil {
+		in, out := &in.Excludes, &out.Excludes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.QuarantinePeriod != nil {
+		in, out := &in.QuarantinePeriod, &out.QuarantinePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRBlockSpec.
@@ -117,6 +262,38 @@ func (in *CIDRBlockSpec) DeepCopy() *CIDRBlockSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CIDRBlockStatus) DeepCopyInto(out *CIDRBlockStatus) {
 	*out = *in
+	if in.Allocations != nil {
+		in, out := &in.Allocations, &out.Allocations
+		*out = make([]CIDRBlockAllocation, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReleasedPrefixes != nil {
+		in, out := &in.ReleasedPrefixes, &out.ReleasedPrefixes
+		*out = make([]CIDRBlockReleasedPrefix, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReservedCIDRs != nil {
+		in, out := &in.ReservedCIDRs, &out.ReservedCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LargestFreePrefixes != nil {
+		in, out := &in.LargestFreePrefixes, &out.LargestFreePrefixes
+		*out = make([]FamilyCIDR, len(*in))
+		copy(*out, *in)
+	}
+	if in.FreePrefixes != nil {
+		in, out := &in.FreePrefixes, &out.FreePrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BlockingClaims != nil {
+		in, out := &in.BlockingClaims, &out.BlockingClaims
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRBlockStatus.
@@ -129,13 +306,40 @@ func (in *CIDRBlockStatus) DeepCopy() *CIDRBlockStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRBlockTemplate) DeepCopyInto(out *CIDRBlockTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Excludes != nil {
+		in, out := &in.Excludes, &out.Excludes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRBlockTemplate.
+func (in *CIDRBlockTemplate) DeepCopy() *CIDRBlockTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRBlockTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CIDRClaim) DeepCopyInto(out *CIDRClaim) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRClaim.
@@ -156,6 +360,66 @@ func (in *CIDRClaim) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRClaimDrainingStatus) DeepCopyInto(out *CIDRClaimDrainingStatus) {
+	*out = *in
+	out.CIDRClaimFamilyStatus = in.CIDRClaimFamilyStatus
+	in.ReleaseAt.DeepCopyInto(&out.ReleaseAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRClaimDrainingStatus.
+func (in *CIDRClaimDrainingStatus) DeepCopy() *CIDRClaimDrainingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRClaimDrainingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRClaimFamily) DeepCopyInto(out *CIDRClaimFamily) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	if in.MinSizeBit != nil {
+		in, out := &in.MinSizeBit, &out.MinSizeBit
+		*out = new(int)
+		**out = **in
+	}
+	if in.Preferred != nil {
+		in, out := &in.Preferred, &out.Preferred
+		*out = make([]PreferredCIDRBlockTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRClaimFamily.
+func (in *CIDRClaimFamily) DeepCopy() *CIDRClaimFamily {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRClaimFamily)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRClaimFamilyStatus) DeepCopyInto(out *CIDRClaimFamilyStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRClaimFamilyStatus.
+func (in *CIDRClaimFamilyStatus) DeepCopy() *CIDRClaimFamilyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRClaimFamilyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CIDRClaimList) DeepCopyInto(out *CIDRClaimList) {
 	*out = *in
@@ -192,6 +456,30 @@ func (in *CIDRClaimList) DeepCopyObject() runtime.Object {
 func (in *CIDRClaimSpec) DeepCopyInto(out *CIDRClaimSpec) {
 	*out = *in
 	in.Selector.DeepCopyInto(&out.Selector)
+	if in.MinSizeBit != nil {
+		in, out := &in.MinSizeBit, &out.MinSizeBit
+		*out = new(int)
+		**out = **in
+	}
+	if in.Families != nil {
+		in, out := &in.Families, &out.Families
+		*out = make([]CIDRClaimFamily, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Preferred != nil {
+		in, out := &in.Preferred, &out.Preferred
+		*out = make([]PreferredCIDRBlockTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DrainPeriod != nil {
+		in, out := &in.DrainPeriod, &out.DrainPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRClaimSpec.
@@ -207,6 +495,18 @@ func (in *CIDRClaimSpec) DeepCopy() *CIDRClaimSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CIDRClaimStatus) DeepCopyInto(out *CIDRClaimStatus) {
 	*out = *in
+	if in.CIDRs != nil {
+		in, out := &in.CIDRs, &out.CIDRs
+		*out = make([]CIDRClaimFamilyStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Draining != nil {
+		in, out := &in.Draining, &out.Draining
+		*out = make([]CIDRClaimDrainingStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRClaimStatus.
@@ -282,6 +582,30 @@ func (in *CIDRClaimTemplateList) DeepCopyObject() runtime.Object {
 func (in *CIDRClaimTemplateSpec) DeepCopyInto(out *CIDRClaimTemplateSpec) {
 	*out = *in
 	in.Selector.DeepCopyInto(&out.Selector)
+	if in.MinSizeBit != nil {
+		in, out := &in.MinSizeBit, &out.MinSizeBit
+		*out = new(int)
+		**out = **in
+	}
+	if in.Families != nil {
+		in, out := &in.Families, &out.Families
+		*out = make([]CIDRClaimFamily, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Preferred != nil {
+		in, out := &in.Preferred, &out.Preferred
+		*out = make([]PreferredCIDRBlockTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DrainPeriod != nil {
+		in, out := &in.DrainPeriod, &out.DrainPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRClaimTemplateSpec.
@@ -309,6 +633,214 @@ func (in *CIDRClaimTemplateStatus) DeepCopy() *CIDRClaimTemplateStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRQuota) DeepCopyInto(out *CIDRQuota) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRQuota.
+func (in *CIDRQuota) DeepCopy() *CIDRQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CIDRQuota) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRQuotaList) DeepCopyInto(out *CIDRQuotaList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CIDRQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRQuotaList.
+func (in *CIDRQuotaList) DeepCopy() *CIDRQuotaList {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRQuotaList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CIDRQuotaList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRQuotaSpec) DeepCopyInto(out *CIDRQuotaSpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxClaims != nil {
+		in, out := &in.MaxClaims, &out.MaxClaims
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRQuotaSpec.
+func (in *CIDRQuotaSpec) DeepCopy() *CIDRQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRQuotaStatus) DeepCopyInto(out *CIDRQuotaStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRQuotaStatus.
+func (in *CIDRQuotaStatus) DeepCopy() *CIDRQuotaStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRQuotaStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRReservation) DeepCopyInto(out *CIDRReservation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRReservation.
+func (in *CIDRReservation) DeepCopy() *CIDRReservation {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRReservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CIDRReservation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRReservationList) DeepCopyInto(out *CIDRReservationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CIDRReservation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRReservationList.
+func (in *CIDRReservationList) DeepCopy() *CIDRReservationList {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRReservationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CIDRReservationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRReservationSpec) DeepCopyInto(out *CIDRReservationSpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRReservationSpec.
+func (in *CIDRReservationSpec) DeepCopy() *CIDRReservationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRReservationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CIDRReservationStatus) DeepCopyInto(out *CIDRReservationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CIDRReservationStatus.
+func (in *CIDRReservationStatus) DeepCopy() *CIDRReservationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CIDRReservationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FamilyCIDR) DeepCopyInto(out *FamilyCIDR) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FamilyCIDR.
+func (in *FamilyCIDR) DeepCopy() *FamilyCIDR {
+	if in == nil {
+		return nil
+	}
+	out := new(FamilyCIDR)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PeerNode) DeepCopyInto(out *PeerNode) {
 	*out = *in
@@ -410,3 +942,24 @@ func (in *PeerNodeStatus) DeepCopy() *PeerNodeStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreferredCIDRBlockTerm) DeepCopyInto(out *PreferredCIDRBlockTerm) {
+	*out = *in
+	in.Preference.DeepCopyInto(&out.Preference)
+	if in.PeerNodeLabelKeys != nil {
+		in, out := &in.PeerNodeLabelKeys, &out.PeerNodeLabelKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreferredCIDRBlockTerm.
+func (in *PreferredCIDRBlockTerm) DeepCopy() *PreferredCIDRBlockTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(PreferredCIDRBlockTerm)
+	in.DeepCopyInto(out)
+	return out
+}
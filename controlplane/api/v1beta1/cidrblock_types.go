@@ -0,0 +1,212 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// CIDRBlockSpec defines the desired state of CIDRBlock
+type CIDRBlockSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// CIDR represents the block of assigned addresses like 192.168.1.0/24, [fe80::]/32
+	CIDR string `json:"cidr"`
+
+	// AllocationStrategy decides which free prefix is allocated to CIDRClaims
+	// +kubebuilder:default=FirstFit
+	// +optional
+	AllocationStrategy AllocationStrategy `json:"allocationStrategy,omitempty"`
+
+	// Excludes lists the CIDRs in the block never allocated to CIDRClaims like gateways and VIPs
+	// +optional
+	Excludes []string `json:"excludes,omitempty"`
+
+	// QuarantinePeriod is how long released prefixes are kept from being allocated again
+	// so that stale routes and caches referring to them expire first
+	// +optional
+	QuarantinePeriod *metav1.Duration `json:"quarantinePeriod,omitempty"`
+
+	// NamespaceSelector shares the block with CIDRClaims in the namespaces whose labels match it.
+	// The block serves only CIDRClaims in its own namespace if it is not set. An empty selector
+	// matches all namespaces. Shared blocks must not overlap CIDRBlocks in any namespace.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// AllocationStrategy represents how prefixes are picked from free blocks
+// +kubebuilder:validation:Enum=FirstFit;BestFit;Sequential;Random
+type AllocationStrategy string
+
+const (
+	// AllocationStrategyFirstFit picks the first free prefix
+	AllocationStrategyFirstFit AllocationStrategy = "FirstFit"
+
+	// AllocationStrategyBestFit picks a prefix from the smallest free block that fits
+	AllocationStrategyBestFit AllocationStrategy = "BestFit"
+
+	// AllocationStrategySequential picks the next free prefix after the last allocated one
+	AllocationStrategySequential AllocationStrategy = "Sequential"
+
+	// AllocationStrategyRandom picks a free prefix at random
+	AllocationStrategyRandom AllocationStrategy = "Random"
+)
+
+// AddressFamily represents the family of IP addresses
+// +kubebuilder:validation:Enum=IPv4;IPv6
+type AddressFamily string
+
+const (
+	// AddressFamilyIPv4 represents IPv4
+	AddressFamilyIPv4 AddressFamily = "IPv4"
+
+	// AddressFamilyIPv6 represents IPv6
+	AddressFamilyIPv6 AddressFamily = "IPv6"
+)
+
+// FamilyCIDR is a CIDR with its address family
+type FamilyCIDR struct {
+	// Family is the address family of CIDR
+	Family AddressFamily `json:"family"`
+
+	// CIDR represents the block of addresses like 192.168.1.0/24, [fe80::]/32
+	CIDR string `json:"cidr"`
+}
+
+// CIDRBlockAllocation is a record of a prefix allocated from a CIDRBlock
+type CIDRBlockAllocation struct {
+	// ClaimName is the name of the CIDRClaim the prefix is allocated to
+	ClaimName string `json:"claimName"`
+
+	// ClaimNamespace is the namespace of the CIDRClaim the prefix is allocated to.
+	// It is empty for allocations recorded before CIDRBlocks were shared across namespaces.
+	// +optional
+	ClaimNamespace string `json:"claimNamespace,omitempty"`
+
+	// ClaimUID is the UID of the CIDRClaim the prefix is allocated to
+	ClaimUID types.UID `json:"claimUID,omitempty"`
+
+	// CIDR is the allocated prefix
+	CIDR string `json:"cidr"`
+
+	// ExternalID is the ID of the prefix reserved in the external IPAM
+	// +optional
+	ExternalID string `json:"externalID,omitempty"`
+}
+
+// CIDRBlockReleasedPrefix is a record of a prefix released by a CIDRClaim which is
+// not allocatable yet
+type CIDRBlockReleasedPrefix struct {
+	// ClaimName is the name of the CIDRClaim the prefix was allocated to
+	ClaimName string `json:"claimName"`
+
+	// ClaimNamespace is the namespace of the CIDRClaim the prefix was allocated to
+	// +optional
+	ClaimNamespace string `json:"claimNamespace,omitempty"`
+
+	// ClaimLabels are the labels of the CIDRClaim the prefix was allocated to
+	// +optional
+	ClaimLabels map[string]string `json:"claimLabels,omitempty"`
+
+	// CIDR is the released prefix
+	CIDR string `json:"cidr"`
+
+	// ReleasedAt is when the prefix was released
+	ReleasedAt metav1.Time `json:"releasedAt"`
+
+	// Retained is true if the prefix is reserved for a CIDRClaim with the same name or labels
+	// until it is claimed again or the record is removed
+	// +optional
+	Retained bool `json:"retained,omitempty"`
+}
+
+// CIDRBlockStatus defines the observed state of CIDRBlock
+type CIDRBlockStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Allocations is the authoritative ledger of prefixes allocated from the block.
+	// It is only updated with optimistic concurrency so that a prefix is never
+	// allocated twice.
+	Allocations []CIDRBlockAllocation `json:"allocations,omitempty"`
+
+	// ReleasedPrefixes are the prefixes released by CIDRClaims which are quarantined
+	// or retained. They are not allocated to other CIDRClaims.
+	ReleasedPrefixes []CIDRBlockReleasedPrefix `json:"releasedPrefixes,omitempty"`
+
+	// LedgerVersion is incremented each time Allocations or ReleasedPrefixes are
+	// updated so that changes of the ledger can be told from other status updates.
+	// +optional
+	LedgerVersion int64 `json:"ledgerVersion,omitempty"`
+
+	// ReservedCIDRs lists the CIDRs in the block excluded by the spec or CIDRReservations
+	ReservedCIDRs []string `json:"reservedCIDRs,omitempty"`
+
+	// BoundClaims is the number of CIDRClaims bound to the block
+	BoundClaims int `json:"boundClaims"`
+
+	// AllocatedAddresses is the number of addresses allocated to CIDRClaims.
+	// It is a decimal string because IPv6 blocks can exceed int64.
+	AllocatedAddresses string `json:"allocatedAddresses,omitempty"`
+
+	// FreeAddresses is the number of addresses neither allocated nor reserved.
+	// It is a decimal string because IPv6 blocks can exceed int64.
+	FreeAddresses string `json:"freeAddresses,omitempty"`
+
+	// LargestFreePrefixes are the largest free prefixes per address family
+	LargestFreePrefixes []FamilyCIDR `json:"largestFreePrefixes,omitempty"`
+
+	// FreePrefixes are the unallocated prefixes in the block
+	FreePrefixes []string `json:"freePrefixes,omitempty"`
+
+	// BlockingClaims lists the CIDRClaims still bound to the block while it is being deleted
+	BlockingClaims []string `json:"blockingClaims,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="CIDR",type=string,JSONPath=`.spec.cidr`
+//+kubebuilder:printcolumn:name="Claims",type=integer,JSONPath=`.status.boundClaims`
+//+kubebuilder:printcolumn:name="Free",type=string,JSONPath=`.status.freeAddresses`
+
+// CIDRBlock is the Schema for the cidrblocks API
+type CIDRBlock struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CIDRBlockSpec   `json:"spec,omitempty"`
+	Status CIDRBlockStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CIDRBlockList contains a list of CIDRBlock
+type CIDRBlockList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CIDRBlock `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CIDRBlock{}, &CIDRBlockList{})
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// CIDRClaimTemplateSpec defines the desired state of CIDRClaimTemplate
+type CIDRClaimTemplateSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Selector is a label selector of CIDRBlock
+	Selector metav1.LabelSelector `json:"selector"`
+
+	// SizeBit is log2(the number of requested addresses).
+	// It is the largest acceptable size if MinSizeBit is set.
+	// +kubebuilder:default=0
+	SizeBit int `json:"sizeBit"`
+
+	// MinSizeBit is log2(the smallest acceptable number of addresses).
+	// The largest available prefix between MinSizeBit and SizeBit is allocated
+	// and the granted size is reported in the status.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinSizeBit *int `json:"minSizeBit,omitempty"`
+
+	// RequestedCIDR pins the allocation to the prefix like 192.168.1.1/32, [fe80::]/64.
+	// It is allocated only if it is free in a matching CIDRBlock.
+	// SizeBit is ignored when it is set.
+	// +optional
+	RequestedCIDR string `json:"requestedCIDR,omitempty"`
+
+	// Families requests one allocation per address family.
+	// Selector, SizeBit and RequestedCIDR are ignored when it is set.
+	// +optional
+	Families []CIDRClaimFamily `json:"families,omitempty"`
+
+	// Preferred lists the preferences of CIDRBlocks matching Selector
+	// +optional
+	Preferred []PreferredCIDRBlockTerm `json:"preferred,omitempty"`
+
+	// ReclaimPolicy decides what happens to the allocated prefixes when they are released
+	// +kubebuilder:default=Delete
+	// +optional
+	ReclaimPolicy ReclaimPolicy `json:"reclaimPolicy,omitempty"`
+
+	// DrainPeriod keeps the previous prefix of renumbered CIDRClaims advertised for the period
+	// +optional
+	DrainPeriod *metav1.Duration `json:"drainPeriod,omitempty"`
+}
+
+// CIDRClaimTemplateStatus defines the observed state of CIDRClaimTemplate
+type CIDRClaimTemplateStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+
+// CIDRClaimTemplate is the Schema for the cidrclaimtemplates API
+type CIDRClaimTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CIDRClaimTemplateSpec   `json:"spec,omitempty"`
+	Status CIDRClaimTemplateStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CIDRClaimTemplateList contains a list of CIDRClaimTemplate
+type CIDRClaimTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CIDRClaimTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CIDRClaimTemplate{}, &CIDRClaimTemplateList{})
+}
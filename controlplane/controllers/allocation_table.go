@@ -0,0 +1,381 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/ipaddrutil"
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+// cidrClaimBlockNameField is the field index of the CIDRBlocks a CIDRClaim is bound to.
+// The values are the keys of the blocks like namespace/name as CIDRBlocks can be shared
+// across namespaces.
+const cidrClaimBlockNameField = ".status.cidrBlockName"
+
+// indexCIDRClaimBlockNames returns the keys of CIDRBlocks the CIDRClaim is bound to or draining from
+func indexCIDRClaimBlockNames(o client.Object) []string {
+	claim := o.(*controlplanev1alpha1.CIDRClaim)
+
+	statuses := claim.Status.AllocatedStatuses()
+
+	names := make([]string, 0, len(statuses))
+	for _, s := range statuses {
+		if s.CIDRBlockName != "" {
+			names = append(names, blockKeyOf(claim, &s).String())
+		}
+	}
+
+	return names
+}
+
+// allocationTable is an in-memory table of the CIDRs bound to CIDRClaims per CIDRBlock.
+// It is kept up to date by the watch events of CIDRClaims so that an allocation
+// only looks at the claims bound to the candidate blocks instead of listing all claims.
+type allocationTable struct {
+	mu sync.RWMutex
+
+	// blocks maps a CIDRBlock to the allocations of CIDRClaims bound to it
+	blocks map[types.NamespacedName]map[types.UID]controlplanev1alpha1.CIDRBlockAllocation
+	// claims maps a CIDRClaim to the CIDRBlocks it is bound to
+	claims map[types.UID][]types.NamespacedName
+	// tries maps a CIDRBlock to the trie of the prefixes used in it
+	tries map[types.NamespacedName]*blockTrie
+}
+
+func newAllocationTable() *allocationTable {
+	return &allocationTable{
+		blocks: map[types.NamespacedName]map[types.UID]controlplanev1alpha1.CIDRBlockAllocation{},
+		claims: map[types.UID][]types.NamespacedName{},
+		tries:  map[types.NamespacedName]*blockTrie{},
+	}
+}
+
+// update replaces the allocations of the claim with its current status
+func (t *allocationTable) update(claim *controlplanev1alpha1.CIDRClaim) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The previous prefixes are unbound after the current ones are bound so that
+	// the prefixes bound again are not removed from the tries
+	previous := t.bound(claim.UID)
+	defer t.unbind(previous)
+
+	t.removeLocked(claim.UID)
+
+	statuses := claim.Status.FamilyStatuses()
+	if len(statuses) == 0 {
+		return
+	}
+
+	keys := make([]types.NamespacedName, 0, len(statuses))
+	for _, s := range statuses {
+		if s.CIDRBlockName == "" {
+			continue
+		}
+
+		key := blockKeyOf(claim, &s)
+
+		allocations, ok := t.blocks[key]
+		if !ok {
+			allocations = map[types.UID]controlplanev1alpha1.CIDRBlockAllocation{}
+			t.blocks[key] = allocations
+		}
+
+		allocations[claim.UID] = controlplanev1alpha1.CIDRBlockAllocation{
+			ClaimName:      claim.Name,
+			ClaimNamespace: claim.Namespace,
+			ClaimUID:       claim.UID,
+			CIDR:           s.CIDR,
+		}
+		keys = append(keys, key)
+
+		if bt, ok := t.tries[key]; ok {
+			bt.add(s.CIDR)
+		}
+	}
+
+	t.claims[claim.UID] = keys
+}
+
+// remove drops the allocations of the claim
+func (t *allocationTable) remove(claim *controlplanev1alpha1.CIDRClaim) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.unbind(t.bound(claim.UID))
+	t.removeLocked(claim.UID)
+}
+
+// boundPrefix is a prefix bound to a claim in a block
+type boundPrefix struct {
+	block types.NamespacedName
+	cidr  string
+}
+
+// bound returns the prefixes bound to the claim with the UID
+func (t *allocationTable) bound(uid types.UID) []boundPrefix {
+	prefixes := make([]boundPrefix, 0, len(t.claims[uid]))
+	for _, key := range t.claims[uid] {
+		prefixes = append(prefixes, boundPrefix{block: key, cidr: t.blocks[key][uid].CIDR})
+	}
+
+	return prefixes
+}
+
+// unbind removes the prefixes from the tries of the blocks unless they are still used
+func (t *allocationTable) unbind(prefixes []boundPrefix) {
+	for _, p := range prefixes {
+		if bt, ok := t.tries[p.block]; ok {
+			bt.drop(p.cidr)
+		}
+	}
+}
+
+func (t *allocationTable) removeLocked(uid types.UID) {
+	for _, key := range t.claims[uid] {
+		delete(t.blocks[key], uid)
+
+		if len(t.blocks[key]) == 0 {
+			delete(t.blocks, key)
+		}
+	}
+
+	delete(t.claims, uid)
+}
+
+// allocations returns the allocations in the block except the ones of the claim with the UID
+func (t *allocationTable) allocations(block types.NamespacedName, except types.UID) []controlplanev1alpha1.CIDRBlockAllocation {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	allocations := make([]controlplanev1alpha1.CIDRBlockAllocation, 0, len(t.blocks[block]))
+	for uid, a := range t.blocks[block] {
+		if uid == except {
+			continue
+		}
+
+		allocations = append(allocations, a)
+	}
+
+	return allocations
+}
+
+// findFree returns a free prefix of 2^sizeBit addresses in the block picked by the allocator.
+// The prefixes used in the ledger of the block, by the claims bound to it, and by the reservations
+// are kept in a trie. It is updated with the changes of the ledger and the claims, and rebuilt only
+// if the CIDR of the block or the reserved prefixes have changed.
+func (t *allocationTable) findFree(
+	block *controlplanev1alpha1.CIDRBlock,
+	reservations []controlplanev1alpha1.CIDRReservation,
+	sizeBit int,
+	allocator ipaddrutil.Allocator,
+) *ipaddr.IPAddress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := client.ObjectKeyFromObject(block)
+	reserved := reservedAddresses(block, reservations)
+
+	bt, ok := t.tries[key]
+	if !ok || bt.cidr != block.Spec.CIDR || !equalAddresses(bt.reserved, reserved) {
+		bt = newBlockTrie(block, reserved)
+		if bt == nil {
+			return nil
+		}
+
+		for _, a := range t.blocks[key] {
+			bt.add(a.CIDR)
+		}
+
+		t.tries[key] = bt
+	}
+
+	bt.sync(block)
+
+	// The lowest free prefix is found without listing the free blocks
+	if _, ok := allocator.(ipaddrutil.FirstFit); ok {
+		return bt.trie.FindFree(sizeBit)
+	}
+
+	return allocator.Allocate(bt.trie.FreeBlocks(), sizeBit)
+}
+
+// allocated records the CIDR allocated from the block in its trie without reading the whole ledger
+// if the trie has read the ledger of the version the allocation is based on
+func (t *allocationTable) allocated(block *controlplanev1alpha1.CIDRBlock, ledgerVersion int64, cidr string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	bt, ok := t.tries[client.ObjectKeyFromObject(block)]
+	if !ok || bt.ledger == nil || bt.ledgerVersion != ledgerVersion {
+		return
+	}
+
+	// A reclaimed prefix is already in the ledger as a released prefix
+	if !bt.ledger[cidr] {
+		bt.ledger[cidr] = true
+		bt.add(cidr)
+	}
+	bt.ledgerVersion = block.Status.LedgerVersion
+}
+
+// blockTrie is the trie of the prefixes used in a CIDRBlock
+type blockTrie struct {
+	trie *ipaddrutil.PrefixTrie
+
+	// cidr and reserved are the CIDR and the reserved prefixes of the block the trie is built for
+	cidr     string
+	reserved []*ipaddr.IPAddress
+
+	// ledgerVersion is the LedgerVersion of the block the ledger is read from, and
+	// ledger is the set of the CIDRs of the allocations and the released prefixes in it
+	ledgerVersion int64
+	ledger        map[string]bool
+
+	// used counts the entries in the ledger and the claims bound to the block for each CIDR
+	used map[string]int
+}
+
+// newBlockTrie returns the trie of the block with the reserved prefixes used, or nil if the CIDR is invalid
+func newBlockTrie(block *controlplanev1alpha1.CIDRBlock, reserved []*ipaddr.IPAddress) *blockTrie {
+	blockSubnet := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+	if blockSubnet == nil {
+		return nil
+	}
+
+	bt := &blockTrie{
+		trie:     ipaddrutil.NewPrefixTrie(blockSubnet),
+		cidr:     block.Spec.CIDR,
+		reserved: reserved,
+		used:     map[string]int{},
+	}
+
+	for _, addr := range reserved {
+		bt.trie.Insert(addr)
+	}
+
+	return bt
+}
+
+// sync applies the changes of the ledger of the block since the trie read it last time.
+// Updates of the block which do not change the ledger like the utilization keep the trie as it is.
+func (bt *blockTrie) sync(block *controlplanev1alpha1.CIDRBlock) {
+	if bt.ledger != nil && bt.ledgerVersion == block.Status.LedgerVersion {
+		return
+	}
+
+	ledger := make(map[string]bool, len(block.Status.Allocations)+len(block.Status.ReleasedPrefixes))
+	for _, a := range block.Status.Allocations {
+		ledger[a.CIDR] = true
+	}
+	for _, p := range block.Status.ReleasedPrefixes {
+		ledger[p.CIDR] = true
+	}
+
+	for cidr := range ledger {
+		if !bt.ledger[cidr] {
+			bt.add(cidr)
+		}
+	}
+	for cidr := range bt.ledger {
+		if !ledger[cidr] {
+			bt.drop(cidr)
+		}
+	}
+
+	bt.ledger = ledger
+	bt.ledgerVersion = block.Status.LedgerVersion
+}
+
+// add counts up the CIDR and marks it used in the trie if it is used for the first time
+func (bt *blockTrie) add(cidr string) {
+	bt.used[cidr]++
+
+	if bt.used[cidr] == 1 {
+		bt.trie.Insert(ipaddr.NewIPAddressString(cidr).GetAddress())
+	}
+}
+
+// drop counts down the CIDR and removes it from the trie if it is no longer used.
+// The reserved prefixes and the larger prefixes still used which overlap it are marked used again.
+func (bt *blockTrie) drop(cidr string) {
+	if bt.used[cidr]--; bt.used[cidr] > 0 {
+		return
+	}
+	delete(bt.used, cidr)
+
+	addr := ipaddr.NewIPAddressString(cidr).GetAddress()
+	if addr == nil || addr.GetPrefixLen() == nil {
+		return
+	}
+	bt.trie.Remove(addr)
+
+	for _, r := range bt.reserved {
+		if r.Overlaps(addr) {
+			bt.trie.Insert(r)
+		}
+	}
+
+	for bits := addr.GetPrefixLen().Len() - 1; bits >= 0; bits-- {
+		supernet := addr.ToPrefixBlockLen(bits)
+
+		if bt.used[supernet.String()] > 0 {
+			bt.trie.Insert(supernet)
+		}
+	}
+}
+
+// equalAddresses returns true if the addresses are equal in the same order
+func equalAddresses(a, b []*ipaddr.IPAddress) bool {
+	return slices.EqualFunc(a, b, func(x, y *ipaddr.IPAddress) bool {
+		return x.Equal(y)
+	})
+}
+
+// eventHandler returns a handler which keeps the table up to date with the watch events of CIDRClaims.
+// It never enqueues requests.
+func (t *allocationTable) eventHandler() handler.EventHandler {
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if claim, ok := e.Object.(*controlplanev1alpha1.CIDRClaim); ok {
+				t.update(claim)
+			}
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if claim, ok := e.ObjectNew.(*controlplanev1alpha1.CIDRClaim); ok {
+				t.update(claim)
+			}
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if claim, ok := e.Object.(*controlplanev1alpha1.CIDRClaim); ok {
+				t.remove(claim)
+			}
+		},
+	}
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/ipaddrutil"
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+func boundClaimForTest(name, blockName, cidr string) *controlplanev1alpha1.CIDRClaim {
+	return &controlplanev1alpha1.CIDRClaim{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID(name),
+		},
+		Status: controlplanev1alpha1.CIDRClaimStatus{
+			CIDRBlockName: blockName,
+			CIDR:          cidr,
+		},
+	}
+}
+
+func TestAllocationTable(t *testing.T) {
+	block := types.NamespacedName{Namespace: "default", Name: "block-001"}
+	other := types.NamespacedName{Namespace: "default", Name: "block-002"}
+
+	table := newAllocationTable()
+	table.update(boundClaimForTest("claim-001", "block-001", "192.168.1.0/30"))
+	table.update(boundClaimForTest("claim-002", "block-001", "192.168.1.4/30"))
+	table.update(boundClaimForTest("claim-003", "block-002", "192.168.2.0/30"))
+
+	cidrs := func(block types.NamespacedName, except types.UID) string {
+		var cidrs []string
+		for _, a := range table.allocations(block, except) {
+			cidrs = append(cidrs, a.CIDR)
+		}
+		sort.Strings(cidrs)
+
+		return fmt.Sprint(cidrs)
+	}
+
+	if got, want := cidrs(block, ""), "[192.168.1.0/30 192.168.1.4/30]"; got != want {
+		t.Errorf("allocations() = %v, want %v", got, want)
+	}
+
+	if got, want := cidrs(block, "claim-001"), "[192.168.1.4/30]"; got != want {
+		t.Errorf("allocations() except claim-001 = %v, want %v", got, want)
+	}
+
+	// Rebound to another block
+	table.update(boundClaimForTest("claim-002", "block-002", "192.168.2.4/30"))
+
+	if got, want := cidrs(block, ""), "[192.168.1.0/30]"; got != want {
+		t.Errorf("allocations() after rebind = %v, want %v", got, want)
+	}
+	if got, want := cidrs(other, ""), "[192.168.2.0/30 192.168.2.4/30]"; got != want {
+		t.Errorf("allocations() of block-002 after rebind = %v, want %v", got, want)
+	}
+
+	table.remove(boundClaimForTest("claim-001", "", ""))
+
+	if got, want := cidrs(block, ""), "[]"; got != want {
+		t.Errorf("allocations() after remove = %v, want %v", got, want)
+	}
+	if _, ok := table.blocks[block]; ok {
+		t.Errorf("empty block is left in the table")
+	}
+}
+
+func TestAllocationTableFindFree(t *testing.T) {
+	key := types.NamespacedName{Namespace: "default", Name: "block-001"}
+	block := &controlplanev1alpha1.CIDRBlock{
+		ObjectMeta: v1.ObjectMeta{Name: key.Name, Namespace: key.Namespace, ResourceVersion: "1"},
+		Spec: controlplanev1alpha1.CIDRBlockSpec{
+			CIDR:     "192.168.1.0/24",
+			Excludes: []string{"192.168.1.0/30"},
+		},
+		Status: controlplanev1alpha1.CIDRBlockStatus{
+			Allocations: []controlplanev1alpha1.CIDRBlockAllocation{
+				{ClaimName: "ledger", CIDR: "192.168.1.4/30"},
+			},
+			ReleasedPrefixes: []controlplanev1alpha1.CIDRBlockReleasedPrefix{
+				{ClaimName: "released", CIDR: "192.168.1.8/30"},
+			},
+			LedgerVersion: 1,
+		},
+	}
+	reservations := []controlplanev1alpha1.CIDRReservation{{
+		ObjectMeta: v1.ObjectMeta{Name: "reservation", Namespace: "default", UID: "reservation", ResourceVersion: "1"},
+		Spec:       controlplanev1alpha1.CIDRReservationSpec{CIDR: "192.168.1.12/30"},
+	}}
+
+	table := newAllocationTable()
+	table.update(boundClaimForTest("claim-001", key.Name, "192.168.1.16/30"))
+
+	// The result is the same as the one of the free blocks computed for each allocation
+	want := func(sizeBit int, allocator ipaddrutil.Allocator) string {
+		blockSubnet := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+		free := freeBlocksFor(block, blockSubnet, sizeBit, table.allocations(key, ""), reservations)
+
+		return fmt.Sprint(allocator.Allocate(free, sizeBit))
+	}
+
+	for sizeBit := 1; sizeBit <= 8; sizeBit++ {
+		for _, allocator := range []ipaddrutil.Allocator{ipaddrutil.FirstFit{}, ipaddrutil.BestFit{}} {
+			if got, want := fmt.Sprint(table.findFree(block, reservations, sizeBit, allocator)), want(sizeBit, allocator); got != want {
+				t.Errorf("findFree(%d) with %T = %v, want %v", sizeBit, allocator, got, want)
+			}
+		}
+	}
+
+	// A prefix recorded in the ledger is inserted into the cached trie
+	bt := table.tries[key]
+	allocated := table.findFree(block, reservations, 2, ipaddrutil.FirstFit{})
+	updated := block.DeepCopy()
+	updated.ResourceVersion = "2"
+	updated.Status.LedgerVersion = 2
+	updated.Status.Allocations = append(updated.Status.Allocations, controlplanev1alpha1.CIDRBlockAllocation{
+		ClaimName: "claim-002",
+		CIDR:      allocated.String(),
+	})
+	table.allocated(updated, block.Status.LedgerVersion, allocated.String())
+
+	if got := table.tries[key]; got != bt || got.ledgerVersion != 2 {
+		t.Fatalf("trie is not kept after the allocation: %+v", got)
+	}
+	if got := table.findFree(updated, reservations, 2, ipaddrutil.FirstFit{}); got.Equal(allocated) {
+		t.Errorf("findFree() = %v, which has just been allocated", got)
+	}
+
+	// An update of the block which keeps the ledger keeps the trie
+	updated = updated.DeepCopy()
+	updated.ResourceVersion = "3"
+	updated.Status.BoundClaims = 2
+	if got, want := table.findFree(updated, reservations, 2, ipaddrutil.FirstFit{}).String(), "192.168.1.24/30"; got != want {
+		t.Errorf("findFree() after a status update = %v, want %v", got, want)
+	}
+	if got := table.tries[key]; got != bt {
+		t.Errorf("trie is rebuilt after a status update")
+	}
+
+	// A claim bound to the block is inserted, and removed if it is unbound
+	table.update(boundClaimForTest("claim-003", key.Name, "192.168.1.24/30"))
+	if got, want := table.findFree(updated, reservations, 2, ipaddrutil.FirstFit{}).String(), "192.168.1.28/30"; got != want {
+		t.Errorf("findFree() after the claim is bound = %v, want %v", got, want)
+	}
+
+	table.remove(boundClaimForTest("claim-001", "", ""))
+	if got, want := table.findFree(updated, reservations, 2, ipaddrutil.FirstFit{}).String(), "192.168.1.16/30"; got != want {
+		t.Errorf("findFree() after the claim is unbound = %v, want %v", got, want)
+	}
+	if got := table.tries[key]; got != bt {
+		t.Errorf("trie is rebuilt after the claim is unbound")
+	}
+
+	// A prefix released from the ledger is free again, and the prefix recorded in both the ledger
+	// and the claim is used until it is removed from both
+	table.update(boundClaimForTest("claim-002", key.Name, allocated.String()))
+	updated = updated.DeepCopy()
+	updated.ResourceVersion = "4"
+	updated.Status.LedgerVersion = 3
+	updated.Status.Allocations = updated.Status.Allocations[:1]
+	updated.Status.ReleasedPrefixes = nil
+	if got, want := table.findFree(updated, reservations, 2, ipaddrutil.FirstFit{}).String(), "192.168.1.8/30"; got != want {
+		t.Errorf("findFree() after the ledger is changed = %v, want %v", got, want)
+	}
+	table.remove(boundClaimForTest("claim-002", "", ""))
+	table.remove(boundClaimForTest("claim-003", "", ""))
+	if got, want := fmt.Sprint(table.tries[key].trie.FreeBlocks()), fmt.Sprint(freeBlocksFor(updated, ipaddr.NewIPAddressString(updated.Spec.CIDR).GetAddress(), 0, nil, reservations)); got != want {
+		t.Errorf("free blocks after the claims are unbound = %v, want %v", got, want)
+	}
+
+	// The trie is rebuilt if the reservations are changed
+	reservations[0].ResourceVersion = "2"
+	reservations[0].Spec.CIDR = "192.168.1.16/29"
+	if got, want := table.findFree(updated, reservations, 2, ipaddrutil.FirstFit{}).String(), "192.168.1.8/30"; got != want {
+		t.Errorf("findFree() after the reservation is changed = %v, want %v", got, want)
+	}
+	if got := table.tries[key]; got == bt {
+		t.Errorf("trie is kept after the reservation is changed")
+	}
+}
+
+// BenchmarkAllocationTable measures an allocation from a block as done for each CIDRClaim:
+// the search of a free prefix, the record of it in the ledger, and the status update of the
+// block by the CIDRBlock controller which follows it. The time should stay flat as the number
+// of the claims bound to the block grows.
+func BenchmarkAllocationTable(b *testing.B) {
+	for _, claims := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("claims=%d", claims), func(b *testing.B) {
+			table := newAllocationTable()
+			block := &controlplanev1alpha1.CIDRBlock{
+				ObjectMeta: v1.ObjectMeta{Name: "block-0", Namespace: "default", ResourceVersion: "1"},
+				Spec:       controlplanev1alpha1.CIDRBlockSpec{CIDR: "10.0.0.0/8"},
+				Status:     controlplanev1alpha1.CIDRBlockStatus{LedgerVersion: 1},
+			}
+
+			for i := 0; i < claims; i++ {
+				name := fmt.Sprintf("claim-%d", i)
+				cidr := fmt.Sprintf("10.%d.%d.%d/32", i>>16&0xff, i>>8&0xff, i&0xff)
+
+				table.update(boundClaimForTest(name, block.Name, cidr))
+				block.Status.Allocations = append(block.Status.Allocations, controlplanev1alpha1.CIDRBlockAllocation{
+					ClaimName: name,
+					ClaimUID:  types.UID(name),
+					CIDR:      cidr,
+				})
+			}
+
+			// The trie is built before the first allocation
+			if table.findFree(block, nil, 1, ipaddrutil.FirstFit{}) == nil {
+				b.Fatal("no free prefix")
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				allocated := table.findFree(block, nil, 1, ipaddrutil.FirstFit{})
+				if allocated == nil {
+					b.Fatal("no free prefix")
+				}
+
+				// The allocation patches the ledger
+				ledgerVersion := block.Status.LedgerVersion
+				block.Status.Allocations = append(block.Status.Allocations, controlplanev1alpha1.CIDRBlockAllocation{
+					ClaimName: "allocated",
+					CIDR:      allocated.String(),
+				})
+				block.Status.LedgerVersion++
+				block.ResourceVersion = strconv.Itoa(2*i + 2)
+				table.allocated(block, ledgerVersion, allocated.String())
+
+				// The CIDRBlock controller patches the utilization in the status
+				block.ResourceVersion = strconv.Itoa(2*i + 3)
+			}
+		})
+	}
+}
@@ -0,0 +1,490 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+// CIDRBlockReconciler reconciles a CIDRBlock object
+type CIDRBlockReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// IPAM releases the prefixes in the external IPAM recorded in the ledger for
+	// CIDRClaims which no longer exist. They are only removed from the ledger if it is nil.
+	IPAM IPAMProvider
+}
+
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrblocks,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrblocks/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrblocks/finalizers,verbs=update
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrclaims,verbs=get;list;watch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrreservations,verbs=get;list;watch
+
+// Reconcile keeps the allocation ledger of the CIDRBlock consistent with the
+// CIDRClaims bound to it and reports the utilization in the status.
+// Deletion of the CIDRBlock is blocked by a finalizer while CIDRClaims are bound to it.
+func (r *CIDRBlockReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var cidrBlock controlplanev1alpha1.CIDRBlock
+
+	err := r.Get(ctx, req.NamespacedName, &cidrBlock)
+
+	if errors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get CIDRBlock: %w", err)
+	}
+
+	if cidrBlock.DeletionTimestamp == nil && !controllerutil.ContainsFinalizer(&cidrBlock, cidrBlockFinalizer) {
+		if err := patchFinalizer(ctx, r.Client, &cidrBlock, cidrBlockFinalizer, true); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	var cidrClaims controlplanev1alpha1.CIDRClaimList
+	if err := r.List(ctx, &cidrClaims, client.MatchingFields{
+		cidrClaimBlockNameField: req.NamespacedName.String(),
+	}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list CIDRClaims: %w", err)
+	}
+
+	bound := make([]controlplanev1alpha1.CIDRClaim, 0, len(cidrClaims.Items))
+	for _, claim := range cidrClaims.Items {
+		if boundCIDR(&claim, &cidrBlock) == "" {
+			continue
+		}
+
+		bound = append(bound, claim)
+	}
+
+	claims, err := r.ledgerClaims(ctx, &cidrBlock, bound)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	reservations, err := listReservations(ctx, r.Client, req.Namespace)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	allocations, duplicated := repairAllocations(&cidrBlock, claims, bound)
+
+	if r.IPAM != nil {
+		orphans := orphanedClaims(&cidrBlock, claims)
+
+		for i := range orphans {
+			if err := r.IPAM.Release(ctx, &orphans[i], nil); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to release allocations of CIDRClaim %s: %w", orphans[i].Name, err)
+			}
+		}
+
+		// The ledger updated by the releases is reconciled again
+		if len(orphans) != 0 {
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if cidrBlock.DeletionTimestamp != nil && len(bound) == 0 {
+		return ctrl.Result{}, patchFinalizer(ctx, r.Client, &cidrBlock, cidrBlockFinalizer, false)
+	}
+
+	now := time.Now()
+	released := append(cidrBlock.Status.ReleasedPrefixes, quarantineOrphans(&cidrBlock, claims, now)...)
+	released, requeueAfter := expireReleased(&cidrBlock, released, now)
+
+	status := cidrBlock.Status.DeepCopy()
+	status.Allocations = allocations
+	status.ReleasedPrefixes = released
+	if !equality.Semantic.DeepEqual(cidrBlock.Status.Allocations, status.Allocations) ||
+		!equality.Semantic.DeepEqual(cidrBlock.Status.ReleasedPrefixes, status.ReleasedPrefixes) {
+		status.LedgerVersion++
+	}
+	computeCIDRBlockStatus(&cidrBlock, status, len(bound), reservedAddresses(&cidrBlock, reservations))
+
+	status.BlockingClaims = nil
+	if cidrBlock.DeletionTimestamp != nil {
+		for _, claim := range bound {
+			name := claim.Name
+			if claim.Namespace != cidrBlock.Namespace {
+				name = client.ObjectKeyFromObject(&claim).String()
+			}
+
+			status.BlockingClaims = append(status.BlockingClaims, name)
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(&cidrBlock.Status, status) {
+		updated := cidrBlock.DeepCopy()
+		updated.Status = *status
+
+		if err := r.Status().Patch(ctx, updated, client.MergeFromWithOptions(&cidrBlock, client.MergeFromWithOptimisticLock{})); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+	}
+
+	for i := range duplicated {
+		claim := &duplicated[i]
+
+		updated := claim.DeepCopy()
+		updated.Status.State = controlplanev1alpha1.CIDRClaimStatusStateBindingError
+		updated.Status.Message = fmt.Sprintf("%s is allocated to another CIDRClaim in CIDRBlock %s", boundCIDR(claim, &cidrBlock), cidrBlock.Name)
+		updated.Status.CIDRBlockName = ""
+		updated.Status.CIDRBlockNamespace = ""
+		updated.Status.CIDR = ""
+		updated.Status.SizeBit = 0
+		updated.Status.CIDRs = nil
+
+		if err := r.Status().Patch(ctx, updated, client.MergeFrom(claim)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to reset duplicated CIDRClaim %s: %w", claim.Name, err)
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// ledgerClaims returns the bound claims and the claims recorded in the ledger of the block
+func (r *CIDRBlockReconciler) ledgerClaims(
+	ctx context.Context,
+	block *controlplanev1alpha1.CIDRBlock,
+	bound []controlplanev1alpha1.CIDRClaim,
+) ([]controlplanev1alpha1.CIDRClaim, error) {
+	claims := append([]controlplanev1alpha1.CIDRClaim{}, bound...)
+
+	found := make(map[types.NamespacedName]bool, len(bound))
+	for _, claim := range bound {
+		found[client.ObjectKeyFromObject(&claim)] = true
+	}
+
+	for _, a := range block.Status.Allocations {
+		key := allocationClaimKey(block, &a)
+
+		if found[key] {
+			continue
+		}
+		found[key] = true
+
+		var claim controlplanev1alpha1.CIDRClaim
+		err := r.Get(ctx, key, &claim)
+
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get CIDRClaim %s: %w", key, err)
+		}
+
+		claims = append(claims, claim)
+	}
+
+	return claims, nil
+}
+
+// repairAllocations returns the repaired ledger of the block and the claims whose CIDR
+// has to be reallocated.
+//   - Allocations for claims which no longer exist are released.
+//   - Overlapping allocations are released except the oldest one.
+//   - Claims bound to the block but missing in the ledger are recorded if their CIDR is free,
+//     otherwise they are reported as duplicated.
+func repairAllocations(
+	block *controlplanev1alpha1.CIDRBlock,
+	claims []controlplanev1alpha1.CIDRClaim,
+	bound []controlplanev1alpha1.CIDRClaim,
+) (allocations []controlplanev1alpha1.CIDRBlockAllocation, duplicated []controlplanev1alpha1.CIDRClaim) {
+	byKey := claimsByKey(claims)
+
+	allocations = make([]controlplanev1alpha1.CIDRBlockAllocation, 0, len(block.Status.Allocations))
+	used := make([]*ipaddr.IPAddress, 0, len(block.Status.Allocations))
+	for _, a := range block.Status.Allocations {
+		claim, ok := byKey[allocationClaimKey(block, &a)]
+
+		if !ok || !isAllocationOf(&a, claim) {
+			continue
+		}
+
+		addr := ipaddr.NewIPAddressString(a.CIDR).GetAddress()
+
+		if addr == nil || overlapsAny(addr, used) {
+			continue
+		}
+
+		allocations = append(allocations, a)
+		used = append(used, addr)
+	}
+
+	for _, claim := range bound {
+		status := boundStatus(&claim, block)
+		if status == nil {
+			continue
+		}
+		cidr := status.CIDR
+
+		recorded := false
+		for _, a := range allocations {
+			if isAllocationOf(&a, &claim) && a.CIDR == cidr {
+				recorded = true
+
+				break
+			}
+		}
+
+		if recorded {
+			continue
+		}
+
+		addr := ipaddr.NewIPAddressString(cidr).GetAddress()
+
+		if addr == nil {
+			continue
+		}
+
+		if overlapsAny(addr, used) {
+			duplicated = append(duplicated, claim)
+
+			continue
+		}
+
+		// The prefix reserved in the external IPAM is released with the ID in the status
+		allocations = append(allocations, controlplanev1alpha1.CIDRBlockAllocation{
+			ClaimName:      claim.Name,
+			ClaimNamespace: claim.Namespace,
+			ClaimUID:       claim.UID,
+			CIDR:           addr.String(),
+			ExternalID:     status.ExternalID,
+		})
+		used = append(used, addr)
+	}
+
+	return allocations, duplicated
+}
+
+// orphanedClaims returns the CIDRClaims which no longer exist but have the prefixes reserved
+// in the external IPAM recorded in the ledger of the block. They have the names and the UIDs
+// of the allocations so that the allocations are released with them.
+func orphanedClaims(block *controlplanev1alpha1.CIDRBlock, claims []controlplanev1alpha1.CIDRClaim) []controlplanev1alpha1.CIDRClaim {
+	byKey := claimsByKey(claims)
+
+	var orphans []controlplanev1alpha1.CIDRClaim
+	for _, a := range block.Status.Allocations {
+		key := allocationClaimKey(block, &a)
+
+		if claim, ok := byKey[key]; a.ExternalID == "" || (ok && isAllocationOf(&a, claim)) {
+			continue
+		}
+
+		orphan := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, UID: a.ClaimUID},
+		}
+
+		if !slices.ContainsFunc(orphans, func(o controlplanev1alpha1.CIDRClaim) bool {
+			return o.Namespace == orphan.Namespace && o.Name == orphan.Name && o.UID == orphan.UID
+		}) {
+			orphans = append(orphans, orphan)
+		}
+	}
+
+	return orphans
+}
+
+// quarantineOrphans returns the released prefixes for allocations of claims which no
+// longer exist if the block has a quarantine period.
+func quarantineOrphans(
+	block *controlplanev1alpha1.CIDRBlock,
+	claims []controlplanev1alpha1.CIDRClaim,
+	now time.Time,
+) []controlplanev1alpha1.CIDRBlockReleasedPrefix {
+	if quarantinePeriod(block) <= 0 {
+		return nil
+	}
+
+	byKey := claimsByKey(claims)
+
+	var released []controlplanev1alpha1.CIDRBlockReleasedPrefix
+	for _, a := range block.Status.Allocations {
+		if claim, ok := byKey[allocationClaimKey(block, &a)]; ok && isAllocationOf(&a, claim) {
+			continue
+		}
+
+		released = append(released, controlplanev1alpha1.CIDRBlockReleasedPrefix{
+			ClaimName:      a.ClaimName,
+			ClaimNamespace: a.ClaimNamespace,
+			CIDR:           a.CIDR,
+			ReleasedAt:     metav1.NewTime(now),
+		})
+	}
+
+	return released
+}
+
+// computeCIDRBlockStatus fills the utilization of the block from its ledger and reserved ranges
+func computeCIDRBlockStatus(
+	block *controlplanev1alpha1.CIDRBlock,
+	status *controlplanev1alpha1.CIDRBlockStatus,
+	boundClaims int,
+	reserved []*ipaddr.IPAddress,
+) {
+	status.BoundClaims = boundClaims
+	status.AllocatedAddresses = ""
+	status.FreeAddresses = ""
+	status.FreePrefixes = nil
+	status.LargestFreePrefixes = nil
+	status.ReservedCIDRs = nil
+
+	blockSubnet := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+
+	if blockSubnet == nil {
+		return
+	}
+	blockSubnet = blockSubnet.ToPrefixBlock()
+
+	allocated := big.NewInt(0)
+	used := make([]*ipaddr.IPAddress, 0, len(status.Allocations))
+	for _, addr := range ledgerAddresses(status.Allocations) {
+		if !blockSubnet.Contains(addr) {
+			continue
+		}
+
+		allocated.Add(allocated, addr.GetCount())
+		used = append(used, addr)
+	}
+
+	for _, addr := range reserved {
+		status.ReservedCIDRs = append(status.ReservedCIDRs, addr.String())
+		used = append(used, addr)
+	}
+
+	// Released prefixes are neither allocated nor free until they are reclaimed or expire
+	for _, p := range status.ReleasedPrefixes {
+		if addr := ipaddr.NewIPAddressString(p.CIDR).GetAddress(); addr != nil {
+			used = append(used, addr)
+		}
+	}
+
+	free := big.NewInt(0)
+	var largest *ipaddr.IPAddress
+	for _, block := range freeBlocks(blockSubnet, used) {
+		free.Add(free, block.GetCount())
+		status.FreePrefixes = append(status.FreePrefixes, block.String())
+
+		if largest == nil || block.GetPrefixLen().Len() < largest.GetPrefixLen().Len() {
+			largest = block
+		}
+	}
+
+	status.AllocatedAddresses = allocated.String()
+	status.FreeAddresses = free.String()
+
+	if largest != nil {
+		status.LargestFreePrefixes = []controlplanev1alpha1.FamilyCIDR{
+			{
+				Family: addressFamily(largest),
+				CIDR:   largest.String(),
+			},
+		}
+	}
+}
+
+func addressFamily(addr *ipaddr.IPAddress) controlplanev1alpha1.AddressFamily {
+	if addr.IsIPv6() {
+		return controlplanev1alpha1.AddressFamilyIPv6
+	}
+
+	return controlplanev1alpha1.AddressFamilyIPv4
+}
+
+// claimsByKey returns a map from the keys of the claims to them
+func claimsByKey(claims []controlplanev1alpha1.CIDRClaim) map[types.NamespacedName]*controlplanev1alpha1.CIDRClaim {
+	m := make(map[types.NamespacedName]*controlplanev1alpha1.CIDRClaim, len(claims))
+	for i := range claims {
+		m[client.ObjectKeyFromObject(&claims[i])] = &claims[i]
+	}
+
+	return m
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CIDRBlockReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(
+		context.Background(), &controlplanev1alpha1.CIDRClaim{},
+		cidrClaimBlockNameField, indexCIDRClaimBlockNames,
+	); err != nil {
+		return fmt.Errorf("failed to index CIDRClaims: %w", err)
+	}
+
+	cidrClaimHandler := handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+		cidrClaim := o.(*controlplanev1alpha1.CIDRClaim)
+
+		var requests []reconcile.Request
+		for _, s := range cidrClaim.Status.AllocatedStatuses() {
+			if s.CIDRBlockName == "" {
+				continue
+			}
+
+			requests = append(requests, reconcile.Request{
+				NamespacedName: blockKeyOf(cidrClaim, &s),
+			})
+		}
+
+		return requests
+	})
+
+	cidrReservationHandler := handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+		var cidrBlocks controlplanev1alpha1.CIDRBlockList
+		if err := r.List(ctx, &cidrBlocks, &client.ListOptions{
+			Namespace: o.GetNamespace(),
+		}); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list CIDRBlocks")
+
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(cidrBlocks.Items))
+		for _, block := range cidrBlocks.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&block),
+			})
+		}
+
+		return requests
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&controlplanev1alpha1.CIDRBlock{}).
+		Watches(&controlplanev1alpha1.CIDRClaim{}, cidrClaimHandler).
+		Watches(&controlplanev1alpha1.CIDRReservation{}, cidrReservationHandler).
+		Complete(r)
+}
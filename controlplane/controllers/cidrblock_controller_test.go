@@ -0,0 +1,370 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+var _ = Describe("CIDRBlock", func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		deleteAll(ctx)
+
+		scheme := scheme.Scheme
+
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme: scheme,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = (&CIDRClaimReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   scheme,
+			Recorder: mgr.GetEventRecorder("cidrclaim-controller"),
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = (&CIDRBlockReconciler{
+			Client: mgr.GetClient(),
+			Scheme: scheme,
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		go func() {
+			err := mgr.Start(ctx)
+
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		cancel()
+
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("Report utilization", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrClaim := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-claim",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+				SizeBit: 7,
+			},
+		}
+
+		err = k8sClient.Create(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrBlockKey := client.ObjectKeyFromObject(&cidrBlock)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+
+			if err != nil {
+				return err
+			}
+
+			if cidrBlock.Status.BoundClaims != 1 {
+				return fmt.Errorf("not updated")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(cidrBlock.Status.Allocations).To(HaveLen(1))
+		Expect(cidrBlock.Status.Allocations[0].ClaimName).To(Equal(cidrClaim.Name))
+		Expect(cidrBlock.Status.Allocations[0].CIDR).To(Equal("192.168.1.0/25"))
+		Expect(cidrBlock.Status.AllocatedAddresses).To(Equal("128"))
+		Expect(cidrBlock.Status.FreeAddresses).To(Equal("128"))
+		Expect(cidrBlock.Status.FreePrefixes).To(Equal([]string{"192.168.1.128/25"}))
+		Expect(cidrBlock.Status.LargestFreePrefixes).To(Equal([]controlplanev1alpha1.FamilyCIDR{
+			{
+				Family: controlplanev1alpha1.AddressFamilyIPv4,
+				CIDR:   "192.168.1.128/25",
+			},
+		}))
+
+		err = k8sClient.Delete(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+
+			if err != nil {
+				return err
+			}
+
+			if cidrBlock.Status.BoundClaims != 0 {
+				return fmt.Errorf("not updated")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(cidrBlock.Status.Allocations).To(BeEmpty())
+		Expect(cidrBlock.Status.AllocatedAddresses).To(Equal("0"))
+		Expect(cidrBlock.Status.FreeAddresses).To(Equal("256"))
+		Expect(cidrBlock.Status.FreePrefixes).To(Equal([]string{"192.168.1.0/24"}))
+	})
+
+	It("Exclude reserved ranges", func() {
+		cidrReservation := controlplanev1alpha1.CIDRReservation{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-reservation",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRReservationSpec{
+				CIDR: "192.168.1.64/26",
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrReservation)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+				Excludes: []string{
+					"192.168.1.0/26",
+				},
+			},
+		}
+
+		err = k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrClaim := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-claim",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+				SizeBit: 6,
+			},
+		}
+
+		err = k8sClient.Create(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrBlockKey := client.ObjectKeyFromObject(&cidrBlock)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+
+			if err != nil {
+				return err
+			}
+
+			if cidrBlock.Status.BoundClaims != 1 {
+				return fmt.Errorf("not updated")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(cidrBlock.Status.Allocations).To(HaveLen(1))
+		Expect(cidrBlock.Status.Allocations[0].CIDR).To(Equal("192.168.1.128/26"))
+		Expect(cidrBlock.Status.ReservedCIDRs).To(Equal([]string{"192.168.1.0/26", "192.168.1.64/26"}))
+		Expect(cidrBlock.Status.FreeAddresses).To(Equal("64"))
+		Expect(cidrBlock.Status.FreePrefixes).To(Equal([]string{"192.168.1.192/26"}))
+	})
+
+	It("Block deletion while claims are bound", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrClaim := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-claim",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+				SizeBit: 2,
+			},
+		}
+
+		err = k8sClient.Create(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrBlockKey := client.ObjectKeyFromObject(&cidrBlock)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+
+			if err != nil {
+				return err
+			}
+
+			if cidrBlock.Status.BoundClaims != 1 {
+				return fmt.Errorf("not updated")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		err = k8sClient.Delete(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+
+			if err != nil {
+				return err
+			}
+
+			if len(cidrBlock.Status.BlockingClaims) == 0 {
+				return fmt.Errorf("not updated")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(cidrBlock.DeletionTimestamp).NotTo(BeNil())
+		Expect(cidrBlock.Status.BlockingClaims).To(Equal([]string{cidrClaim.Name}))
+
+		cidrClaimKey := client.ObjectKeyFromObject(&cidrClaim)
+		err = k8sClient.Get(ctx, cidrClaimKey, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cidrClaim.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateReady))
+		Expect(cidrClaim.Status.CIDRBlockName).To(Equal(cidrBlock.Name))
+
+		err = k8sClient.Delete(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() bool {
+			return errors.IsNotFound(k8sClient.Get(ctx, cidrClaimKey, &cidrClaim))
+		}).Should(BeTrue())
+
+		Eventually(func() bool {
+			return errors.IsNotFound(k8sClient.Get(ctx, cidrBlockKey, &cidrBlock))
+		}).Should(BeTrue())
+	})
+
+	It("Repair duplicated allocations", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+			},
+			Status: controlplanev1alpha1.CIDRBlockStatus{
+				Allocations: []controlplanev1alpha1.CIDRBlockAllocation{
+					{ClaimName: "claim-a", ClaimUID: "uid-a", CIDR: "192.168.1.0/26"},
+					{ClaimName: "claim-b", ClaimUID: "uid-b", CIDR: "192.168.1.0/25"},
+					{ClaimName: "claim-deleted", ClaimUID: "uid-deleted", CIDR: "192.168.1.128/26"},
+				},
+			},
+		}
+
+		claim := func(name, uid, cidr string) controlplanev1alpha1.CIDRClaim {
+			return controlplanev1alpha1.CIDRClaim{
+				ObjectMeta: v1.ObjectMeta{
+					Name: name,
+					UID:  types.UID(uid),
+				},
+				Status: controlplanev1alpha1.CIDRClaimStatus{
+					State:         controlplanev1alpha1.CIDRClaimStatusStateReady,
+					CIDRBlockName: cidrBlock.Name,
+					CIDR:          cidr,
+				},
+			}
+		}
+
+		claims := []controlplanev1alpha1.CIDRClaim{
+			claim("claim-a", "uid-a", "192.168.1.0/26"),
+			claim("claim-b", "uid-b", "192.168.1.0/25"),
+			claim("claim-legacy", "uid-legacy", "192.168.1.192/26"),
+		}
+		claims[2].Status.CIDRs = claims[2].Status.FamilyStatuses()
+		claims[2].Status.CIDRs[0].ExternalID = "42"
+
+		allocations, duplicated := repairAllocations(&cidrBlock, claims, claims)
+
+		Expect(allocations).To(Equal([]controlplanev1alpha1.CIDRBlockAllocation{
+			{ClaimName: "claim-a", ClaimUID: "uid-a", CIDR: "192.168.1.0/26"},
+			{ClaimName: "claim-legacy", ClaimUID: "uid-legacy", CIDR: "192.168.1.192/26", ExternalID: "42"},
+		}))
+		Expect(duplicated).To(HaveLen(1))
+		Expect(duplicated[0].Name).To(Equal("claim-b"))
+
+		// Only the allocations reserved in the external IPAM are released by the provider
+		Expect(orphanedClaims(&cidrBlock, claims)).To(BeEmpty())
+
+		cidrBlock.Status.Allocations[2].ExternalID = "7"
+		orphans := orphanedClaims(&cidrBlock, claims)
+
+		Expect(orphans).To(HaveLen(1))
+		Expect(orphans[0].Name).To(Equal("claim-deleted"))
+		Expect(orphans[0].UID).To(Equal(types.UID("uid-deleted")))
+	})
+})
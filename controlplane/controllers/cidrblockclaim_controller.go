@@ -0,0 +1,288 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+// CIDRBlockClaimReconciler reconciles a CIDRBlockClaim object
+type CIDRBlockClaimReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrblockclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrblockclaims/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrblockclaims/finalizers,verbs=update
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrblocks,verbs=create;delete
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrclaims,verbs=create;update;patch;delete
+
+// Reconcile allocates a prefix from the parent CIDRBlocks through a CIDRClaim
+// owned by the CIDRBlockClaim, and creates the child CIDRBlock with the prefix.
+// The child CIDRBlock has the same name as the CIDRBlockClaim while the CIDRClaim
+// is named by BlockClaimCIDRClaimName. The CIDRClaim is deleted
+// only after the child CIDRBlock is gone so that the prefix is not released while
+// claims are bound to the child.
+func (r *CIDRBlockClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var blockClaim controlplanev1alpha1.CIDRBlockClaim
+
+	err := r.Get(ctx, req.NamespacedName, &blockClaim)
+
+	if errors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get CIDRBlockClaim: %w", err)
+	}
+
+	if blockClaim.DeletionTimestamp != nil {
+		return ctrl.Result{}, r.finalize(ctx, &blockClaim)
+	}
+
+	if err := patchFinalizer(ctx, r.Client, &blockClaim, cidrBlockClaimFinalizer, true); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var cidrBlock controlplanev1alpha1.CIDRBlock
+	cidrBlock.Namespace = blockClaim.Namespace
+	cidrBlock.Name = blockClaim.Name
+
+	if err := r.Get(ctx, client.ObjectKeyFromObject(&cidrBlock), &cidrBlock); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get CIDRBlock: %w", err)
+	}
+
+	cidrClaimKey, err := r.cidrClaimKey(ctx, &blockClaim)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The claim deleted by someone else is recreated after the unused child is deleted
+	var cidrClaim controlplanev1alpha1.CIDRClaim
+	if err := r.Get(ctx, cidrClaimKey, &cidrClaim); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get CIDRClaim: %w", err)
+	}
+	if cidrClaim.UID != "" && !metav1.IsControlledBy(&cidrClaim, &blockClaim) {
+		return ctrl.Result{}, r.updateStatus(ctx, &blockClaim, &controlplanev1alpha1.CIDRBlockClaimStatus{
+			State:   controlplanev1alpha1.CIDRClaimStatusStateBindingError,
+			Message: fmt.Sprintf("CIDRClaim %s exists and is not controlled by the CIDRBlockClaim", cidrClaim.Name),
+		})
+	}
+	if cidrClaim.DeletionTimestamp != nil {
+		if inUse(&cidrBlock) {
+			return ctrl.Result{}, r.updateStatus(ctx, &blockClaim, &controlplanev1alpha1.CIDRBlockClaimStatus{
+				State:         controlplanev1alpha1.CIDRClaimStatusStateBindingError,
+				Message:       fmt.Sprintf("CIDRClaim %s is being deleted while CIDRBlock %s has allocations", cidrClaim.Name, cidrBlock.Name),
+				CIDR:          cidrBlock.Spec.CIDR,
+				CIDRBlockName: cidrBlock.Name,
+			})
+		}
+
+		return ctrl.Result{}, r.finalize(ctx, &blockClaim)
+	}
+
+	cidrClaim = controlplanev1alpha1.CIDRClaim{}
+	cidrClaim.Namespace = cidrClaimKey.Namespace
+	cidrClaim.Name = cidrClaimKey.Name
+
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, &cidrClaim, func() error {
+		controllerutil.AddFinalizer(&cidrClaim, cidrBlockClaimFinalizer)
+
+		cidrClaim.Spec.Selector = blockClaim.Spec.Selector
+		cidrClaim.Spec.SizeBit = blockClaim.Spec.SizeBit
+
+		// The prefix is pinned while claims are bound to the child so that it does not move under them
+		cidrClaim.Spec.RequestedCIDR = ""
+		if inUse(&cidrBlock) {
+			cidrClaim.Spec.RequestedCIDR = cidrBlock.Spec.CIDR
+		}
+
+		return controllerutil.SetControllerReference(&blockClaim, &cidrClaim, r.Scheme)
+	})
+
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to upsert CIDRClaim: %w", err)
+	}
+
+	if cidrClaim.Status.ObservedGeneration != cidrClaim.Generation {
+		return ctrl.Result{}, nil
+	}
+
+	if cidrClaim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+		return ctrl.Result{}, r.updateStatus(ctx, &blockClaim, &controlplanev1alpha1.CIDRBlockClaimStatus{
+			State:   cidrClaim.Status.State,
+			Message: cidrClaim.Status.Message,
+		})
+	}
+
+	if inUse(&cidrBlock) && cidrBlock.Spec.CIDR != cidrClaim.Status.CIDR {
+		return ctrl.Result{}, r.updateStatus(ctx, &blockClaim, &controlplanev1alpha1.CIDRBlockClaimStatus{
+			State: controlplanev1alpha1.CIDRClaimStatusStateBindingError,
+			Message: fmt.Sprintf(
+				"CIDRBlock %s has allocations in %s and cannot be moved to %s",
+				cidrBlock.Name, cidrBlock.Spec.CIDR, cidrClaim.Status.CIDR,
+			),
+			CIDR:          cidrBlock.Spec.CIDR,
+			CIDRBlockName: cidrBlock.Name,
+		})
+	}
+
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, &cidrBlock, func() error {
+		if cidrBlock.Labels == nil {
+			cidrBlock.Labels = map[string]string{}
+		}
+		for k, v := range blockClaim.Spec.Template.Labels {
+			cidrBlock.Labels[k] = v
+		}
+
+		cidrBlock.Spec.CIDR = cidrClaim.Status.CIDR
+		cidrBlock.Spec.AllocationStrategy = blockClaim.Spec.Template.AllocationStrategy
+		cidrBlock.Spec.Excludes = blockClaim.Spec.Template.Excludes
+
+		return controllerutil.SetControllerReference(&blockClaim, &cidrBlock, r.Scheme)
+	})
+
+	if err != nil {
+		return ctrl.Result{}, r.updateStatus(ctx, &blockClaim, &controlplanev1alpha1.CIDRBlockClaimStatus{
+			State:   controlplanev1alpha1.CIDRClaimStatusStateBindingError,
+			Message: fmt.Sprintf("failed to upsert CIDRBlock: %v", err),
+			CIDR:    cidrClaim.Status.CIDR,
+		})
+	}
+
+	return ctrl.Result{}, r.updateStatus(ctx, &blockClaim, &controlplanev1alpha1.CIDRBlockClaimStatus{
+		State:         controlplanev1alpha1.CIDRClaimStatusStateReady,
+		CIDR:          cidrClaim.Status.CIDR,
+		CIDRBlockName: cidrBlock.Name,
+	})
+}
+
+// finalize deletes the child CIDRBlock and then the CIDRClaim holding its prefix.
+// The finalizer of the CIDRBlockClaim is removed after both of them are gone.
+func (r *CIDRBlockClaimReconciler) finalize(ctx context.Context, blockClaim *controlplanev1alpha1.CIDRBlockClaim) error {
+	key := client.ObjectKeyFromObject(blockClaim)
+
+	var cidrBlock controlplanev1alpha1.CIDRBlock
+	err := r.Get(ctx, key, &cidrBlock)
+
+	if err == nil {
+		// The CIDRBlock is kept by its finalizer until the claims bound to it are deleted
+		if cidrBlock.DeletionTimestamp == nil && metav1.IsControlledBy(&cidrBlock, blockClaim) {
+			if err := r.Delete(ctx, &cidrBlock); client.IgnoreNotFound(err) != nil {
+				return fmt.Errorf("failed to delete CIDRBlock: %w", err)
+			}
+		}
+
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get CIDRBlock: %w", err)
+	}
+
+	cidrClaimKey, err := r.cidrClaimKey(ctx, blockClaim)
+	if err != nil {
+		return err
+	}
+
+	var cidrClaim controlplanev1alpha1.CIDRClaim
+	err = r.Get(ctx, cidrClaimKey, &cidrClaim)
+
+	// The CIDRClaims of users with the same name are left as they are
+	if err == nil && metav1.IsControlledBy(&cidrClaim, blockClaim) {
+		if err := patchFinalizer(ctx, r.Client, &cidrClaim, cidrBlockClaimFinalizer, false); err != nil {
+			return err
+		}
+
+		if blockClaim.DeletionTimestamp == nil {
+			return nil
+		}
+
+		if err := r.Delete(ctx, &cidrClaim); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete CIDRClaim: %w", err)
+		}
+
+		return nil
+	}
+	if client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to get CIDRClaim: %w", err)
+	}
+
+	if blockClaim.DeletionTimestamp == nil {
+		return nil
+	}
+
+	return patchFinalizer(ctx, r.Client, blockClaim, cidrBlockClaimFinalizer, false)
+}
+
+// cidrClaimKey returns the key of the CIDRClaim of the CIDRBlockClaim. The CIDRClaim created
+// before it was named by BlockClaimCIDRClaimName has the same name as the CIDRBlockClaim and is
+// used until it is deleted.
+func (r *CIDRBlockClaimReconciler) cidrClaimKey(ctx context.Context, blockClaim *controlplanev1alpha1.CIDRBlockClaim) (types.NamespacedName, error) {
+	legacy := client.ObjectKeyFromObject(blockClaim)
+
+	var cidrClaim controlplanev1alpha1.CIDRClaim
+	err := r.Get(ctx, legacy, &cidrClaim)
+
+	if err == nil && metav1.IsControlledBy(&cidrClaim, blockClaim) {
+		return legacy, nil
+	}
+	if client.IgnoreNotFound(err) != nil {
+		return types.NamespacedName{}, fmt.Errorf("failed to get CIDRClaim: %w", err)
+	}
+
+	return types.NamespacedName{
+		Namespace: blockClaim.Namespace,
+		Name:      controlplanev1alpha1.BlockClaimCIDRClaimName(blockClaim.Name),
+	}, nil
+}
+
+// inUse returns true if the CIDRBlock exists and prefixes are allocated from it
+func inUse(cidrBlock *controlplanev1alpha1.CIDRBlock) bool {
+	return cidrBlock.UID != "" && len(cidrBlock.Status.Allocations) != 0
+}
+
+func (r *CIDRBlockClaimReconciler) updateStatus(ctx context.Context, blockClaim *controlplanev1alpha1.CIDRBlockClaim, status *controlplanev1alpha1.CIDRBlockClaimStatus) error {
+	updated := blockClaim.DeepCopy()
+	updated.Status = *status
+	updated.Status.ObservedGeneration = blockClaim.Generation
+
+	if err := r.Client.Status().Patch(ctx, updated, client.MergeFrom(blockClaim)); err != nil {
+		return fmt.Errorf("failed to update status: %w", err)
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CIDRBlockClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&controlplanev1alpha1.CIDRBlockClaim{}).
+		Owns(&controlplanev1alpha1.CIDRClaim{}).
+		Owns(&controlplanev1alpha1.CIDRBlock{}).
+		Complete(r)
+}
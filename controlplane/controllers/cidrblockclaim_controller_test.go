@@ -0,0 +1,380 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+var _ = Describe("CIDRBlockClaim", func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		deleteAll(ctx)
+
+		scheme := scheme.Scheme
+
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme: scheme,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = (&CIDRClaimReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   scheme,
+			Recorder: mgr.GetEventRecorder("cidrclaim-controller"),
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = (&CIDRBlockReconciler{
+			Client: mgr.GetClient(),
+			Scheme: scheme,
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = (&CIDRBlockClaimReconciler{
+			Client: mgr.GetClient(),
+			Scheme: scheme,
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		go func() {
+			err := mgr.Start(ctx)
+
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		cancel()
+
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("Carve a child CIDRBlock from the parent", func() {
+		parent := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "global",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/pool": "global",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "10.0.0.0/8",
+			},
+		}
+
+		err := k8sClient.Create(ctx, &parent)
+		Expect(err).NotTo(HaveOccurred())
+
+		blockClaim := controlplanev1alpha1.CIDRBlockClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cluster-a",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRBlockClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/pool": "global",
+					},
+				},
+				SizeBit: 16,
+				Template: controlplanev1alpha1.CIDRBlockTemplate{
+					Labels: map[string]string{
+						"controlplane.miscord.win/cluster": "cluster-a",
+					},
+				},
+			},
+		}
+
+		err = k8sClient.Create(ctx, &blockClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		blockClaimKey := client.ObjectKeyFromObject(&blockClaim)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, blockClaimKey, &blockClaim)
+
+			if err != nil {
+				return err
+			}
+
+			if blockClaim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+				return fmt.Errorf("not ready: %s", blockClaim.Status.Message)
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(blockClaim.Status.CIDR).To(Equal("10.0.0.0/16"))
+		Expect(blockClaim.Status.CIDRBlockName).To(Equal("cluster-a"))
+
+		var child controlplanev1alpha1.CIDRBlock
+		err = k8sClient.Get(ctx, types.NamespacedName{
+			Namespace: testNamespace,
+			Name:      blockClaim.Status.CIDRBlockName,
+		}, &child)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(child.Spec.CIDR).To(Equal("10.0.0.0/16"))
+		Expect(child.Labels).To(HaveKeyWithValue("controlplane.miscord.win/cluster", "cluster-a"))
+
+		cidrClaim := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "node-001",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/cluster": "cluster-a",
+					},
+				},
+				SizeBit: 8,
+			},
+		}
+
+		err = k8sClient.Create(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrClaimKey := client.ObjectKeyFromObject(&cidrClaim)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrClaimKey, &cidrClaim)
+
+			if err != nil {
+				return err
+			}
+
+			if cidrClaim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+				return fmt.Errorf("not ready: %s", cidrClaim.Status.Message)
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(cidrClaim.Status.CIDRBlockName).To(Equal("cluster-a"))
+		Expect(cidrClaim.Status.CIDR).To(Equal("10.0.0.0/24"))
+	})
+
+	It("Carves neither from its own child nor from the CIDRClaims of users", func() {
+		parent := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "global",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/pool": "global",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "10.0.0.0/8",
+			},
+		}
+
+		err := k8sClient.Create(ctx, &parent)
+		Expect(err).NotTo(HaveOccurred())
+
+		// The CIDRClaim of a user with the same name as the CIDRBlockClaim
+		userClaim := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cluster-a",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/pool": "global",
+					},
+				},
+				SizeBit: 8,
+			},
+		}
+
+		err = k8sClient.Create(ctx, &userClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		// The child has the labels matching the selector of the CIDRBlockClaim
+		blockClaim := controlplanev1alpha1.CIDRBlockClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cluster-a",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRBlockClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/pool": "global",
+					},
+				},
+				SizeBit: 16,
+				Template: controlplanev1alpha1.CIDRBlockTemplate{
+					Labels: map[string]string{
+						"controlplane.miscord.win/pool": "global",
+					},
+				},
+			},
+		}
+
+		err = k8sClient.Create(ctx, &blockClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		blockClaimKey := client.ObjectKeyFromObject(&blockClaim)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, blockClaimKey, &blockClaim)
+
+			if err != nil {
+				return err
+			}
+
+			if blockClaim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+				return fmt.Errorf("not ready: %s", blockClaim.Status.Message)
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&userClaim), &userClaim)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(userClaim.OwnerReferences).To(BeEmpty())
+		Expect(userClaim.Spec.SizeBit).To(Equal(8))
+
+		// The prefix resized is allocated from the parent again
+		blockClaim.Spec.SizeBit = 12
+		err = k8sClient.Update(ctx, &blockClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		var intermediate controlplanev1alpha1.CIDRClaim
+		Eventually(func() (int, error) {
+			err := k8sClient.Get(ctx, client.ObjectKey{
+				Namespace: testNamespace,
+				Name:      controlplanev1alpha1.BlockClaimCIDRClaimName(blockClaim.Name),
+			}, &intermediate)
+
+			return intermediate.Status.SizeBit, err
+		}).Should(Equal(12))
+		Expect(intermediate.Status.CIDRBlockName).To(Equal("global"))
+	})
+
+	It("Keeps the prefix of the child CIDRBlock until it is deleted", func() {
+		parent := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "global",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/pool": "global",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "10.0.0.0/8",
+			},
+		}
+
+		err := k8sClient.Create(ctx, &parent)
+		Expect(err).NotTo(HaveOccurred())
+
+		blockClaim := controlplanev1alpha1.CIDRBlockClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cluster-a",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRBlockClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/pool": "global",
+					},
+				},
+				SizeBit: 16,
+				Template: controlplanev1alpha1.CIDRBlockTemplate{
+					Labels: map[string]string{
+						"controlplane.miscord.win/cluster": "cluster-a",
+					},
+				},
+			},
+		}
+
+		err = k8sClient.Create(ctx, &blockClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrClaim := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "node-001",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/cluster": "cluster-a",
+					},
+				},
+				SizeBit: 8,
+			},
+		}
+
+		err = k8sClient.Create(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrClaimKey := client.ObjectKeyFromObject(&cidrClaim)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrClaimKey, &cidrClaim)
+
+			if err != nil {
+				return err
+			}
+
+			if cidrClaim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+				return fmt.Errorf("not ready: %s", cidrClaim.Status.Message)
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		// The intermediate claim is pinned to the prefix of the child in use
+		var intermediate controlplanev1alpha1.CIDRClaim
+		Eventually(func() (string, error) {
+			err := k8sClient.Get(ctx, client.ObjectKey{
+				Namespace: testNamespace,
+				Name:      controlplanev1alpha1.BlockClaimCIDRClaimName(blockClaim.Name),
+			}, &intermediate)
+
+			return intermediate.Spec.RequestedCIDR, err
+		}).Should(Equal("10.0.0.0/16"))
+
+		err = k8sClient.Delete(ctx, &blockClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		parentKey := client.ObjectKeyFromObject(&parent)
+		Consistently(func() ([]controlplanev1alpha1.CIDRBlockAllocation, error) {
+			err := k8sClient.Get(ctx, parentKey, &parent)
+
+			return parent.Status.Allocations, err
+		}, 2*time.Second).Should(HaveLen(1))
+
+		err = k8sClient.Delete(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() ([]controlplanev1alpha1.CIDRBlockAllocation, error) {
+			err := k8sClient.Get(ctx, parentKey, &parent)
+
+			return parent.Status.Allocations, err
+		}).Should(BeEmpty())
+
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(&blockClaim), &blockClaim)
+
+			return errors.IsNotFound(err)
+		}).Should(BeTrue())
+	})
+})
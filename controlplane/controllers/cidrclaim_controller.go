@@ -19,31 +19,48 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"math/rand"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
-	"github.com/miscord-dev/tetrapod/controlplane/pkg/ipaddrutil"
 	"github.com/seancfoley/ipaddress-go/ipaddr"
 )
 
 // CIDRClaimReconciler reconciles a CIDRClaim object
 type CIDRClaimReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder events.EventRecorder
+
+	// IPAM reserves and releases the prefixes. The allocator in the ledgers of the
+	// CIDRBlocks is used if it is nil.
+	IPAM IPAMProvider
+
+	allocations *allocationTable
+	pending     *pendingQueue
 }
 
 //+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrclaims,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrclaims/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrclaims/finalizers,verbs=update
 //+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrblocks,verbs=get;list;watch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrblocks/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrreservations,verbs=get;list;watch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=peernodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrquotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -60,165 +77,506 @@ func (r *CIDRClaimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	err := r.Get(ctx, req.NamespacedName, &cidrClaim)
 
 	if errors.IsNotFound(err) {
+		r.pending.remove(req.NamespacedName)
+
 		return ctrl.Result{}, nil
 	}
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to get CIDRClaim: %w", err)
 	}
 
+	if cidrClaim.DeletionTimestamp != nil {
+		r.pending.remove(req.NamespacedName)
+
+		return ctrl.Result{}, r.release(ctx, &cidrClaim)
+	}
+
+	if err := patchFinalizer(ctx, r.Client, &cidrClaim, cidrClaimFinalizer, true); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	if cidrClaim.Generation == cidrClaim.Status.ObservedGeneration &&
 		cidrClaim.Status.State == controlplanev1alpha1.CIDRClaimStatusStateReady {
-		return ctrl.Result{}, nil
+		if len(cidrClaim.Status.Draining) == 0 {
+			return ctrl.Result{}, nil
+		}
+
+		return r.drain(ctx, &cidrClaim, cidrClaim.Status.DeepCopy())
+	}
+
+	var namespace corev1.Namespace
+	if err := r.Get(ctx, types.NamespacedName{Name: req.Namespace}, &namespace); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get Namespace: %w", err)
 	}
 
 	status := cidrClaim.Status.DeepCopy()
+	requests := allocationRequests(&cidrClaim)
+
+	// Shared blocks overlapping the ones in other namespaces are skipped
+	var allBlocks controlplanev1alpha1.CIDRBlockList
+	if err := r.List(ctx, &allBlocks); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list CIDRBlocks: %w", err)
+	}
+
+	candidates := make([][]controlplanev1alpha1.CIDRBlock, len(requests))
+	overlapping := make([][]string, len(requests))
+	ready := len(status.FamilyStatuses()) == len(requests)
+	for i, request := range requests {
+		selector, err := metav1.LabelSelectorAsSelector(&request.selector)
+		if err != nil {
+			status.State = controlplanev1alpha1.CIDRClaimStatusStateBindingError
+			status.Message = fmt.Sprintf("%sselector is invalid: %v", request.messagePrefix(), err)
+			allocationFailures.WithLabelValues(failureReasonInvalidSelector).Inc()
+			r.Recorder.Eventf(&cidrClaim, nil, corev1.EventTypeWarning, "InvalidSelector", "Allocate", "%s", status.Message)
+
+			return ctrl.Result{}, r.updateStatus(ctx, &cidrClaim, status)
+		}
+
+		// CIDRBlocks in other namespaces are candidates if they are shared with the namespace
+		var cidrBlocks controlplanev1alpha1.CIDRBlockList
+		if err := r.List(ctx, &cidrBlocks, &client.ListOptions{
+			LabelSelector: selector,
+		}); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to get init selector: %w", err)
+		}
+
+		items := filterCarvedBy(filterByFamily(filterByNamespace(cidrBlocks.Items, &namespace), request.family), &cidrClaim)
+		candidates[i], overlapping[i] = filterOverlapping(items, allBlocks.Items)
+
+		if !r.isReady(cidrClaim, request, selector, candidates[i]) {
+			ready = false
+		}
+	}
+
+	if ready {
+		r.pending.remove(req.NamespacedName)
 
-	selector, err := metav1.LabelSelectorAsSelector(&cidrClaim.Spec.Selector)
+		return r.drain(ctx, &cidrClaim, status)
+	}
+
+	// The claims created earlier and enqueued for freed capacity are bound first
+	var blocks []controlplanev1alpha1.CIDRBlock
+	for _, items := range candidates {
+		blocks = append(blocks, items...)
+	}
+	if len(r.pending.ahead(&cidrClaim, blocks)) != 0 {
+		return ctrl.Result{RequeueAfter: pendingRetryInterval}, nil
+	}
+
+	// CIDRs bound to other claims in the candidate blocks
+	claims := map[types.NamespacedName][]controlplanev1alpha1.CIDRBlockAllocation{}
+	for _, items := range candidates {
+		for _, block := range items {
+			key := client.ObjectKeyFromObject(&block)
+			claims[key] = r.allocations.allocations(key, cidrClaim.UID)
+		}
+	}
+
+	// The reservations apply to the blocks in their namespaces
+	reservations, err := listReservations(ctx, r.Client, metav1.NamespaceAll)
 	if err != nil {
-		status.State = controlplanev1alpha1.CIDRClaimStatusStateBindingError
-		status.Message = fmt.Sprintf("selector is invalid: %v", err)
+		return ctrl.Result{}, err
+	}
 
-		return ctrl.Result{}, r.updateStatus(ctx, &cidrClaim, status)
+	peerNodeLabels, err := ownerPeerNodeLabels(ctx, r.Client, &cidrClaim)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
-	var cidrBlocks controlplanev1alpha1.CIDRBlockList
-	if err := r.List(ctx, &cidrBlocks, &client.ListOptions{
-		Namespace:     req.Namespace,
-		LabelSelector: selector,
-	}); err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to get init selector: %w", err)
+	quotaLimits, err := r.quotaLimits(ctx, &cidrClaim)
+	if err != nil {
+		return ctrl.Result{}, err
 	}
 
-	if r.isReady(cidrClaim, selector, cidrBlocks.Items) {
-		return ctrl.Result{}, r.updateStatus(ctx, &cidrClaim, status)
+	bound := make([]controlplanev1alpha1.CIDRClaimFamilyStatus, 0, len(requests))
+	for i, request := range requests {
+		items := candidates[i]
+		if len(items) == 0 {
+			status.State = controlplanev1alpha1.CIDRClaimStatusStateBindingError
+			status.Message = request.messagePrefix() + "no matching CIDRBlock"
+			if len(overlapping[i]) != 0 {
+				status.Message += fmt.Sprintf(" (%s skipped for overlapping CIDRBlocks in other namespaces)", strings.Join(overlapping[i], ", "))
+			}
+			allocationFailures.WithLabelValues(failureReasonNoMatchingCIDRBlock).Inc()
+			r.Recorder.Eventf(&cidrClaim, nil, corev1.EventTypeWarning, "NoMatchingCIDRBlock", "Allocate", "%s", status.Message)
+			r.pending.add(&cidrClaim, pendingSelectors(requests))
+
+			return ctrl.Result{RequeueAfter: 1 * time.Minute}, r.updateStatus(ctx, &cidrClaim, status)
+		}
+
+		items = withinQuota(items, &cidrClaim, request, quotaLimits)
+		if len(items) == 0 {
+			status.State = controlplanev1alpha1.CIDRClaimStatusStateQuotaExceeded
+			status.Message = request.messagePrefix() + "CIDRQuota exceeded in all matching CIDRBlocks"
+			allocationFailures.WithLabelValues(failureReasonQuotaExceeded).Inc()
+			r.Recorder.Eventf(&cidrClaim, nil, corev1.EventTypeWarning, "QuotaExceeded", "Allocate", "%s", status.Message)
+			r.pending.add(&cidrClaim, pendingSelectors(requests))
+
+			return ctrl.Result{RequeueAfter: 1 * time.Minute}, r.updateStatus(ctx, &cidrClaim, status)
+		}
+
+		sortByPreference(items, request.preferred, peerNodeLabels)
+
+		allocated, err := r.ipam().Allocate(ctx, &AllocationRequest{
+			Claim:         &cidrClaim,
+			Blocks:        items,
+			SizeBit:       request.sizeBit,
+			MinSizeBit:    request.minSizeBit,
+			RequestedCIDR: request.requestedCIDR,
+			Allocations:   claims,
+			Reservations:  reservations,
+			QuotaLimits:   quotaLimits,
+		})
+
+		if errors.IsConflict(err) {
+			return ctrl.Result{}, err
+		}
+		if err != nil {
+			status.State = controlplanev1alpha1.CIDRClaimStatusStateBindingError
+			status.Message = request.messagePrefix() + err.Error()
+			allocationFailures.WithLabelValues(request.failureReason()).Inc()
+			r.Recorder.Eventf(&cidrClaim, nil, corev1.EventTypeWarning, request.eventReason(), "Allocate", "%s", status.Message)
+			r.pending.add(&cidrClaim, pendingSelectors(requests))
+
+			return ctrl.Result{RequeueAfter: 1 * time.Minute}, r.updateStatus(ctx, &cidrClaim, status)
+		}
+
+		addr := ipaddr.NewIPAddressString(allocated.CIDR).GetAddress()
+
+		bound = append(bound, controlplanev1alpha1.CIDRClaimFamilyStatus{
+			Family:             addressFamily(addr),
+			CIDRBlockName:      allocated.Block.Name,
+			CIDRBlockNamespace: sharedNamespace(&cidrClaim, allocated.Block),
+			CIDR:               allocated.CIDR,
+			SizeBit:            prefixSizeBit(addr),
+			ExternalID:         allocated.ExternalID,
+		})
 	}
 
-	var cidrClaims controlplanev1alpha1.CIDRClaimList
-	if err := r.List(ctx, &cidrClaims, &client.ListOptions{
-		Namespace: req.Namespace,
-	}); err != nil {
-		return ctrl.Result{}, fmt.Errorf("failed to list CIDRClaims: %w", err)
+	now := time.Now()
+
+	status.State = controlplanev1alpha1.CIDRClaimStatusStateReady
+	status.Message = ""
+	status.CIDRs = bound
+	status.CIDR = bound[0].CIDR
+	status.CIDRBlockName = bound[0].CIDRBlockName
+	status.CIDRBlockNamespace = bound[0].CIDRBlockNamespace
+	status.SizeBit = bound[0].SizeBit
+	status.Draining = drainingAllocations(&cidrClaim, requests, bound, now)
+
+	if err := r.updateStatus(ctx, &cidrClaim, status); err != nil {
+		return ctrl.Result{}, err
 	}
+	r.pending.remove(req.NamespacedName)
 
-	// Filter out self claim
-	for i := range cidrClaims.Items {
-		if cidrClaims.Items[i].Name == cidrClaim.Name {
-			cidrClaims.Items = append(cidrClaims.Items[:i], cidrClaims.Items[i+1:]...)
+	if len(cidrClaim.Status.FamilyStatuses()) == 0 {
+		allocationDuration.Observe(time.Since(cidrClaim.CreationTimestamp.Time).Seconds())
+	}
+
+	for i, request := range requests {
+		r.recordBound(&cidrClaim, request.boundStatus(&cidrClaim), &bound[i])
+	}
 
-			break
+	// The allocations which were bound until now have just started draining
+	for _, d := range status.Draining {
+		if hasAllocation(cidrClaim.Status.FamilyStatuses(), &d.CIDRClaimFamilyStatus) {
+			r.Recorder.Eventf(
+				&cidrClaim, nil, corev1.EventTypeNormal, "Draining", "Allocate",
+				"Draining %s of CIDRBlock %s until %s", d.CIDR, d.CIDRBlockName, d.ReleaseAt.UTC().Format(time.RFC3339),
+			)
 		}
 	}
 
-	claims := map[string][]controlplanev1alpha1.CIDRClaim{}
-	for _, claim := range cidrClaims.Items {
-		if claim.Status.CIDRBlockName == "" {
-			continue
+	if err := r.ipam().Release(ctx, &cidrClaim, keptAllocations(&cidrClaim, status)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to release previous allocations: %w", err)
+	}
+
+	_, _, requeueAfter := expireDraining(status.Draining, now)
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// allocationRequest is a request of an allocation for the claim.
+// family is empty for claims without Families.
+type allocationRequest struct {
+	family        controlplanev1alpha1.AddressFamily
+	selector      metav1.LabelSelector
+	sizeBit       int
+	minSizeBit    int
+	requestedCIDR string
+	preferred     []controlplanev1alpha1.PreferredCIDRBlockTerm
+}
+
+// allocationRequests returns a request for each family of the claim
+func allocationRequests(claim *controlplanev1alpha1.CIDRClaim) []allocationRequest {
+	if len(claim.Spec.Families) == 0 {
+		return []allocationRequest{
+			{
+				selector:      claim.Spec.Selector,
+				sizeBit:       claim.Spec.SizeBit,
+				minSizeBit:    minSizeBit(claim.Spec.SizeBit, claim.Spec.MinSizeBit),
+				requestedCIDR: claim.Spec.RequestedCIDR,
+				preferred:     claim.Spec.Preferred,
+			},
 		}
+	}
 
-		claims[claim.Status.CIDRBlockName] = append(claims[claim.Status.CIDRBlockName], claim)
+	requests := make([]allocationRequest, 0, len(claim.Spec.Families))
+	for _, f := range claim.Spec.Families {
+		requests = append(requests, allocationRequest{
+			family:        f.Family,
+			selector:      f.Selector,
+			sizeBit:       f.SizeBit,
+			minSizeBit:    minSizeBit(f.SizeBit, f.MinSizeBit),
+			requestedCIDR: f.RequestedCIDR,
+			preferred:     f.Preferred,
+		})
 	}
 
-	items := cidrBlocks.Items
-	if len(items) == 0 {
-		status.State = controlplanev1alpha1.CIDRClaimStatusStateBindingError
-		status.Message = "no matching CIDRBlock"
+	return requests
+}
 
-		return ctrl.Result{RequeueAfter: 1 * time.Minute}, r.updateStatus(ctx, &cidrClaim, status)
+// minSizeBit returns the smallest size acceptable for the request
+func minSizeBit(sizeBit int, minimum *int) int {
+	if minimum == nil || *minimum > sizeBit {
+		return sizeBit
 	}
 
-	rand.Shuffle(len(items), func(i, j int) {
-		items[i], items[j] = items[j], items[i]
-	})
+	return *minimum
+}
 
-	block, allocated, err := r.allocate(&cidrClaim, items, claims)
+// acceptsSizeBit returns true if an allocation of the size satisfies the request
+func (r allocationRequest) acceptsSizeBit(sizeBit int) bool {
+	return r.minSizeBit <= sizeBit && sizeBit <= r.sizeBit
+}
 
-	if err != nil {
-		status.State = controlplanev1alpha1.CIDRClaimStatusStateBindingError
-		status.Message = err.Error()
+func (r allocationRequest) messagePrefix() string {
+	if r.family == "" {
+		return ""
+	}
 
-		return ctrl.Result{RequeueAfter: 1 * time.Minute}, r.updateStatus(ctx, &cidrClaim, status)
+	return string(r.family) + ": "
+}
+
+// failureReason returns the reason of the allocation failure reported to metrics
+func (r allocationRequest) failureReason() string {
+	if r.requestedCIDR != "" {
+		return failureReasonRequestedCIDRUnavailable
 	}
 
-	status.State = controlplanev1alpha1.CIDRClaimStatusStateReady
-	status.Message = ""
-	status.CIDR = allocated
-	status.CIDRBlockName = block
-	status.SizeBit = cidrClaim.Spec.SizeBit
+	return failureReasonNoSpace
+}
+
+// eventReason returns the reason of the Event for the allocation failure
+func (r allocationRequest) eventReason() string {
+	if r.requestedCIDR != "" {
+		return "RequestedCIDRUnavailable"
+	}
 
-	return ctrl.Result{}, r.updateStatus(ctx, &cidrClaim, status)
+	return "PoolExhausted"
 }
 
-func (r *CIDRClaimReconciler) isReady(
-	claim controlplanev1alpha1.CIDRClaim,
-	selector labels.Selector,
-	blocks []controlplanev1alpha1.CIDRBlock,
-) bool {
-	if claim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
-		return false
+// boundStatus returns the allocation bound to the claim for the request
+func (r allocationRequest) boundStatus(claim *controlplanev1alpha1.CIDRClaim) *controlplanev1alpha1.CIDRClaimFamilyStatus {
+	statuses := claim.Status.FamilyStatuses()
+
+	for i := range statuses {
+		if r.family == "" || statuses[i].Family == r.family {
+			return &statuses[i]
+		}
 	}
 
-	var block *controlplanev1alpha1.CIDRBlock
-	for _, b := range blocks {
-		if b.Name == claim.Status.CIDRBlockName {
-			block = &b
+	return nil
+}
+
+// filterByFamily returns the blocks of the family. All blocks are returned if family is empty.
+func filterByFamily(blocks []controlplanev1alpha1.CIDRBlock, family controlplanev1alpha1.AddressFamily) []controlplanev1alpha1.CIDRBlock {
+	if family == "" {
+		return blocks
+	}
+
+	filtered := make([]controlplanev1alpha1.CIDRBlock, 0, len(blocks))
+	for _, block := range blocks {
+		addr := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+
+		if addr == nil || addressFamily(addr) != family {
+			continue
 		}
+
+		filtered = append(filtered, block)
 	}
 
-	if block == nil {
-		return false
+	return filtered
+}
+
+// filterByNamespace returns the blocks serving CIDRClaims in the namespace
+func filterByNamespace(blocks []controlplanev1alpha1.CIDRBlock, namespace *corev1.Namespace) []controlplanev1alpha1.CIDRBlock {
+	filtered := make([]controlplanev1alpha1.CIDRBlock, 0, len(blocks))
+	for _, block := range blocks {
+		if block.ServesNamespace(namespace) {
+			filtered = append(filtered, block)
+		}
 	}
 
-	return selector.Matches(labels.Set(block.Labels))
+	return filtered
 }
 
-func (r *CIDRClaimReconciler) allocate(
-	cidrClaim *controlplanev1alpha1.CIDRClaim,
-	blocks []controlplanev1alpha1.CIDRBlock,
-	usedClaims map[string][]controlplanev1alpha1.CIDRClaim,
-) (cidrBlockName, cidr string, err error) {
-	sizeBit := cidrClaim.Spec.SizeBit
+// filterCarvedBy returns the blocks except the children of the CIDRBlockClaim controlling the claim.
+// The prefix of a CIDRBlockClaim would be allocated from its own child if the labels of the template
+// matched the selector.
+func filterCarvedBy(blocks []controlplanev1alpha1.CIDRBlock, claim *controlplanev1alpha1.CIDRClaim) []controlplanev1alpha1.CIDRBlock {
+	owner := metav1.GetControllerOf(claim)
+	if owner == nil || owner.Kind != "CIDRBlockClaim" || owner.APIVersion != controlplanev1alpha1.GroupVersion.String() {
+		return blocks
+	}
 
+	filtered := make([]controlplanev1alpha1.CIDRBlock, 0, len(blocks))
 	for _, block := range blocks {
-		blockSubnet := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+		if blockOwner := metav1.GetControllerOf(&block); blockOwner != nil && blockOwner.UID == owner.UID {
+			continue
+		}
+
+		filtered = append(filtered, block)
+	}
+
+	return filtered
+}
+
+// filterOverlapping returns the blocks except the ones overlapping an older CIDRBlock in another
+// namespace while either of them is shared, and the names of the skipped ones. The validating
+// webhook rejects such blocks, but it can miss concurrent creations or be disabled.
+func filterOverlapping(blocks, all []controlplanev1alpha1.CIDRBlock) ([]controlplanev1alpha1.CIDRBlock, []string) {
+	filtered := make([]controlplanev1alpha1.CIDRBlock, 0, len(blocks))
+	var skipped []string
+	for _, block := range blocks {
+		if other := olderOverlappingBlock(&block, all); other != nil {
+			skipped = append(skipped, block.Namespace+"/"+block.Name)
 
-		if blockSubnet == nil {
 			continue
 		}
 
-		used := []*ipaddr.IPAddress{}
-		for _, claim := range usedClaims[block.Name] {
-			addr := ipaddr.NewIPAddressString(claim.Status.CIDR).GetAddress()
+		filtered = append(filtered, block)
+	}
 
-			if addr == nil {
-				continue
-			}
+	return filtered, skipped
+}
 
-			used = append(used, addr)
+// olderOverlappingBlock returns a CIDRBlock in another namespace created before the block and
+// overlapping it while either of them is shared, or nil
+func olderOverlappingBlock(block *controlplanev1alpha1.CIDRBlock, all []controlplanev1alpha1.CIDRBlock) *controlplanev1alpha1.CIDRBlock {
+	addr := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+	if addr == nil {
+		return nil
+	}
+
+	for i := range all {
+		other := &all[i]
+
+		if other.Namespace == block.Namespace || (other.Spec.NamespaceSelector == nil && block.Spec.NamespaceSelector == nil) {
+			continue
+		}
+		if block.IsCarvedFrom(other) || other.IsCarvedFrom(block) {
+			continue
 		}
 
-		if sizeBit == 0 {
-			addr, err := blockSubnet.SetPrefixLenZeroed(blockSubnet.GetBitCount())
+		otherAddr := ipaddr.NewIPAddressString(other.Spec.CIDR).GetAddress()
+		if otherAddr == nil || !otherAddr.ToPrefixBlock().Overlaps(addr.ToPrefixBlock()) {
+			continue
+		}
 
-			if err != nil {
-				continue
-			}
+		if other.CreationTimestamp.Before(&block.CreationTimestamp) ||
+			other.CreationTimestamp.Equal(&block.CreationTimestamp) && string(other.UID) < string(block.UID) {
+			return other
+		}
+	}
 
-			used = append(used, addr)
+	return nil
+}
+
+// release releases the allocations of the claim being deleted and removes its finalizer
+func (r *CIDRClaimReconciler) release(ctx context.Context, cidrClaim *controlplanev1alpha1.CIDRClaim) error {
+	if !controllerutil.ContainsFinalizer(cidrClaim, cidrClaimFinalizer) {
+		return nil
+	}
+
+	// The prefix of a CIDRBlockClaim is in use until its child CIDRBlock is deleted
+	if controllerutil.ContainsFinalizer(cidrClaim, cidrBlockClaimFinalizer) {
+		return nil
+	}
+
+	if cidrClaim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReleasing {
+		status := cidrClaim.Status.DeepCopy()
+		status.State = controlplanev1alpha1.CIDRClaimStatusStateReleasing
+		status.Message = ""
+
+		if err := r.updateStatus(ctx, cidrClaim, status); err != nil {
+			return err
 		}
+	}
 
-		allocated := ipaddrutil.FindSubBlock(
-			ipaddrutil.FreeBlocks(blockSubnet, used),
-			sizeBit,
-		)
+	if err := r.ipam().Release(ctx, cidrClaim, nil); err != nil {
+		return fmt.Errorf("failed to release allocations: %w", err)
+	}
 
-		if allocated == nil {
-			continue
+	return patchFinalizer(ctx, r.Client, cidrClaim, cidrClaimFinalizer, false)
+}
+
+func (r *CIDRClaimReconciler) isReady(
+	claim controlplanev1alpha1.CIDRClaim,
+	request allocationRequest,
+	selector labels.Selector,
+	blocks []controlplanev1alpha1.CIDRBlock,
+) bool {
+	if claim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+		return false
+	}
+
+	bound := request.boundStatus(&claim)
+
+	if bound == nil {
+		return false
+	}
+
+	var block *controlplanev1alpha1.CIDRBlock
+	for _, b := range blocks {
+		if client.ObjectKeyFromObject(&b) == blockKeyOf(&claim, bound) {
+			block = &b
 		}
+	}
+
+	if block == nil {
+		return false
+	}
+
+	if request.requestedCIDR != "" {
+		requested := ipaddr.NewIPAddressString(request.requestedCIDR).GetAddress()
+		current := ipaddr.NewIPAddressString(bound.CIDR).GetAddress()
 
-		return block.Name, allocated.String(), nil
+		if requested == nil || current == nil || !requested.Equal(current) {
+			return false
+		}
+	} else if !request.acceptsSizeBit(bound.SizeBit) {
+		return false
 	}
 
-	return "", "", fmt.Errorf("no available CIDRBlock")
+	return selector.Matches(labels.Set(block.Labels))
+}
+
+// recordBound records an Event if the allocation differs from the previous one
+func (r *CIDRClaimReconciler) recordBound(cidrClaim *controlplanev1alpha1.CIDRClaim, prev, bound *controlplanev1alpha1.CIDRClaimFamilyStatus) {
+	switch {
+	case prev == nil:
+		r.Recorder.Eventf(
+			cidrClaim, nil, corev1.EventTypeNormal, "Bound", "Allocate",
+			"Bound %s from CIDRBlock %s", bound.CIDR, bound.CIDRBlockName,
+		)
+	case !hasAllocation([]controlplanev1alpha1.CIDRClaimFamilyStatus{*prev}, bound):
+		r.Recorder.Eventf(
+			cidrClaim, nil, corev1.EventTypeNormal, "Rebound", "Allocate",
+			"Rebound from %s of CIDRBlock %s to %s of CIDRBlock %s",
+			prev.CIDR, prev.CIDRBlockName, bound.CIDR, bound.CIDRBlockName,
+		)
+	}
 }
 
 func (r *CIDRClaimReconciler) updateStatus(ctx context.Context, cidrClaim *controlplanev1alpha1.CIDRClaim, status *controlplanev1alpha1.CIDRClaimStatus) error {
@@ -226,7 +584,10 @@ func (r *CIDRClaimReconciler) updateStatus(ctx context.Context, cidrClaim *contr
 	updated.Status.ObservedGeneration = cidrClaim.Generation
 	updated.Status.CIDR = status.CIDR
 	updated.Status.CIDRBlockName = status.CIDRBlockName
+	updated.Status.CIDRBlockNamespace = status.CIDRBlockNamespace
 	updated.Status.SizeBit = status.SizeBit
+	updated.Status.CIDRs = status.CIDRs
+	updated.Status.Draining = status.Draining
 	updated.Status.State = status.State
 	updated.Status.Message = status.Message
 
@@ -237,9 +598,50 @@ func (r *CIDRClaimReconciler) updateStatus(ctx context.Context, cidrClaim *contr
 	return nil
 }
 
+// quotaLimits returns the largest size of a new allocation for the claim in each block
+// limited by the CIDRQuotas in the namespace of the claim. Only the allocations of the claims
+// in the namespace are counted against them wherever the blocks are.
+func (r *CIDRClaimReconciler) quotaLimits(
+	ctx context.Context,
+	cidrClaim *controlplanev1alpha1.CIDRClaim,
+) (map[types.NamespacedName]int, error) {
+	quotas, err := listQuotas(ctx, r.Client, cidrClaim.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(quotas) == 0 {
+		return nil, nil
+	}
+
+	// Blocks shared from other namespaces are counted as well
+	var cidrBlocks controlplanev1alpha1.CIDRBlockList
+	if err := r.List(ctx, &cidrBlocks); err != nil {
+		return nil, fmt.Errorf("failed to list CIDRBlocks: %w", err)
+	}
+
+	return quotaSizeBitLimits(quotas, cidrBlocks.Items, func(block *controlplanev1alpha1.CIDRBlock) []controlplanev1alpha1.CIDRBlockAllocation {
+		return allocationsIn(block, r.allocations.allocations(client.ObjectKeyFromObject(block), cidrClaim.UID), cidrClaim.Namespace)
+	}), nil
+}
+
+// ipam returns the IPAMProvider of the reconciler
+func (r *CIDRClaimReconciler) ipam() IPAMProvider {
+	if r.IPAM == nil {
+		return &InClusterProvider{Client: r.Client, allocations: r.allocations}
+	}
+
+	return r.IPAM
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *CIDRClaimReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.allocations = newAllocationTable()
+	r.pending = newPendingQueue()
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&controlplanev1alpha1.CIDRClaim{}).
+		Watches(&controlplanev1alpha1.CIDRClaim{}, r.allocations.eventHandler()).
+		Watches(&controlplanev1alpha1.CIDRBlock{}, r.pending.eventHandler()).
+		Watches(&controlplanev1alpha1.CIDRQuota{}, r.pending.quotaEventHandler()).
 		Complete(r)
 }
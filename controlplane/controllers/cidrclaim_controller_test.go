@@ -7,6 +7,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	eventsv1 "k8s.io/api/events/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -21,11 +22,7 @@ var _ = Describe("CIDRClaim", func() {
 	BeforeEach(func() {
 		ctx, cancel = context.WithCancel(context.Background())
 
-		err := k8sClient.DeleteAllOf(ctx, &controlplanev1alpha1.CIDRBlock{}, client.InNamespace(testNamespace))
-		Expect(err).NotTo(HaveOccurred())
-
-		err = k8sClient.DeleteAllOf(ctx, &controlplanev1alpha1.CIDRClaim{}, client.InNamespace(testNamespace))
-		Expect(err).NotTo(HaveOccurred())
+		deleteAll(ctx)
 
 		scheme := scheme.Scheme
 
@@ -35,12 +32,19 @@ var _ = Describe("CIDRClaim", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		reconciler := CIDRClaimReconciler{
-			Client: k8sClient,
-			Scheme: scheme,
+			Client:   mgr.GetClient(),
+			Scheme:   scheme,
+			Recorder: mgr.GetEventRecorder("cidrclaim-controller"),
 		}
 		err = reconciler.SetupWithManager(mgr)
 		Expect(err).NotTo(HaveOccurred())
 
+		err = (&CIDRBlockReconciler{
+			Client: mgr.GetClient(),
+			Scheme: scheme,
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
 		go func() {
 			err := mgr.Start(ctx)
 
@@ -127,6 +131,10 @@ var _ = Describe("CIDRClaim", func() {
 		Expect(cidrClaim.Status.CIDRBlockName).To(Equal(cidrBlock.Name))
 		Expect(cidrClaim.Status.CIDR).To(Equal("192.168.1.0/30"))
 		Expect(cidrClaim.Status.SizeBit).To(Equal(2))
+
+		Eventually(func() []string {
+			return eventReasons(ctx, &cidrClaim)
+		}).Should(ContainElement("Bound"))
 	})
 
 	It("Allocate IPv6", func() {
@@ -256,6 +264,42 @@ var _ = Describe("CIDRClaim", func() {
 		Expect(cidrClaim.Status.CIDR).To(Equal(""))
 		Expect(cidrClaim.Status.SizeBit).To(Equal(0))
 		Expect(cidrClaim.Status.Message).To(Equal("no matching CIDRBlock"))
+
+		Eventually(func() []string {
+			return eventReasons(ctx, &cidrClaim)
+		}).Should(ContainElement("NoMatchingCIDRBlock"))
+		// The waiting claim is bound as soon as a matching block is created
+		cidrBlockV4 := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-002",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+			},
+		}
+
+		err = k8sClient.Create(ctx, &cidrBlockV4)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrClaimKey, &cidrClaim)
+
+			if err != nil {
+				return err
+			}
+
+			if cidrClaim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+				return fmt.Errorf("not ready")
+			}
+
+			return nil
+		}, 10*time.Second).Should(Succeed())
+
+		Expect(cidrClaim.Status.CIDR).To(Equal("192.168.1.0/24"))
 	})
 
 	It("No available block", func() {
@@ -353,4 +397,683 @@ var _ = Describe("CIDRClaim", func() {
 		Expect(cidrClaim.Status.Message).To(Equal("no available CIDRBlock"))
 		Expect(cidrClaim.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateBindingError))
 	})
+
+	It("Allocate requested CIDR", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrClaim := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-claim",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+				RequestedCIDR: "192.168.1.128/30",
+			},
+		}
+
+		err = k8sClient.Create(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrClaimKey := client.ObjectKeyFromObject(&cidrClaim)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrClaimKey, &cidrClaim)
+
+			if err != nil {
+				return err
+			}
+
+			if cidrClaim.Status.ObservedGeneration != cidrClaim.Generation {
+				return fmt.Errorf("not updated")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(cidrClaim.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateReady))
+		Expect(cidrClaim.Status.CIDRBlockName).To(Equal(cidrBlock.Name))
+		Expect(cidrClaim.Status.CIDR).To(Equal("192.168.1.128/30"))
+		Expect(cidrClaim.Status.SizeBit).To(Equal(2))
+
+		conflicting := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-claim-2",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+				RequestedCIDR: "192.168.1.129",
+			},
+		}
+
+		err = k8sClient.Create(ctx, &conflicting)
+		Expect(err).NotTo(HaveOccurred())
+
+		conflictingKey := client.ObjectKeyFromObject(&conflicting)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, conflictingKey, &conflicting)
+
+			if err != nil {
+				return err
+			}
+
+			if conflicting.Status.ObservedGeneration != conflicting.Generation {
+				return fmt.Errorf("not updated")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(conflicting.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateBindingError))
+		Expect(conflicting.Status.CIDR).To(Equal(""))
+		Expect(conflicting.Status.Message).To(Equal(
+			"requested CIDR 192.168.1.129/32 conflicts with 192.168.1.128/30 allocated to CIDRClaim test/cidr-claim in CIDRBlock cidr-block-001",
+		))
+	})
+
+	It("Allocate sequentially", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR:               "192.168.1.0/24",
+				AllocationStrategy: controlplanev1alpha1.AllocationStrategySequential,
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		createClaim := func(name string) controlplanev1alpha1.CIDRClaim {
+			cidrClaim := controlplanev1alpha1.CIDRClaim{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      name,
+					Namespace: testNamespace,
+				},
+				Spec: controlplanev1alpha1.CIDRClaimSpec{
+					Selector: v1.LabelSelector{
+						MatchLabels: map[string]string{
+							"controlplane.miscord.win/address-type": "v4",
+						},
+					},
+					SizeBit: 4,
+				},
+			}
+
+			err := k8sClient.Create(ctx, &cidrClaim)
+			Expect(err).NotTo(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(&cidrClaim)
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, key, &cidrClaim)
+
+				if err != nil {
+					return err
+				}
+
+				if cidrClaim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+					return fmt.Errorf("not ready")
+				}
+
+				return nil
+			}).Should(Succeed())
+
+			return cidrClaim
+		}
+
+		first := createClaim("cidr-claim-001")
+		Expect(first.Status.CIDR).To(Equal("192.168.1.0/28"))
+
+		second := createClaim("cidr-claim-002")
+		Expect(second.Status.CIDR).To(Equal("192.168.1.16/28"))
+
+		err = k8sClient.Delete(ctx, &first)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrBlockKey := client.ObjectKeyFromObject(&cidrBlock)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+
+			if err != nil {
+				return err
+			}
+
+			if len(cidrBlock.Status.Allocations) != 1 {
+				return fmt.Errorf("not released")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		third := createClaim("cidr-claim-003")
+		Expect(third.Status.CIDR).To(Equal("192.168.1.32/28"))
+	})
+
+	It("Allocate dual-stack", func() {
+		for _, cidrBlock := range []controlplanev1alpha1.CIDRBlock{
+			{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "cidr-block-v4",
+					Namespace: testNamespace,
+					Labels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+				Spec: controlplanev1alpha1.CIDRBlockSpec{
+					CIDR: "192.168.1.0/24",
+				},
+			},
+			{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "cidr-block-v6",
+					Namespace: testNamespace,
+					Labels: map[string]string{
+						"controlplane.miscord.win/address-type": "v6",
+					},
+				},
+				Spec: controlplanev1alpha1.CIDRBlockSpec{
+					CIDR: "fd00::/48",
+				},
+			},
+		} {
+			err := k8sClient.Create(ctx, &cidrBlock)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		family := func(family controlplanev1alpha1.AddressFamily, addressType string, sizeBit int) controlplanev1alpha1.CIDRClaimFamily {
+			return controlplanev1alpha1.CIDRClaimFamily{
+				Family: family,
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": addressType,
+					},
+				},
+				SizeBit: sizeBit,
+			}
+		}
+
+		cidrClaim := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-claim",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Families: []controlplanev1alpha1.CIDRClaimFamily{
+					family(controlplanev1alpha1.AddressFamilyIPv4, "v4", 0),
+					family(controlplanev1alpha1.AddressFamilyIPv6, "v6", 64),
+				},
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		key := client.ObjectKeyFromObject(&cidrClaim)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, key, &cidrClaim)
+
+			if err != nil {
+				return err
+			}
+
+			if cidrClaim.Status.ObservedGeneration != cidrClaim.Generation {
+				return fmt.Errorf("not updated")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(cidrClaim.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateReady))
+		Expect(cidrClaim.Status.CIDRs).To(Equal([]controlplanev1alpha1.CIDRClaimFamilyStatus{
+			{
+				Family:        controlplanev1alpha1.AddressFamilyIPv4,
+				CIDRBlockName: "cidr-block-v4",
+				CIDR:          "192.168.1.1/32",
+				SizeBit:       0,
+			},
+			{
+				Family:        controlplanev1alpha1.AddressFamilyIPv6,
+				CIDRBlockName: "cidr-block-v6",
+				CIDR:          "fd00::/64",
+				SizeBit:       64,
+			},
+		}))
+		Expect(cidrClaim.Status.CIDRBlockName).To(Equal("cidr-block-v4"))
+		Expect(cidrClaim.Status.CIDR).To(Equal("192.168.1.1/32"))
+
+		halfFailed := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-claim-half-failed",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Families: []controlplanev1alpha1.CIDRClaimFamily{
+					family(controlplanev1alpha1.AddressFamilyIPv4, "v4", 0),
+					family(controlplanev1alpha1.AddressFamilyIPv6, "v4", 64),
+				},
+			},
+		}
+
+		err = k8sClient.Create(ctx, &halfFailed)
+		Expect(err).NotTo(HaveOccurred())
+
+		halfFailedKey := client.ObjectKeyFromObject(&halfFailed)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, halfFailedKey, &halfFailed)
+
+			if err != nil {
+				return err
+			}
+
+			if halfFailed.Status.ObservedGeneration != halfFailed.Generation {
+				return fmt.Errorf("not updated")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(halfFailed.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateBindingError))
+		Expect(halfFailed.Status.Message).To(Equal("IPv6: no matching CIDRBlock"))
+		Expect(halfFailed.Status.CIDRs).To(BeEmpty())
+	})
+
+	It("Allocate the largest available size", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR:     "192.168.1.0/24",
+				Excludes: []string{"192.168.1.0/25", "192.168.1.128/26"},
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		createClaim := func(name string, sizeBit, minSizeBit int) controlplanev1alpha1.CIDRClaim {
+			cidrClaim := controlplanev1alpha1.CIDRClaim{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      name,
+					Namespace: testNamespace,
+				},
+				Spec: controlplanev1alpha1.CIDRClaimSpec{
+					Selector: v1.LabelSelector{
+						MatchLabels: map[string]string{
+							"controlplane.miscord.win/address-type": "v4",
+						},
+					},
+					SizeBit:    sizeBit,
+					MinSizeBit: &minSizeBit,
+				},
+			}
+
+			err := k8sClient.Create(ctx, &cidrClaim)
+			Expect(err).NotTo(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(&cidrClaim)
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, key, &cidrClaim)
+
+				if err != nil {
+					return err
+				}
+
+				if cidrClaim.Status.ObservedGeneration != cidrClaim.Generation {
+					return fmt.Errorf("not updated")
+				}
+
+				return nil
+			}).Should(Succeed())
+
+			return cidrClaim
+		}
+
+		shrunk := createClaim("cidr-claim-001", 8, 4)
+		Expect(shrunk.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateReady))
+		Expect(shrunk.Status.CIDR).To(Equal("192.168.1.192/26"))
+		Expect(shrunk.Status.SizeBit).To(Equal(6))
+
+		failed := createClaim("cidr-claim-002", 8, 4)
+		Expect(failed.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateBindingError))
+	})
+
+	It("Retain and quarantine released prefixes", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR:             "192.168.1.0/24",
+				QuarantinePeriod: &v1.Duration{Duration: time.Hour},
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		createClaim := func(name string, policy controlplanev1alpha1.ReclaimPolicy) controlplanev1alpha1.CIDRClaim {
+			cidrClaim := controlplanev1alpha1.CIDRClaim{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      name,
+					Namespace: testNamespace,
+				},
+				Spec: controlplanev1alpha1.CIDRClaimSpec{
+					Selector: v1.LabelSelector{
+						MatchLabels: map[string]string{
+							"controlplane.miscord.win/address-type": "v4",
+						},
+					},
+					SizeBit:       4,
+					ReclaimPolicy: policy,
+				},
+			}
+
+			err := k8sClient.Create(ctx, &cidrClaim)
+			Expect(err).NotTo(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(&cidrClaim)
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, key, &cidrClaim)
+
+				if err != nil {
+					return err
+				}
+
+				if cidrClaim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+					return fmt.Errorf("not ready")
+				}
+
+				return nil
+			}).Should(Succeed())
+
+			return cidrClaim
+		}
+
+		retained := createClaim("cidr-claim-retained", controlplanev1alpha1.ReclaimPolicyRetain)
+		Expect(retained.Status.CIDR).To(Equal("192.168.1.0/28"))
+
+		deleted := createClaim("cidr-claim-deleted", controlplanev1alpha1.ReclaimPolicyDelete)
+		Expect(deleted.Status.CIDR).To(Equal("192.168.1.16/28"))
+
+		for _, claim := range []*controlplanev1alpha1.CIDRClaim{&retained, &deleted} {
+			err = k8sClient.Delete(ctx, claim)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		cidrBlockKey := client.ObjectKeyFromObject(&cidrBlock)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+
+			if err != nil {
+				return err
+			}
+
+			if len(cidrBlock.Status.Allocations) != 0 || len(cidrBlock.Status.ReleasedPrefixes) != 2 {
+				return fmt.Errorf("not released")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		other := createClaim("cidr-claim-other", controlplanev1alpha1.ReclaimPolicyDelete)
+		Expect(other.Status.CIDR).To(Equal("192.168.1.32/28"))
+
+		retained = createClaim("cidr-claim-retained", controlplanev1alpha1.ReclaimPolicyRetain)
+		Expect(retained.Status.CIDR).To(Equal("192.168.1.0/28"))
+
+		deleted = createClaim("cidr-claim-deleted", controlplanev1alpha1.ReclaimPolicyDelete)
+		Expect(deleted.Status.CIDR).To(Equal("192.168.1.48/28"))
+
+		err = k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cidrBlock.Status.ReleasedPrefixes).To(HaveLen(1))
+		Expect(cidrBlock.Status.ReleasedPrefixes[0].CIDR).To(Equal("192.168.1.16/28"))
+		Expect(cidrBlock.Status.ReleasedPrefixes[0].Retained).To(BeFalse())
+	})
+
+	It("Drain the previous prefix after renumbering", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		cidrClaim := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-claim-001",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+				SizeBit:     4,
+				DrainPeriod: &v1.Duration{Duration: 3 * time.Second},
+			},
+		}
+
+		err = k8sClient.Create(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		key := client.ObjectKeyFromObject(&cidrClaim)
+		waitForGeneration := func() {
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, key, &cidrClaim)
+
+				if err != nil {
+					return err
+				}
+
+				if cidrClaim.Status.ObservedGeneration != cidrClaim.Generation ||
+					cidrClaim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+					return fmt.Errorf("not ready")
+				}
+
+				return nil
+			}).Should(Succeed())
+		}
+
+		waitForGeneration()
+		Expect(cidrClaim.Status.CIDR).To(Equal("192.168.1.0/28"))
+
+		cidrClaim.Spec.SizeBit = 5
+		err = k8sClient.Update(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		waitForGeneration()
+		Expect(cidrClaim.Status.CIDR).To(Equal("192.168.1.32/27"))
+		Expect(cidrClaim.Status.Draining).To(HaveLen(1))
+		Expect(cidrClaim.Status.Draining[0].CIDR).To(Equal("192.168.1.0/28"))
+		Expect(cidrClaim.Status.AllocatedStatuses()).To(HaveLen(2))
+
+		cidrBlockKey := client.ObjectKeyFromObject(&cidrBlock)
+		err = k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cidrBlock.Status.Allocations).To(HaveLen(2))
+
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, key, &cidrClaim)
+
+			if err != nil {
+				return err
+			}
+
+			if len(cidrClaim.Status.Draining) != 0 {
+				return fmt.Errorf("still draining")
+			}
+
+			err = k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+
+			if err != nil {
+				return err
+			}
+
+			if len(cidrBlock.Status.Allocations) != 1 {
+				return fmt.Errorf("not released")
+			}
+
+			return nil
+		}, 10*time.Second).Should(Succeed())
+
+		Expect(cidrBlock.Status.Allocations[0].CIDR).To(Equal("192.168.1.32/27"))
+		Expect(eventReasons(ctx, &cidrClaim)).To(ContainElements("Draining", "Drained"))
+	})
+
+	It("Allocate from a CIDRBlock shared across namespaces", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+				NamespaceSelector: &v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/tenant": "true",
+					},
+				},
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		createClaim := func(namespace string) controlplanev1alpha1.CIDRClaim {
+			cidrClaim := controlplanev1alpha1.CIDRClaim{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "cidr-claim-001",
+					Namespace: namespace,
+				},
+				Spec: controlplanev1alpha1.CIDRClaimSpec{
+					Selector: v1.LabelSelector{
+						MatchLabels: map[string]string{
+							"controlplane.miscord.win/address-type": "v4",
+						},
+					},
+					SizeBit: 4,
+				},
+			}
+
+			err := k8sClient.Create(ctx, &cidrClaim)
+			Expect(err).NotTo(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(&cidrClaim)
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, key, &cidrClaim)
+
+				if err != nil {
+					return err
+				}
+
+				if cidrClaim.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+					return fmt.Errorf("not ready")
+				}
+
+				return nil
+			}).Should(Succeed())
+
+			return cidrClaim
+		}
+
+		local := createClaim(testNamespace)
+		Expect(local.Status.CIDR).To(Equal("192.168.1.0/28"))
+		Expect(local.Status.CIDRBlockNamespace).To(BeEmpty())
+
+		tenant := createClaim(tenantNamespace)
+		Expect(tenant.Status.CIDR).To(Equal("192.168.1.16/28"))
+		Expect(tenant.Status.CIDRBlockName).To(Equal("cidr-block-001"))
+		Expect(tenant.Status.CIDRBlockNamespace).To(Equal(testNamespace))
+
+		cidrBlockKey := client.ObjectKeyFromObject(&cidrBlock)
+		err = k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cidrBlock.Status.Allocations).To(HaveLen(2))
+		Expect(cidrBlock.Status.Allocations[1].ClaimNamespace).To(Equal(tenantNamespace))
+
+		err = k8sClient.Delete(ctx, &tenant)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+
+			if err != nil {
+				return err
+			}
+
+			if len(cidrBlock.Status.Allocations) != 1 {
+				return fmt.Errorf("not released")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(cidrBlock.Status.Allocations[0].ClaimNamespace).To(Equal(testNamespace))
+	})
 })
+
+// eventReasons returns the reasons of the Events regarding the object
+func eventReasons(ctx context.Context, obj client.Object) []string {
+	var events eventsv1.EventList
+	err := k8sClient.List(ctx, &events, client.InNamespace(obj.GetNamespace()))
+	Expect(err).NotTo(HaveOccurred())
+
+	var reasons []string
+	for _, event := range events.Items {
+		if event.Regarding.UID == obj.GetUID() {
+			reasons = append(reasons, event.Reason)
+		}
+	}
+
+	return reasons
+}
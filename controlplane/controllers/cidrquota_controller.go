@@ -0,0 +1,150 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+// CIDRQuotaReconciler reconciles a CIDRQuota object
+type CIDRQuotaReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrquotas,verbs=get;list;watch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrquotas/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrblocks,verbs=get;list;watch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrclaims,verbs=get;list;watch
+
+// Reconcile reports the number of CIDRClaims in the namespace of the CIDRQuota and their
+// addresses bound to the CIDRBlocks selected by it in any namespace. The quota is enforced
+// by CIDRClaimReconciler.
+func (r *CIDRQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var quota controlplanev1alpha1.CIDRQuota
+
+	err := r.Get(ctx, req.NamespacedName, &quota)
+
+	if errors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get CIDRQuota: %w", err)
+	}
+
+	// The claims may be bound to the blocks shared from other namespaces
+	var cidrBlocks controlplanev1alpha1.CIDRBlockList
+	if err := r.List(ctx, &cidrBlocks); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list CIDRBlocks: %w", err)
+	}
+
+	var cidrClaims controlplanev1alpha1.CIDRClaimList
+	if err := r.List(ctx, &cidrClaims, client.InNamespace(req.Namespace)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list CIDRClaims: %w", err)
+	}
+
+	selected := map[types.NamespacedName]bool{}
+	for i := range cidrBlocks.Items {
+		if quotaSelects(&quota, &cidrBlocks.Items[i]) {
+			selected[client.ObjectKeyFromObject(&cidrBlocks.Items[i])] = true
+		}
+	}
+
+	usage := newQuotaUsage()
+	for _, claim := range cidrClaims.Items {
+		for _, s := range claim.Status.FamilyStatuses() {
+			if !selected[blockKeyOf(&claim, &s)] {
+				continue
+			}
+
+			usage.add(&controlplanev1alpha1.CIDRBlockAllocation{
+				ClaimName: claim.Name,
+				ClaimUID:  claim.UID,
+				CIDR:      s.CIDR,
+			})
+		}
+	}
+
+	status := controlplanev1alpha1.CIDRQuotaStatus{
+		ObservedGeneration: quota.Generation,
+		UsedClaims:         int64(len(usage.claims)),
+		UsedAddresses:      usage.addresses.String(),
+	}
+
+	if equality.Semantic.DeepEqual(&quota.Status, &status) {
+		return ctrl.Result{}, nil
+	}
+
+	updated := quota.DeepCopy()
+	updated.Status = status
+
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(&quota)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CIDRQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	quotasIn := func(ctx context.Context, opts ...client.ListOption) []reconcile.Request {
+		var quotas controlplanev1alpha1.CIDRQuotaList
+		if err := r.List(ctx, &quotas, opts...); err != nil {
+			log.FromContext(ctx).Error(err, "failed to list CIDRQuotas")
+
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(quotas.Items))
+		for _, quota := range quotas.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: client.ObjectKeyFromObject(&quota),
+			})
+		}
+
+		return requests
+	}
+
+	// Any change of CIDRClaims can change the usage of the quotas in their namespace
+	quotasInNamespace := handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+		return quotasIn(ctx, client.InNamespace(o.GetNamespace()))
+	})
+
+	// CIDRBlocks in any namespace can be selected by the quotas
+	allQuotas := handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+		return quotasIn(ctx)
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&controlplanev1alpha1.CIDRQuota{}).
+		Watches(&controlplanev1alpha1.CIDRClaim{}, quotasInNamespace).
+		Watches(&controlplanev1alpha1.CIDRBlock{}, allQuotas).
+		Complete(r)
+}
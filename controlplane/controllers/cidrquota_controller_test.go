@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+var _ = Describe("CIDRQuota", func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		deleteAll(ctx)
+
+		scheme := scheme.Scheme
+
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme: scheme,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = (&CIDRClaimReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   scheme,
+			Recorder: mgr.GetEventRecorder("cidrclaim-controller"),
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = (&CIDRBlockReconciler{
+			Client: mgr.GetClient(),
+			Scheme: scheme,
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = (&CIDRQuotaReconciler{
+			Client: mgr.GetClient(),
+			Scheme: scheme,
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		go func() {
+			err := mgr.Start(ctx)
+
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		cancel()
+
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("Enforce the limits and report the usage", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		maxClaims := int64(2)
+		quota := controlplanev1alpha1.CIDRQuota{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-quota",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRQuotaSpec{
+				Selector: &v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+				MaxClaims:    &maxClaims,
+				MaxAddresses: "48",
+			},
+		}
+
+		err = k8sClient.Create(ctx, &quota)
+		Expect(err).NotTo(HaveOccurred())
+
+		createClaim := func(name string, sizeBit, minSizeBit int) controlplanev1alpha1.CIDRClaim {
+			cidrClaim := controlplanev1alpha1.CIDRClaim{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      name,
+					Namespace: testNamespace,
+				},
+				Spec: controlplanev1alpha1.CIDRClaimSpec{
+					Selector: v1.LabelSelector{
+						MatchLabels: map[string]string{
+							"controlplane.miscord.win/address-type": "v4",
+						},
+					},
+					SizeBit:    sizeBit,
+					MinSizeBit: &minSizeBit,
+				},
+			}
+
+			err := k8sClient.Create(ctx, &cidrClaim)
+			Expect(err).NotTo(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(&cidrClaim)
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, key, &cidrClaim)
+
+				if err != nil {
+					return err
+				}
+
+				if cidrClaim.Status.ObservedGeneration != cidrClaim.Generation {
+					return fmt.Errorf("not updated")
+				}
+
+				return nil
+			}).Should(Succeed())
+
+			return cidrClaim
+		}
+
+		first := createClaim("cidr-claim-001", 5, 4)
+		Expect(first.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateReady))
+		Expect(first.Status.SizeBit).To(Equal(5))
+
+		// Only 16 addresses are left in the quota
+		second := createClaim("cidr-claim-002", 5, 3)
+		Expect(second.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateReady))
+		Expect(second.Status.SizeBit).To(Equal(4))
+
+		third := createClaim("cidr-claim-003", 0, 0)
+		Expect(third.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateQuotaExceeded))
+		Expect(third.Status.Message).To(Equal("CIDRQuota exceeded in all matching CIDRBlocks"))
+
+		quotaKey := client.ObjectKeyFromObject(&quota)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, quotaKey, &quota)
+
+			if err != nil {
+				return err
+			}
+
+			if quota.Status.UsedClaims != 2 || quota.Status.UsedAddresses != "48" {
+				return fmt.Errorf("unexpected usage: %+v", quota.Status)
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		err = k8sClient.Delete(ctx, &first)
+		Expect(err).NotTo(HaveOccurred())
+
+		thirdKey := client.ObjectKeyFromObject(&third)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, thirdKey, &third)
+
+			if err != nil {
+				return err
+			}
+
+			if third.Status.State != controlplanev1alpha1.CIDRClaimStatusStateReady {
+				return fmt.Errorf("not ready")
+			}
+
+			return nil
+		}, 10*time.Second).Should(Succeed())
+	})
+
+	It("Limit the claims in the namespace of the quota in the blocks shared from other namespaces", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+				NamespaceSelector: &v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/tenant": "true",
+					},
+				},
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		maxClaims := int64(1)
+		quota := controlplanev1alpha1.CIDRQuota{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-quota",
+				Namespace: tenantNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRQuotaSpec{
+				MaxClaims: &maxClaims,
+			},
+		}
+
+		err = k8sClient.Create(ctx, &quota)
+		Expect(err).NotTo(HaveOccurred())
+
+		createClaim := func(namespace, name string) controlplanev1alpha1.CIDRClaim {
+			cidrClaim := controlplanev1alpha1.CIDRClaim{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+				Spec: controlplanev1alpha1.CIDRClaimSpec{
+					Selector: v1.LabelSelector{
+						MatchLabels: map[string]string{
+							"controlplane.miscord.win/address-type": "v4",
+						},
+					},
+					SizeBit: 4,
+				},
+			}
+
+			err := k8sClient.Create(ctx, &cidrClaim)
+			Expect(err).NotTo(HaveOccurred())
+
+			key := client.ObjectKeyFromObject(&cidrClaim)
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, key, &cidrClaim)
+
+				if err != nil {
+					return err
+				}
+
+				if cidrClaim.Status.ObservedGeneration != cidrClaim.Generation {
+					return fmt.Errorf("not updated")
+				}
+
+				return nil
+			}).Should(Succeed())
+
+			return cidrClaim
+		}
+
+		// The claims in the namespace of the block are not counted
+		owner := createClaim(testNamespace, "cidr-claim-001")
+		Expect(owner.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateReady))
+
+		first := createClaim(tenantNamespace, "cidr-claim-002")
+		Expect(first.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateReady))
+
+		second := createClaim(tenantNamespace, "cidr-claim-003")
+		Expect(second.Status.State).To(Equal(controlplanev1alpha1.CIDRClaimStatusStateQuotaExceeded))
+
+		quotaKey := client.ObjectKeyFromObject(&quota)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, quotaKey, &quota)
+
+			if err != nil {
+				return err
+			}
+
+			if quota.Status.UsedClaims != 1 || quota.Status.UsedAddresses != "16" {
+				return fmt.Errorf("unexpected usage: %+v", quota.Status)
+			}
+
+			return nil
+		}).Should(Succeed())
+	})
+})
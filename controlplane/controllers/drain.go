@@ -0,0 +1,155 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+// drain releases the previous allocations of the ready claim whose drain period has ended
+// and updates the status with the remaining ones.
+func (r *CIDRClaimReconciler) drain(
+	ctx context.Context,
+	cidrClaim *controlplanev1alpha1.CIDRClaim,
+	status *controlplanev1alpha1.CIDRClaimStatus,
+) (ctrl.Result, error) {
+	draining, drained, requeueAfter := expireDraining(status.Draining, time.Now())
+	status.Draining = draining
+
+	if err := r.updateStatus(ctx, cidrClaim, status); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(drained) == 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	if err := r.ipam().Release(ctx, cidrClaim, keptAllocations(cidrClaim, status)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to release drained allocations: %w", err)
+	}
+
+	for _, d := range drained {
+		r.Recorder.Eventf(
+			cidrClaim, nil, corev1.EventTypeNormal, "Drained", "Allocate",
+			"Released %s of CIDRBlock %s after the drain period", d.CIDR, d.CIDRBlockName,
+		)
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// drainingAllocations returns the allocations to drain after the claim is bound to bound: the
+// previous ones replaced by bound if the claim has a drain period, followed by the ones already
+// being drained unless they are bound again.
+func drainingAllocations(
+	cidrClaim *controlplanev1alpha1.CIDRClaim,
+	requests []allocationRequest,
+	bound []controlplanev1alpha1.CIDRClaimFamilyStatus,
+	now time.Time,
+) []controlplanev1alpha1.CIDRClaimDrainingStatus {
+	var draining []controlplanev1alpha1.CIDRClaimDrainingStatus
+
+	if period := drainPeriod(cidrClaim); period > 0 {
+		for _, request := range requests {
+			prev := request.boundStatus(cidrClaim)
+
+			if prev == nil || hasAllocation(bound, prev) {
+				continue
+			}
+
+			draining = append(draining, controlplanev1alpha1.CIDRClaimDrainingStatus{
+				CIDRClaimFamilyStatus: *prev,
+				ReleaseAt:             metav1.NewTime(now.Add(period)),
+			})
+		}
+	}
+
+	for _, d := range cidrClaim.Status.Draining {
+		if hasAllocation(bound, &d.CIDRClaimFamilyStatus) {
+			continue
+		}
+
+		draining = append(draining, d)
+	}
+
+	return draining
+}
+
+// drainPeriod returns the period the previous allocations of the claim are kept after renumbering
+func drainPeriod(cidrClaim *controlplanev1alpha1.CIDRClaim) time.Duration {
+	if cidrClaim.Spec.DrainPeriod == nil {
+		return 0
+	}
+
+	return cidrClaim.Spec.DrainPeriod.Duration
+}
+
+// expireDraining splits the draining allocations into the remaining ones and the ones whose
+// drain period has ended at now, and returns the duration until the next one ends.
+func expireDraining(
+	draining []controlplanev1alpha1.CIDRClaimDrainingStatus,
+	now time.Time,
+) (remaining, drained []controlplanev1alpha1.CIDRClaimDrainingStatus, next time.Duration) {
+	for _, d := range draining {
+		left := d.ReleaseAt.Sub(now)
+
+		if left <= 0 {
+			drained = append(drained, d)
+
+			continue
+		}
+
+		remaining = append(remaining, d)
+
+		if next == 0 || left < next {
+			next = left
+		}
+	}
+
+	return remaining, drained, next
+}
+
+// hasAllocation returns true if the statuses contain the allocation of s
+func hasAllocation(statuses []controlplanev1alpha1.CIDRClaimFamilyStatus, s *controlplanev1alpha1.CIDRClaimFamilyStatus) bool {
+	for _, status := range statuses {
+		if status.CIDRBlockName == s.CIDRBlockName && status.CIDRBlockNamespace == s.CIDRBlockNamespace && status.CIDR == s.CIDR {
+			return true
+		}
+	}
+
+	return false
+}
+
+// keptAllocations returns the CIDRs bound to the claim or being drained per CIDRBlock
+func keptAllocations(claim *controlplanev1alpha1.CIDRClaim, status *controlplanev1alpha1.CIDRClaimStatus) map[types.NamespacedName][]string {
+	keep := map[types.NamespacedName][]string{}
+	for _, s := range status.AllocatedStatuses() {
+		key := blockKeyOf(claim, &s)
+		keep[key] = append(keep[key], s.CIDR)
+	}
+
+	return keep
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func TestDrainingAllocations(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	status := func(block, cidr string) controlplanev1alpha1.CIDRClaimFamilyStatus {
+		return controlplanev1alpha1.CIDRClaimFamilyStatus{
+			Family:        controlplanev1alpha1.AddressFamilyIPv4,
+			CIDRBlockName: block,
+			CIDR:          cidr,
+		}
+	}
+
+	claim := &controlplanev1alpha1.CIDRClaim{
+		Spec: controlplanev1alpha1.CIDRClaimSpec{
+			DrainPeriod: &v1.Duration{Duration: time.Hour},
+		},
+		Status: controlplanev1alpha1.CIDRClaimStatus{
+			CIDRs: []controlplanev1alpha1.CIDRClaimFamilyStatus{status("block-002", "192.168.2.0/28")},
+			Draining: []controlplanev1alpha1.CIDRClaimDrainingStatus{
+				{CIDRClaimFamilyStatus: status("block-001", "192.168.1.0/28"), ReleaseAt: v1.NewTime(now.Add(time.Minute))},
+			},
+		},
+	}
+	requests := allocationRequests(claim)
+
+	// Renumbered to a new prefix
+	draining := drainingAllocations(claim, requests, []controlplanev1alpha1.CIDRClaimFamilyStatus{status("block-003", "192.168.3.0/28")}, now)
+
+	if len(draining) != 2 ||
+		draining[0].CIDR != "192.168.2.0/28" || !draining[0].ReleaseAt.Time.Equal(now.Add(time.Hour)) ||
+		draining[1].CIDR != "192.168.1.0/28" {
+		t.Errorf("unexpected draining allocations: %+v", draining)
+	}
+
+	// Renumbered back to the prefix being drained
+	draining = drainingAllocations(claim, requests, []controlplanev1alpha1.CIDRClaimFamilyStatus{status("block-001", "192.168.1.0/28")}, now)
+
+	if len(draining) != 1 || draining[0].CIDR != "192.168.2.0/28" {
+		t.Errorf("unexpected draining allocations after rebinding: %+v", draining)
+	}
+
+	claim.Spec.DrainPeriod = nil
+	draining = drainingAllocations(claim, requests, []controlplanev1alpha1.CIDRClaimFamilyStatus{status("block-003", "192.168.3.0/28")}, now)
+
+	if len(draining) != 1 || draining[0].CIDR != "192.168.1.0/28" {
+		t.Errorf("unexpected draining allocations without drain period: %+v", draining)
+	}
+}
+
+func TestExpireDraining(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	draining := []controlplanev1alpha1.CIDRClaimDrainingStatus{
+		{CIDRClaimFamilyStatus: controlplanev1alpha1.CIDRClaimFamilyStatus{CIDR: "192.168.1.0/28"}, ReleaseAt: v1.NewTime(now.Add(-time.Minute))},
+		{CIDRClaimFamilyStatus: controlplanev1alpha1.CIDRClaimFamilyStatus{CIDR: "192.168.1.16/28"}, ReleaseAt: v1.NewTime(now.Add(time.Hour))},
+		{CIDRClaimFamilyStatus: controlplanev1alpha1.CIDRClaimFamilyStatus{CIDR: "192.168.1.32/28"}, ReleaseAt: v1.NewTime(now.Add(30 * time.Minute))},
+	}
+
+	remaining, drained, next := expireDraining(draining, now)
+
+	if len(drained) != 1 || drained[0].CIDR != "192.168.1.0/28" {
+		t.Errorf("unexpected drained allocations: %+v", drained)
+	}
+	if len(remaining) != 2 || remaining[0].CIDR != "192.168.1.16/28" || remaining[1].CIDR != "192.168.1.32/28" {
+		t.Errorf("unexpected remaining allocations: %+v", remaining)
+	}
+	if next != 30*time.Minute {
+		t.Errorf("expected the next release in 30m, got %s", next)
+	}
+
+	claim := &controlplanev1alpha1.CIDRClaim{
+		ObjectMeta: v1.ObjectMeta{Namespace: "default"},
+		Status: controlplanev1alpha1.CIDRClaimStatus{
+			CIDRs: []controlplanev1alpha1.CIDRClaimFamilyStatus{{CIDRBlockName: "block-001", CIDR: "192.168.1.48/28"}},
+			Draining: []controlplanev1alpha1.CIDRClaimDrainingStatus{
+				{CIDRClaimFamilyStatus: controlplanev1alpha1.CIDRClaimFamilyStatus{CIDRBlockName: "block-001", CIDR: "192.168.1.16/28"}},
+				{CIDRClaimFamilyStatus: controlplanev1alpha1.CIDRClaimFamilyStatus{CIDRBlockName: "block-001", CIDRBlockNamespace: "shared", CIDR: "10.0.0.0/28"}},
+			},
+		},
+	}
+
+	keep := keptAllocations(claim, &claim.Status)
+	if len(keep[types.NamespacedName{Namespace: "default", Name: "block-001"}]) != 2 ||
+		len(keep[types.NamespacedName{Namespace: "shared", Name: "block-001"}]) != 1 {
+		t.Errorf("expected both the bound and draining CIDRs to be kept, got %v", keep)
+	}
+}
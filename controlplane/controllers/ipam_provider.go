@@ -0,0 +1,436 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/ipaddrutil"
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+// IPAMProvider reserves the prefixes of CIDRClaims in CIDRBlocks and releases them.
+// Implementations record the allocations in the ledgers of the CIDRBlocks so that
+// quotas, draining and the protection of CIDRBlocks work regardless of the provider.
+type IPAMProvider interface {
+	// Allocate reserves a prefix for the request in one of the candidate blocks
+	Allocate(ctx context.Context, req *AllocationRequest) (*Allocation, error)
+
+	// Release releases the allocations of the claim except the ones in keep,
+	// a map from a block to the CIDRs kept in it
+	Release(ctx context.Context, claim *controlplanev1alpha1.CIDRClaim, keep map[types.NamespacedName][]string) error
+}
+
+// AllocationRequest is a request of a prefix of an address family for a CIDRClaim
+type AllocationRequest struct {
+	Claim *controlplanev1alpha1.CIDRClaim
+
+	// Blocks are the candidate CIDRBlocks in the order of preference
+	Blocks []controlplanev1alpha1.CIDRBlock
+
+	// SizeBit and MinSizeBit are the range of log2(the number of addresses) acceptable for the claim
+	SizeBit    int
+	MinSizeBit int
+
+	// RequestedCIDR is the exact prefix requested by the claim if not empty
+	RequestedCIDR string
+
+	// Allocations are the prefixes bound to other claims in each candidate block
+	// including the ones not recorded in the ledger yet
+	Allocations map[types.NamespacedName][]controlplanev1alpha1.CIDRBlockAllocation
+
+	// Reservations are the CIDRReservations applied to the candidate blocks
+	Reservations []controlplanev1alpha1.CIDRReservation
+
+	// QuotaLimits are the largest sizes of a new allocation for the claim in each block
+	QuotaLimits map[types.NamespacedName]int
+}
+
+// acceptsSizeBit returns true if an allocation of the size satisfies the request
+func (r *AllocationRequest) acceptsSizeBit(sizeBit int) bool {
+	return r.MinSizeBit <= sizeBit && sizeBit <= r.SizeBit
+}
+
+// Allocation is a prefix reserved for a CIDRClaim
+type Allocation struct {
+	// Block is the CIDRBlock the prefix is allocated from
+	Block types.NamespacedName
+
+	// CIDR is the allocated prefix
+	CIDR string
+
+	// ExternalID is the ID of the prefix in the external IPAM, if any
+	ExternalID string
+}
+
+// InClusterProvider allocates the prefixes from the ledgers of the CIDRBlocks.
+// It is the default IPAMProvider of CIDRClaimReconciler.
+type InClusterProvider struct {
+	client.Client
+
+	// allocations keeps the tries of the prefixes used in the blocks between allocations
+	allocations *allocationTable
+}
+
+var _ IPAMProvider = &InClusterProvider{}
+
+// Release implements IPAMProvider
+func (p *InClusterProvider) Release(ctx context.Context, claim *controlplanev1alpha1.CIDRClaim, keep map[types.NamespacedName][]string) error {
+	return releaseAllocations(ctx, p.Client, claim, keep, true)
+}
+
+// parseRequestedCIDR parses the CIDR requested by a claim. A single address is treated as a prefix of the full length.
+func parseRequestedCIDR(requestedCIDR string) (*ipaddr.IPAddress, error) {
+	requested, err := ipaddr.NewIPAddressString(requestedCIDR).ToAddress()
+
+	if err != nil {
+		return nil, fmt.Errorf("requested CIDR is invalid: %w", err)
+	}
+	if requested.GetPrefixLen() == nil {
+		requested = requested.SetPrefixLen(requested.GetBitCount())
+	}
+	if !requested.IsPrefixBlock() {
+		return nil, fmt.Errorf("requested CIDR %s is not aligned to its prefix length", requestedCIDR)
+	}
+
+	return requested, nil
+}
+
+// Allocate finds a free prefix for the claim and records it in the ledger of the CIDRBlock.
+// An allocation already recorded for the claim is reused so that a lost status update
+// does not leak the prefix.
+func (p *InClusterProvider) Allocate(ctx context.Context, req *AllocationRequest) (*Allocation, error) {
+	cidrClaim, blocks, quotaLimits, reservations := req.Claim, req.Blocks, req.QuotaLimits, req.Reservations
+
+	if req.RequestedCIDR != "" {
+		return p.allocateRequested(ctx, req)
+	}
+
+	// The claim may have several allocations in a block while the previous one is drained
+	for _, block := range blocks {
+		for _, a := range block.Status.Allocations {
+			if !isAllocationOf(&a, cidrClaim) {
+				continue
+			}
+
+			addr := ipaddr.NewIPAddressString(a.CIDR).GetAddress()
+
+			if addr == nil || !req.acceptsSizeBit(prefixSizeBit(addr)) {
+				continue
+			}
+
+			return &Allocation{Block: client.ObjectKeyFromObject(&block), CIDR: addr.String()}, nil
+		}
+	}
+
+	for sizeBit := req.SizeBit; sizeBit >= req.MinSizeBit; sizeBit-- {
+		for _, block := range blocks {
+			if block.DeletionTimestamp != nil || exceedsQuota(quotaLimits, client.ObjectKeyFromObject(&block), sizeBit) {
+				continue
+			}
+
+			addr := reclaimRetained(cidrClaim, &block, sizeBit, reservations)
+
+			if addr == nil {
+				continue
+			}
+
+			allocations := append(block.Status.Allocations, controlplanev1alpha1.CIDRBlockAllocation{
+				ClaimName:      cidrClaim.Name,
+				ClaimNamespace: cidrClaim.Namespace,
+				ClaimUID:       cidrClaim.UID,
+				CIDR:           addr.String(),
+			})
+
+			ledgerVersion := block.Status.LedgerVersion
+			if err := patchAllocations(ctx, p.Client, &block, allocations, removeReleased(block.Status.ReleasedPrefixes, addr.String())); err != nil {
+				return nil, err
+			}
+
+			if p.allocations != nil {
+				p.allocations.allocated(&block, ledgerVersion, addr.String())
+			}
+
+			return &Allocation{Block: client.ObjectKeyFromObject(&block), CIDR: addr.String()}, nil
+		}
+	}
+
+	// The free prefixes of the blocks are computed once and shared by all sizes in the range
+	free := make(map[string][]*ipaddr.IPAddress, len(blocks))
+
+	for sizeBit := req.SizeBit; sizeBit >= req.MinSizeBit; sizeBit-- {
+		for _, block := range blocks {
+			if block.DeletionTimestamp != nil || exceedsQuota(quotaLimits, client.ObjectKeyFromObject(&block), sizeBit) {
+				continue
+			}
+
+			blockSubnet := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+
+			if blockSubnet == nil {
+				continue
+			}
+
+			var allocated *ipaddr.IPAddress
+			if p.allocations != nil && sizeBit != 0 {
+				allocated = p.allocations.findFree(&block, reservations, sizeBit, allocatorFor(&block))
+			} else {
+				key := client.ObjectKeyFromObject(&block).String()
+				if sizeBit == 0 {
+					key += "/0"
+				}
+
+				prefixes, ok := free[key]
+				if !ok {
+					prefixes = freeBlocksFor(&block, blockSubnet, sizeBit, req.Allocations[client.ObjectKeyFromObject(&block)], reservations)
+					free[key] = prefixes
+				}
+
+				allocated = allocatorFor(&block).Allocate(prefixes, sizeBit)
+			}
+
+			if allocated == nil {
+				continue
+			}
+
+			allocations := append(block.Status.Allocations, controlplanev1alpha1.CIDRBlockAllocation{
+				ClaimName:      cidrClaim.Name,
+				ClaimNamespace: cidrClaim.Namespace,
+				ClaimUID:       cidrClaim.UID,
+				CIDR:           allocated.String(),
+			})
+
+			ledgerVersion := block.Status.LedgerVersion
+			if err := patchAllocations(ctx, p.Client, &block, allocations, block.Status.ReleasedPrefixes); err != nil {
+				return nil, err
+			}
+
+			if p.allocations != nil {
+				p.allocations.allocated(&block, ledgerVersion, allocated.String())
+			}
+
+			return &Allocation{Block: client.ObjectKeyFromObject(&block), CIDR: allocated.String()}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no available CIDRBlock")
+}
+
+// freeBlocksFor returns the prefixes in the block free for an allocation of the size
+func freeBlocksFor(
+	block *controlplanev1alpha1.CIDRBlock,
+	blockSubnet *ipaddr.IPAddress,
+	sizeBit int,
+	usedClaims []controlplanev1alpha1.CIDRBlockAllocation,
+	reservations []controlplanev1alpha1.CIDRReservation,
+) []*ipaddr.IPAddress {
+	used := ledgerAddresses(block.Status.Allocations)
+
+	// Claims allocated before the ledger was introduced are not recorded yet
+	for _, a := range usedClaims {
+		addr := ipaddr.NewIPAddressString(a.CIDR).GetAddress()
+
+		if addr == nil {
+			continue
+		}
+
+		used = append(used, addr)
+	}
+
+	used = append(used, reservedAddresses(block, reservations)...)
+	used = append(used, releasedAddresses(block, nil)...)
+
+	if sizeBit == 0 {
+		addr, err := blockSubnet.SetPrefixLenZeroed(blockSubnet.GetBitCount())
+
+		if err == nil {
+			used = append(used, addr)
+		}
+	}
+
+	return freeBlocks(blockSubnet, used)
+}
+
+// reclaimRetained returns a prefix of the size retained for the claim in the block, or nil
+func reclaimRetained(
+	cidrClaim *controlplanev1alpha1.CIDRClaim,
+	block *controlplanev1alpha1.CIDRBlock,
+	sizeBit int,
+	reservations []controlplanev1alpha1.CIDRReservation,
+) *ipaddr.IPAddress {
+	reserved := reservedAddresses(block, reservations)
+	used := ledgerAddresses(block.Status.Allocations)
+
+	for i := range block.Status.ReleasedPrefixes {
+		p := &block.Status.ReleasedPrefixes[i]
+
+		if !isRetainedFor(p, cidrClaim) {
+			continue
+		}
+
+		addr := ipaddr.NewIPAddressString(p.CIDR).GetAddress()
+
+		if addr == nil || prefixSizeBit(addr) != sizeBit || overlapsAny(addr, reserved) || overlapsAny(addr, used) {
+			continue
+		}
+
+		return addr
+	}
+
+	return nil
+}
+
+// allocatorFor returns the allocator for the strategy of the block
+func allocatorFor(block *controlplanev1alpha1.CIDRBlock) ipaddrutil.Allocator {
+	switch block.Spec.AllocationStrategy {
+	case controlplanev1alpha1.AllocationStrategyBestFit:
+		return ipaddrutil.BestFit{}
+	case controlplanev1alpha1.AllocationStrategySequential:
+		var last *ipaddr.IPAddress
+		if n := len(block.Status.Allocations); n > 0 {
+			last = ipaddr.NewIPAddressString(block.Status.Allocations[n-1].CIDR).GetAddress()
+		}
+
+		return &ipaddrutil.Sequential{Last: last}
+	case controlplanev1alpha1.AllocationStrategyRandom:
+		return &ipaddrutil.Random{}
+	default:
+		return ipaddrutil.FirstFit{}
+	}
+}
+
+// allocateRequested allocates the prefix requested by the claim if it is free in
+// one of the blocks.
+func (p *InClusterProvider) allocateRequested(ctx context.Context, req *AllocationRequest) (*Allocation, error) {
+	cidrClaim, blocks := req.Claim, req.Blocks
+
+	requested, err := parseRequestedCIDR(req.RequestedCIDR)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, block := range blocks {
+		for _, a := range block.Status.Allocations {
+			if isAllocationOf(&a, cidrClaim) && a.CIDR == requested.String() {
+				return &Allocation{Block: client.ObjectKeyFromObject(&block), CIDR: requested.String()}, nil
+			}
+		}
+	}
+
+	var conflict error
+	for _, block := range blocks {
+		if block.DeletionTimestamp != nil {
+			continue
+		}
+
+		blockSubnet := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+
+		if blockSubnet == nil || !blockSubnet.ToPrefixBlock().Contains(requested) {
+			continue
+		}
+
+		conflict = findConflict(requested, cidrClaim, &block, req.Allocations[client.ObjectKeyFromObject(&block)], req.Reservations)
+
+		if conflict != nil {
+			continue
+		}
+
+		allocations := append(block.Status.Allocations, controlplanev1alpha1.CIDRBlockAllocation{
+			ClaimName:      cidrClaim.Name,
+			ClaimNamespace: cidrClaim.Namespace,
+			ClaimUID:       cidrClaim.UID,
+			CIDR:           requested.String(),
+		})
+
+		if err := patchAllocations(ctx, p.Client, &block, allocations, removeReleased(block.Status.ReleasedPrefixes, requested.String())); err != nil {
+			return nil, err
+		}
+
+		return &Allocation{Block: client.ObjectKeyFromObject(&block), CIDR: requested.String()}, nil
+	}
+
+	if conflict != nil {
+		return nil, conflict
+	}
+
+	return nil, fmt.Errorf("requested CIDR %s is not in any matching CIDRBlock", requested)
+}
+
+// findConflict returns an error naming the reserved range or the claim whose prefix overlaps the requested one
+func findConflict(
+	requested *ipaddr.IPAddress,
+	cidrClaim *controlplanev1alpha1.CIDRClaim,
+	block *controlplanev1alpha1.CIDRBlock,
+	usedClaims []controlplanev1alpha1.CIDRBlockAllocation,
+	reservations []controlplanev1alpha1.CIDRReservation,
+) error {
+	for _, addr := range reservedAddresses(block, reservations) {
+		if addr.Overlaps(requested) {
+			return fmt.Errorf(
+				"requested CIDR %s conflicts with %s reserved in CIDRBlock %s",
+				requested, addr, block.Name,
+			)
+		}
+	}
+
+	for i := range block.Status.ReleasedPrefixes {
+		p := &block.Status.ReleasedPrefixes[i]
+
+		if isRetainedFor(p, cidrClaim) {
+			continue
+		}
+
+		addr := ipaddr.NewIPAddressString(p.CIDR).GetAddress()
+
+		if addr != nil && addr.Overlaps(requested) {
+			namespace := p.ClaimNamespace
+			if namespace == "" {
+				namespace = block.Namespace
+			}
+
+			return fmt.Errorf(
+				"requested CIDR %s conflicts with %s released by CIDRClaim %s/%s in CIDRBlock %s",
+				requested, addr, namespace, p.ClaimName, block.Name,
+			)
+		}
+	}
+
+	allocations := make([]controlplanev1alpha1.CIDRBlockAllocation, 0, len(block.Status.Allocations)+len(usedClaims))
+	allocations = append(allocations, block.Status.Allocations...)
+	allocations = append(allocations, usedClaims...)
+
+	for _, a := range allocations {
+		if isAllocationOf(&a, cidrClaim) {
+			continue
+		}
+
+		addr := ipaddr.NewIPAddressString(a.CIDR).GetAddress()
+
+		if addr != nil && addr.Overlaps(requested) {
+			return fmt.Errorf(
+				"requested CIDR %s conflicts with %s allocated to CIDRClaim %s in CIDRBlock %s",
+				requested, addr, allocationClaimKey(block, &a), block.Name,
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,361 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/ipaddrutil"
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+const (
+	// cidrBlockFinalizer blocks deletion of CIDRBlocks while CIDRClaims are bound to them
+	cidrBlockFinalizer = "controlplane.miscord.win/cidrblock-protection"
+
+	// cidrClaimFinalizer keeps CIDRClaims until their allocations are released
+	cidrClaimFinalizer = "controlplane.miscord.win/cidrclaim-protection"
+
+	// cidrBlockClaimFinalizer keeps CIDRBlockClaims and their CIDRClaims until the child CIDRBlocks
+	// are deleted so that the prefixes of the children are not released while they are in use
+	cidrBlockClaimFinalizer = "controlplane.miscord.win/cidrblockclaim-protection"
+)
+
+// findAllocation returns the index of the allocation for the claim in the ledger of the block
+func findAllocation(block *controlplanev1alpha1.CIDRBlock, claim *controlplanev1alpha1.CIDRClaim) int {
+	for i, a := range block.Status.Allocations {
+		if isAllocationOf(&a, claim) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func isAllocationOf(a *controlplanev1alpha1.CIDRBlockAllocation, claim *controlplanev1alpha1.CIDRClaim) bool {
+	if a.ClaimName != claim.Name {
+		return false
+	}
+
+	if a.ClaimNamespace != "" && a.ClaimNamespace != claim.Namespace {
+		return false
+	}
+
+	return a.ClaimUID == "" || a.ClaimUID == claim.UID
+}
+
+// allocationClaimKey returns the key of the CIDRClaim of the allocation in the block.
+// Allocations without the namespace were recorded for claims in the namespace of the block.
+func allocationClaimKey(block *controlplanev1alpha1.CIDRBlock, a *controlplanev1alpha1.CIDRBlockAllocation) types.NamespacedName {
+	namespace := a.ClaimNamespace
+	if namespace == "" {
+		namespace = block.Namespace
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: a.ClaimName}
+}
+
+// blockKeyOf returns the key of the CIDRBlock the allocation of the claim is bound to
+func blockKeyOf(claim *controlplanev1alpha1.CIDRClaim, s *controlplanev1alpha1.CIDRClaimFamilyStatus) types.NamespacedName {
+	namespace := s.CIDRBlockNamespace
+	if namespace == "" {
+		namespace = claim.Namespace
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: s.CIDRBlockName}
+}
+
+// sharedNamespace returns the namespace of the block recorded in the status of the claim,
+// which is empty for blocks in the namespace of the claim
+func sharedNamespace(claim *controlplanev1alpha1.CIDRClaim, block types.NamespacedName) string {
+	if block.Namespace == claim.Namespace {
+		return ""
+	}
+
+	return block.Namespace
+}
+
+// ledgerAddresses returns the prefixes recorded in the ledger of the block
+func ledgerAddresses(allocations []controlplanev1alpha1.CIDRBlockAllocation) []*ipaddr.IPAddress {
+	addrs := make([]*ipaddr.IPAddress, 0, len(allocations))
+	for _, a := range allocations {
+		addr := ipaddr.NewIPAddressString(a.CIDR).GetAddress()
+
+		if addr == nil {
+			continue
+		}
+
+		addrs = append(addrs, addr)
+	}
+
+	return addrs
+}
+
+// prefixSizeBit returns log2(the number of addresses in the prefix)
+func prefixSizeBit(addr *ipaddr.IPAddress) int {
+	if addr == nil || addr.GetPrefixLen() == nil {
+		return 0
+	}
+
+	return addr.GetBitCount() - addr.GetPrefixLen().Len()
+}
+
+// freeBlocks returns the free prefix blocks in base like ipaddrutil.FreeBlocks.
+// A prefix trie is used so that it scales to pools with many allocations.
+func freeBlocks(base *ipaddr.IPAddress, used []*ipaddr.IPAddress) []*ipaddr.IPAddress {
+	trie := ipaddrutil.NewPrefixTrie(base)
+	for _, u := range used {
+		trie.Insert(u)
+	}
+
+	return trie.FreeBlocks()
+}
+
+// boundCIDR returns the CIDR of the claim bound to the block or an empty string
+func boundCIDR(claim *controlplanev1alpha1.CIDRClaim, block *controlplanev1alpha1.CIDRBlock) string {
+	if s := boundStatus(claim, block); s != nil {
+		return s.CIDR
+	}
+
+	return ""
+}
+
+// boundStatus returns the status of the allocation of the claim bound to the block, or nil
+func boundStatus(claim *controlplanev1alpha1.CIDRClaim, block *controlplanev1alpha1.CIDRBlock) *controlplanev1alpha1.CIDRClaimFamilyStatus {
+	for _, s := range claim.Status.FamilyStatuses() {
+		if blockKeyOf(claim, &s) == client.ObjectKeyFromObject(block) {
+			return &s
+		}
+	}
+
+	return nil
+}
+
+func overlapsAny(addr *ipaddr.IPAddress, addrs []*ipaddr.IPAddress) bool {
+	for _, a := range addrs {
+		if a.Overlaps(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// patchAllocations replaces the ledger and the released prefixes of the block and increments
+// its LedgerVersion. The patch fails with a conflict if the block has been updated since it was read.
+func patchAllocations(
+	ctx context.Context,
+	c client.Client,
+	block *controlplanev1alpha1.CIDRBlock,
+	allocations []controlplanev1alpha1.CIDRBlockAllocation,
+	released []controlplanev1alpha1.CIDRBlockReleasedPrefix,
+) error {
+	updated := block.DeepCopy()
+	updated.Status.Allocations = allocations
+	updated.Status.ReleasedPrefixes = released
+	updated.Status.LedgerVersion++
+
+	if err := c.Status().Patch(ctx, updated, client.MergeFromWithOptions(block, client.MergeFromWithOptimisticLock{})); err != nil {
+		return fmt.Errorf("failed to update allocations of CIDRBlock %s: %w", block.Name, err)
+	}
+	*block = *updated
+
+	return nil
+}
+
+// patchFinalizer adds or removes the finalizer of the object
+func patchFinalizer(ctx context.Context, c client.Client, obj client.Object, finalizer string, add bool) error {
+	base := obj.DeepCopyObject().(client.Object)
+
+	var changed bool
+	if add {
+		changed = controllerutil.AddFinalizer(obj, finalizer)
+	} else {
+		changed = controllerutil.RemoveFinalizer(obj, finalizer)
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := c.Patch(ctx, obj, client.MergeFrom(base)); err != nil {
+		return fmt.Errorf("failed to update finalizers: %w", err)
+	}
+
+	return nil
+}
+
+// releaseAllocations removes the allocations of the claim from all blocks including the ones
+// shared from other namespaces except the ones in keep, a map from a block to the CIDRs kept in it.
+// If keepReleased is true, the removed prefixes are retained for the claim if it is being
+// deleted with the Retain policy, or quarantined if the block has a quarantine period.
+// Otherwise, they are removed from the ledger at once since they are freed elsewhere.
+func releaseAllocations(
+	ctx context.Context,
+	c client.Client,
+	claim *controlplanev1alpha1.CIDRClaim,
+	keep map[types.NamespacedName][]string,
+	keepReleased bool,
+) error {
+	var cidrBlocks controlplanev1alpha1.CIDRBlockList
+	if err := c.List(ctx, &cidrBlocks); err != nil {
+		return fmt.Errorf("failed to list CIDRBlocks: %w", err)
+	}
+
+	retain := claim.DeletionTimestamp != nil && claim.Spec.ReclaimPolicy == controlplanev1alpha1.ReclaimPolicyRetain
+	now := metav1.Now()
+
+	for i := range cidrBlocks.Items {
+		block := &cidrBlocks.Items[i]
+
+		allocations := make([]controlplanev1alpha1.CIDRBlockAllocation, 0, len(block.Status.Allocations))
+		released := block.Status.ReleasedPrefixes
+		for _, a := range block.Status.Allocations {
+			if isAllocationOf(&a, claim) && !slices.Contains(keep[client.ObjectKeyFromObject(block)], a.CIDR) {
+				if keepReleased && (retain || quarantinePeriod(block) > 0) {
+					released = append(released, controlplanev1alpha1.CIDRBlockReleasedPrefix{
+						ClaimName:      claim.Name,
+						ClaimNamespace: claim.Namespace,
+						ClaimLabels:    claim.Labels,
+						CIDR:           a.CIDR,
+						ReleasedAt:     now,
+						Retained:       retain,
+					})
+				}
+
+				continue
+			}
+
+			allocations = append(allocations, a)
+		}
+
+		if len(allocations) == len(block.Status.Allocations) {
+			continue
+		}
+
+		if err := patchAllocations(ctx, c, block, allocations, released); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// quarantinePeriod returns the quarantine period of released prefixes in the block
+func quarantinePeriod(block *controlplanev1alpha1.CIDRBlock) time.Duration {
+	if block.Spec.QuarantinePeriod == nil {
+		return 0
+	}
+
+	return block.Spec.QuarantinePeriod.Duration
+}
+
+// isRetainedFor returns true if the released prefix is retained for the claim, i.e. the
+// claim in the same namespace has the same name or the same labels as the claim which released it.
+func isRetainedFor(p *controlplanev1alpha1.CIDRBlockReleasedPrefix, claim *controlplanev1alpha1.CIDRClaim) bool {
+	if !p.Retained {
+		return false
+	}
+
+	if p.ClaimNamespace != "" && p.ClaimNamespace != claim.Namespace {
+		return false
+	}
+
+	if p.ClaimName == claim.Name {
+		return true
+	}
+
+	return len(p.ClaimLabels) != 0 && labels.Equals(p.ClaimLabels, claim.Labels)
+}
+
+// releasedAddresses returns the released prefixes of the block not allocatable to the claim
+func releasedAddresses(block *controlplanev1alpha1.CIDRBlock, claim *controlplanev1alpha1.CIDRClaim) []*ipaddr.IPAddress {
+	addrs := make([]*ipaddr.IPAddress, 0, len(block.Status.ReleasedPrefixes))
+	for i := range block.Status.ReleasedPrefixes {
+		p := &block.Status.ReleasedPrefixes[i]
+
+		if claim != nil && isRetainedFor(p, claim) {
+			continue
+		}
+
+		addr := ipaddr.NewIPAddressString(p.CIDR).GetAddress()
+
+		if addr == nil {
+			continue
+		}
+
+		addrs = append(addrs, addr)
+	}
+
+	return addrs
+}
+
+// removeReleased returns the released prefixes without the CIDR
+func removeReleased(released []controlplanev1alpha1.CIDRBlockReleasedPrefix, cidr string) []controlplanev1alpha1.CIDRBlockReleasedPrefix {
+	remaining := make([]controlplanev1alpha1.CIDRBlockReleasedPrefix, 0, len(released))
+	for _, p := range released {
+		if p.CIDR == cidr {
+			continue
+		}
+
+		remaining = append(remaining, p)
+	}
+
+	return remaining
+}
+
+// expireReleased returns the released prefixes whose quarantine has not expired at now and
+// the duration until the next one expires. Retained prefixes never expire.
+func expireReleased(
+	block *controlplanev1alpha1.CIDRBlock,
+	released []controlplanev1alpha1.CIDRBlockReleasedPrefix,
+	now time.Time,
+) ([]controlplanev1alpha1.CIDRBlockReleasedPrefix, time.Duration) {
+	remaining := make([]controlplanev1alpha1.CIDRBlockReleasedPrefix, 0, len(released))
+	var next time.Duration
+	for _, p := range released {
+		if p.Retained {
+			remaining = append(remaining, p)
+
+			continue
+		}
+
+		left := p.ReleasedAt.Add(quarantinePeriod(block)).Sub(now)
+
+		if left <= 0 {
+			continue
+		}
+
+		remaining = append(remaining, p)
+
+		if next == 0 || left < next {
+			next = left
+		}
+	}
+
+	return remaining, next
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func TestIsRetainedFor(t *testing.T) {
+	released := &controlplanev1alpha1.CIDRBlockReleasedPrefix{
+		ClaimName:   "node-001",
+		ClaimLabels: map[string]string{"node": "node-001"},
+		CIDR:        "192.168.1.0/28",
+		Retained:    true,
+	}
+
+	claim := func(name string, labels map[string]string) *controlplanev1alpha1.CIDRClaim {
+		return &controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		claim    *controlplanev1alpha1.CIDRClaim
+		retained bool
+	}{
+		{name: "same name", claim: claim("node-001", nil), retained: true},
+		{name: "same labels", claim: claim("node-001-renamed", map[string]string{"node": "node-001"}), retained: true},
+		{name: "other labels", claim: claim("node-002", map[string]string{"node": "node-002"}), retained: false},
+		{name: "no labels", claim: claim("node-002", nil), retained: false},
+	} {
+		if got := isRetainedFor(released, tc.claim); got != tc.retained {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.retained, got)
+		}
+	}
+
+	quarantined := released.DeepCopy()
+	quarantined.Retained = false
+
+	if isRetainedFor(quarantined, claim("node-001", nil)) {
+		t.Errorf("quarantined prefixes must not be retained")
+	}
+
+	shared := released.DeepCopy()
+	shared.ClaimNamespace = "tenant"
+
+	if isRetainedFor(shared, claim("node-001", nil)) {
+		t.Errorf("prefixes released in another namespace must not be retained")
+	}
+}
+
+func TestExpireReleased(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	block := &controlplanev1alpha1.CIDRBlock{
+		Spec: controlplanev1alpha1.CIDRBlockSpec{
+			CIDR:             "192.168.1.0/24",
+			QuarantinePeriod: &v1.Duration{Duration: time.Hour},
+		},
+	}
+
+	released := []controlplanev1alpha1.CIDRBlockReleasedPrefix{
+		{ClaimName: "expired", CIDR: "192.168.1.0/28", ReleasedAt: v1.NewTime(now.Add(-2 * time.Hour))},
+		{ClaimName: "quarantined", CIDR: "192.168.1.16/28", ReleasedAt: v1.NewTime(now.Add(-30 * time.Minute))},
+		{ClaimName: "retained", CIDR: "192.168.1.32/28", ReleasedAt: v1.NewTime(now.Add(-2 * time.Hour)), Retained: true},
+	}
+
+	remaining, next := expireReleased(block, released, now)
+
+	if len(remaining) != 2 || remaining[0].ClaimName != "quarantined" || remaining[1].ClaimName != "retained" {
+		t.Errorf("unexpected remaining prefixes: %+v", remaining)
+	}
+	if next != 30*time.Minute {
+		t.Errorf("expected the next expiry in 30m, got %s", next)
+	}
+
+	block.Spec.QuarantinePeriod = nil
+	remaining, next = expireReleased(block, released, now)
+
+	if len(remaining) != 1 || remaining[0].ClaimName != "retained" || next != 0 {
+		t.Errorf("unexpected remaining prefixes without quarantine: %+v, %s", remaining, next)
+	}
+}
+
+func TestFilterOverlapping(t *testing.T) {
+	now := time.Now()
+
+	block := func(namespace, name, cidr string, created time.Time, shared bool) controlplanev1alpha1.CIDRBlock {
+		b := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				CreationTimestamp: v1.NewTime(created),
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{CIDR: cidr},
+		}
+		if shared {
+			b.Spec.NamespaceSelector = &v1.LabelSelector{}
+		}
+
+		return b
+	}
+
+	shared := block("pools", "shared", "10.0.0.0/16", now.Add(-time.Hour), true)
+	tenant := block("tenant", "copy", "10.0.1.0/24", now, false)
+	unrelated := block("tenant", "other", "10.1.0.0/16", now, false)
+
+	isController := true
+	child := block("tenant", "child", "10.0.2.0/24", now, false)
+	child.OwnerReferences = []v1.OwnerReference{{
+		APIVersion: controlplanev1alpha1.GroupVersion.String(),
+		Kind:       "CIDRBlockClaim",
+		Name:       "child",
+		Controller: &isController,
+	}}
+	shared.Status.Allocations = []controlplanev1alpha1.CIDRBlockAllocation{
+		{ClaimName: "child", ClaimNamespace: "tenant", CIDR: "10.0.2.0/24"},
+	}
+
+	all := []controlplanev1alpha1.CIDRBlock{shared, tenant, unrelated, child}
+
+	// The newer block overlapping the shared one is skipped while the shared one is kept
+	filtered, skipped := filterOverlapping(all, all)
+
+	if len(filtered) != 3 || len(skipped) != 1 || skipped[0] != "tenant/copy" {
+		t.Errorf("unexpected result: %d blocks, skipped %v", len(filtered), skipped)
+	}
+}
+
+func TestFilterCarvedBy(t *testing.T) {
+	isController := true
+	owned := func(kind string, uid types.UID) []v1.OwnerReference {
+		return []v1.OwnerReference{{
+			APIVersion: controlplanev1alpha1.GroupVersion.String(),
+			Kind:       kind,
+			Name:       "owner",
+			UID:        uid,
+			Controller: &isController,
+		}}
+	}
+
+	block := func(name string, owners []v1.OwnerReference) controlplanev1alpha1.CIDRBlock {
+		return controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "tenant", OwnerReferences: owners},
+		}
+	}
+
+	blocks := []controlplanev1alpha1.CIDRBlock{
+		block("parent", nil),
+		block("own", owned("CIDRBlockClaim", "owner-uid")),
+		block("other", owned("CIDRBlockClaim", "other-uid")),
+	}
+
+	claim := &controlplanev1alpha1.CIDRClaim{
+		ObjectMeta: v1.ObjectMeta{Name: "claim", Namespace: "tenant", OwnerReferences: owned("CIDRBlockClaim", "owner-uid")},
+	}
+
+	// The child of the CIDRBlockClaim controlling the claim is skipped
+	if filtered := filterCarvedBy(blocks, claim); len(filtered) != 2 || filtered[0].Name != "parent" || filtered[1].Name != "other" {
+		t.Errorf("unexpected blocks: %+v", filtered)
+	}
+
+	claim.OwnerReferences = owned("PeerNode", "owner-uid")
+	if filtered := filterCarvedBy(blocks, claim); len(filtered) != 3 {
+		t.Errorf("unexpected blocks for a claim of a PeerNode: %+v", filtered)
+	}
+}
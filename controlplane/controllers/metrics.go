@@ -0,0 +1,176 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+const metricsNamespace = "tetrapod"
+
+// Reasons of allocation failures
+const (
+	failureReasonInvalidSelector          = "invalid_selector"
+	failureReasonNoMatchingCIDRBlock      = "no_matching_cidrblock"
+	failureReasonNoSpace                  = "no_space"
+	failureReasonRequestedCIDRUnavailable = "requested_cidr_unavailable"
+	failureReasonQuotaExceeded            = "quota_exceeded"
+)
+
+var (
+	allocationDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "cidrclaim",
+		Name:      "allocation_duration_seconds",
+		Help:      "Duration from the creation of a CIDRClaim to its first allocation",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+	})
+
+	allocationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "cidrclaim",
+		Name:      "allocation_failures_total",
+		Help:      "Number of failed allocations for CIDRClaims by reason",
+	}, []string{"reason"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(allocationDuration, allocationFailures)
+}
+
+var (
+	cidrBlockSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "cidrblock", "size_addresses"),
+		"Number of addresses in the CIDRBlock",
+		[]string{"namespace", "name", "cidr"}, nil,
+	)
+	cidrBlockAllocatedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "cidrblock", "allocated_addresses"),
+		"Number of addresses in the CIDRBlock allocated to CIDRClaims",
+		[]string{"namespace", "name", "cidr"}, nil,
+	)
+	cidrBlockFreeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "cidrblock", "free_addresses"),
+		"Number of addresses in the CIDRBlock neither allocated nor reserved",
+		[]string{"namespace", "name", "cidr"}, nil,
+	)
+	cidrClaimsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "cidrclaims"),
+		"Number of CIDRClaims by state",
+		[]string{"namespace", "state"}, nil,
+	)
+)
+
+// ipamCollector reports the utilization of CIDRBlocks and the states of CIDRClaims.
+// They are read from the cache on each scrape so that deleted objects never leave stale series.
+type ipamCollector struct {
+	client client.Reader
+}
+
+var _ prometheus.Collector = &ipamCollector{}
+
+// RegisterMetrics registers the collector of the IPAM state with the metrics registry of controller-runtime
+func RegisterMetrics(c client.Reader) error {
+	return metrics.Registry.Register(&ipamCollector{
+		client: c,
+	})
+}
+
+// Describe implements prometheus.Collector
+func (c *ipamCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cidrBlockSizeDesc
+	ch <- cidrBlockAllocatedDesc
+	ch <- cidrBlockFreeDesc
+	ch <- cidrClaimsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *ipamCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	logger := log.FromContext(ctx).WithName("metrics")
+
+	var cidrBlocks controlplanev1alpha1.CIDRBlockList
+	if err := c.client.List(ctx, &cidrBlocks); err != nil {
+		logger.Error(err, "failed to list CIDRBlocks")
+	}
+
+	for _, block := range cidrBlocks.Items {
+		labels := []string{block.Namespace, block.Name, block.Spec.CIDR}
+
+		if addr := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress(); addr != nil && addr.GetPrefixLen() != nil {
+			ch <- prometheus.MustNewConstMetric(
+				cidrBlockSizeDesc, prometheus.GaugeValue,
+				math.Ldexp(1, prefixSizeBit(addr)), labels...,
+			)
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			cidrBlockAllocatedDesc, prometheus.GaugeValue,
+			parseAddressCount(block.Status.AllocatedAddresses), labels...,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			cidrBlockFreeDesc, prometheus.GaugeValue,
+			parseAddressCount(block.Status.FreeAddresses), labels...,
+		)
+	}
+
+	var cidrClaims controlplanev1alpha1.CIDRClaimList
+	if err := c.client.List(ctx, &cidrClaims); err != nil {
+		logger.Error(err, "failed to list CIDRClaims")
+	}
+
+	type key struct {
+		namespace string
+		state     controlplanev1alpha1.CIDRClaimStatusState
+	}
+	counts := map[key]int{}
+	for _, claim := range cidrClaims.Items {
+		counts[key{namespace: claim.Namespace, state: claim.Status.State}]++
+	}
+
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(
+			cidrClaimsDesc, prometheus.GaugeValue,
+			float64(count), k.namespace, string(k.state),
+		)
+	}
+}
+
+// parseAddressCount parses the decimal number of addresses in the status of CIDRBlock
+func parseAddressCount(s string) float64 {
+	count, ok := new(big.Float).SetString(s)
+	if !ok {
+		return 0
+	}
+
+	f, _ := count.Float64()
+
+	return f
+}
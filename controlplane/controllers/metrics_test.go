@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+var _ = Describe("Metrics", func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		deleteAll(ctx)
+
+		scheme := scheme.Scheme
+
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme: scheme,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = (&CIDRClaimReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   scheme,
+			Recorder: mgr.GetEventRecorder("cidrclaim-controller"),
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = (&CIDRBlockReconciler{
+			Client: mgr.GetClient(),
+			Scheme: scheme,
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		go func() {
+			err := mgr.Start(ctx)
+
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		cancel()
+
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("Report utilization and claims", func() {
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-001",
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+			},
+		}
+
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		for i, sizeBit := range []int{7, 8} {
+			cidrClaim := controlplanev1alpha1.CIDRClaim{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      fmt.Sprintf("cidr-claim-%03d", i),
+					Namespace: testNamespace,
+				},
+				Spec: controlplanev1alpha1.CIDRClaimSpec{
+					Selector: v1.LabelSelector{
+						MatchLabels: map[string]string{
+							"controlplane.miscord.win/address-type": "v4",
+						},
+					},
+					SizeBit: sizeBit,
+				},
+			}
+
+			err = k8sClient.Create(ctx, &cidrClaim)
+			Expect(err).NotTo(HaveOccurred())
+
+			cidrClaimKey := client.ObjectKeyFromObject(&cidrClaim)
+			Eventually(func() error {
+				err := k8sClient.Get(ctx, cidrClaimKey, &cidrClaim)
+
+				if err != nil {
+					return err
+				}
+
+				if cidrClaim.Status.ObservedGeneration != cidrClaim.Generation {
+					return fmt.Errorf("not updated")
+				}
+
+				return nil
+			}).Should(Succeed())
+		}
+
+		cidrBlockKey := client.ObjectKeyFromObject(&cidrBlock)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, cidrBlockKey, &cidrBlock)
+
+			if err != nil {
+				return err
+			}
+
+			if cidrBlock.Status.BoundClaims != 1 {
+				return fmt.Errorf("not updated")
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		collector := &ipamCollector{client: k8sClient}
+
+		expected := fmt.Sprintf(`
+# HELP tetrapod_cidrblock_allocated_addresses Number of addresses in the CIDRBlock allocated to CIDRClaims
+# TYPE tetrapod_cidrblock_allocated_addresses gauge
+tetrapod_cidrblock_allocated_addresses{cidr="192.168.1.0/24",name="cidr-block-001",namespace="%[1]s"} 128
+# HELP tetrapod_cidrblock_free_addresses Number of addresses in the CIDRBlock neither allocated nor reserved
+# TYPE tetrapod_cidrblock_free_addresses gauge
+tetrapod_cidrblock_free_addresses{cidr="192.168.1.0/24",name="cidr-block-001",namespace="%[1]s"} 128
+# HELP tetrapod_cidrblock_size_addresses Number of addresses in the CIDRBlock
+# TYPE tetrapod_cidrblock_size_addresses gauge
+tetrapod_cidrblock_size_addresses{cidr="192.168.1.0/24",name="cidr-block-001",namespace="%[1]s"} 256
+# HELP tetrapod_cidrclaims Number of CIDRClaims by state
+# TYPE tetrapod_cidrclaims gauge
+tetrapod_cidrclaims{namespace="%[1]s",state="bindingError"} 1
+tetrapod_cidrclaims{namespace="%[1]s",state="ready"} 1
+`, testNamespace)
+
+		err = testutil.CollectAndCompare(collector, strings.NewReader(expected))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(testutil.ToFloat64(allocationFailures.WithLabelValues(failureReasonNoSpace))).To(BeNumerically(">=", 1))
+	})
+})
@@ -0,0 +1,280 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/netbox"
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+// NetBoxProvider reserves the prefixes in NetBox or a server compatible with its IPAM API.
+// Each CIDRBlock is backed by the prefix with the same CIDR in NetBox and the prefixes of
+// CIDRClaims are created as its children. The IDs of the prefixes are recorded as the
+// external IDs in the ledgers of the blocks and the statuses of the claims.
+//
+// NetBox is the source of truth of the free space, so CIDRReservations and the quarantine
+// and retention of released prefixes are not applied. Released prefixes are removed from
+// the ledgers at once regardless of ReclaimPolicy and QuarantinePeriod. Reserve the ranges
+// in NetBox instead.
+type NetBoxProvider struct {
+	client.Client
+
+	NetBox *netbox.Client
+}
+
+var _ IPAMProvider = &NetBoxProvider{}
+
+// Allocate implements IPAMProvider. A prefix reserved for the claim is reused if it is
+// recorded in the ledger, or adopted if the update of the ledger failed after reserving it.
+func (p *NetBoxProvider) Allocate(ctx context.Context, req *AllocationRequest) (*Allocation, error) {
+	var requested *ipaddr.IPAddress
+	if req.RequestedCIDR != "" {
+		var err error
+		requested, err = parseRequestedCIDR(req.RequestedCIDR)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, block := range req.Blocks {
+		for _, a := range block.Status.Allocations {
+			if isAllocationOf(&a, req.Claim) && a.ExternalID != "" && accepts(req, requested, a.CIDR) {
+				return &Allocation{Block: client.ObjectKeyFromObject(&block), CIDR: a.CIDR, ExternalID: a.ExternalID}, nil
+			}
+		}
+	}
+
+	for i := range req.Blocks {
+		block := &req.Blocks[i]
+
+		if block.DeletionTimestamp != nil {
+			continue
+		}
+
+		blockSubnet := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+
+		if blockSubnet == nil || (requested != nil && !blockSubnet.ToPrefixBlock().Contains(requested)) {
+			continue
+		}
+
+		parent, err := p.NetBox.GetPrefix(ctx, blockSubnet.ToPrefixBlock().String())
+
+		if errors.Is(err, netbox.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		prefix, err := p.reserve(ctx, req, block, parent, requested)
+		if err != nil {
+			return nil, err
+		}
+		if prefix == nil {
+			continue
+		}
+
+		addr := ipaddr.NewIPAddressString(prefix.Prefix).GetAddress()
+		if addr == nil {
+			return nil, fmt.Errorf("prefix %d reserved in NetBox is invalid: %s", prefix.ID, prefix.Prefix)
+		}
+
+		externalID := strconv.Itoa(prefix.ID)
+		allocations := append(block.Status.Allocations, controlplanev1alpha1.CIDRBlockAllocation{
+			ClaimName:      req.Claim.Name,
+			ClaimNamespace: req.Claim.Namespace,
+			ClaimUID:       req.Claim.UID,
+			CIDR:           addr.String(),
+			ExternalID:     externalID,
+		})
+
+		// The prefix is adopted on the next attempt if this fails
+		if err := patchAllocations(ctx, p.Client, block, allocations, block.Status.ReleasedPrefixes); err != nil {
+			return nil, err
+		}
+
+		return &Allocation{Block: client.ObjectKeyFromObject(block), CIDR: addr.String(), ExternalID: externalID}, nil
+	}
+
+	if requested != nil {
+		return nil, fmt.Errorf("requested CIDR %s is not available in NetBox for any matching CIDRBlock", requested)
+	}
+
+	return nil, fmt.Errorf("no available CIDRBlock")
+}
+
+// reserve reserves a prefix for the request in the parent prefix of the block in NetBox.
+// It returns nil if the parent prefix has no space for it.
+func (p *NetBoxProvider) reserve(
+	ctx context.Context,
+	req *AllocationRequest,
+	block *controlplanev1alpha1.CIDRBlock,
+	parent *netbox.Prefix,
+	requested *ipaddr.IPAddress,
+) (*netbox.Prefix, error) {
+	description := prefixDescription(req.Claim)
+
+	reserved, err := p.NetBox.ListPrefixes(ctx, url.Values{
+		"within":      {parent.Prefix},
+		"description": {description},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range reserved {
+		recorded := slices.ContainsFunc(block.Status.Allocations, func(a controlplanev1alpha1.CIDRBlockAllocation) bool {
+			return a.ExternalID == strconv.Itoa(r.ID)
+		})
+
+		if !recorded && accepts(req, requested, r.Prefix) {
+			return &r, nil
+		}
+	}
+
+	if requested != nil {
+		available, err := p.NetBox.AvailablePrefixes(ctx, parent.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range available {
+			if addr := ipaddr.NewIPAddressString(a.Prefix).GetAddress(); addr == nil || !addr.ToPrefixBlock().Contains(requested) {
+				continue
+			}
+
+			created, err := p.NetBox.CreatePrefix(ctx, &netbox.Prefix{
+				Prefix:      requested.String(),
+				Description: description,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return p.rollbackConflict(ctx, parent, created)
+		}
+
+		return nil, nil
+	}
+
+	blockSubnet := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+
+	for sizeBit := req.SizeBit; sizeBit >= req.MinSizeBit; sizeBit-- {
+		if sizeBit > prefixSizeBit(blockSubnet) || exceedsQuota(req.QuotaLimits, client.ObjectKeyFromObject(block), sizeBit) {
+			continue
+		}
+
+		prefix, err := p.NetBox.AllocatePrefix(ctx, parent.ID, blockSubnet.GetBitCount()-sizeBit, description)
+
+		if errors.Is(err, netbox.ErrInsufficientSpace) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return prefix, nil
+	}
+
+	return nil, nil
+}
+
+// rollbackConflict deletes the prefix created in the parent prefix and returns nil if another
+// prefix overlapping it was created concurrently. NetBox does not check that the prefixes created
+// directly are free unlike the ones allocated from the available prefixes, so the prefix is
+// checked after it is created.
+func (p *NetBoxProvider) rollbackConflict(ctx context.Context, parent, created *netbox.Prefix) (*netbox.Prefix, error) {
+	// The prefixes in the prefix and the ones containing it under the parent prefix
+	within, err := p.NetBox.ListPrefixes(ctx, url.Values{"within_include": {created.Prefix}})
+	if err == nil {
+		var containing []netbox.Prefix
+		containing, err = p.NetBox.ListPrefixes(ctx, url.Values{"within": {parent.Prefix}, "contains": {created.Prefix}})
+		within = append(within, containing...)
+	}
+
+	if err == nil && !slices.ContainsFunc(within, func(o netbox.Prefix) bool { return o.ID != created.ID }) {
+		return created, nil
+	}
+
+	if deleteErr := p.NetBox.DeletePrefix(ctx, created.ID); deleteErr != nil {
+		return nil, errors.Join(err, deleteErr)
+	}
+
+	return nil, err
+}
+
+// Release implements IPAMProvider. The prefixes are deleted from NetBox before they are
+// removed from the ledgers so that a failure does not leak them.
+func (p *NetBoxProvider) Release(ctx context.Context, claim *controlplanev1alpha1.CIDRClaim, keep map[types.NamespacedName][]string) error {
+	var cidrBlocks controlplanev1alpha1.CIDRBlockList
+	if err := p.List(ctx, &cidrBlocks); err != nil {
+		return fmt.Errorf("failed to list CIDRBlocks: %w", err)
+	}
+
+	for _, block := range cidrBlocks.Items {
+		for _, a := range block.Status.Allocations {
+			if !isAllocationOf(&a, claim) || a.ExternalID == "" || slices.Contains(keep[client.ObjectKeyFromObject(&block)], a.CIDR) {
+				continue
+			}
+
+			id, err := strconv.Atoi(a.ExternalID)
+			if err != nil {
+				return fmt.Errorf("external ID %q of %s in CIDRBlock %s is invalid: %w", a.ExternalID, a.CIDR, block.Name, err)
+			}
+
+			if err := p.NetBox.DeletePrefix(ctx, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The prefixes deleted from NetBox may be allocated again by NetBox at once,
+	// so they are neither retained nor quarantined in the ledgers
+	return releaseAllocations(ctx, p.Client, claim, keep, false)
+}
+
+// prefixDescription returns the description of the prefixes reserved for the claim in NetBox
+func prefixDescription(claim *controlplanev1alpha1.CIDRClaim) string {
+	return fmt.Sprintf("CIDRClaim %s/%s (%s)", claim.Namespace, claim.Name, claim.UID)
+}
+
+// accepts returns true if the prefix satisfies the request. requested is the parsed RequestedCIDR.
+func accepts(req *AllocationRequest, requested *ipaddr.IPAddress, cidr string) bool {
+	addr := ipaddr.NewIPAddressString(cidr).GetAddress()
+
+	if addr == nil {
+		return false
+	}
+
+	if requested != nil {
+		return requested.Equal(addr)
+	}
+
+	return req.acceptsSizeBit(prefixSizeBit(addr))
+}
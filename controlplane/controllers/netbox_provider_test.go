@@ -0,0 +1,295 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/netbox"
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/netbox/netboxtest"
+)
+
+func TestNetBoxProvider(t *testing.T) {
+	ctx := context.Background()
+
+	server := netboxtest.NewServer()
+	defer server.Close()
+	server.Token = "secret"
+	server.AddPrefix("192.168.1.0/28")
+
+	scheme := runtime.NewScheme()
+	if err := controlplanev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	block := &controlplanev1alpha1.CIDRBlock{
+		ObjectMeta: metav1.ObjectMeta{Name: "block", Namespace: "test"},
+		Spec: controlplanev1alpha1.CIDRBlockSpec{
+			CIDR:             "192.168.1.0/28",
+			QuarantinePeriod: &metav1.Duration{Duration: time.Hour},
+		},
+	}
+	unbacked := &controlplanev1alpha1.CIDRBlock{
+		ObjectMeta: metav1.ObjectMeta{Name: "unbacked", Namespace: "test"},
+		Spec:       controlplanev1alpha1.CIDRBlockSpec{CIDR: "192.168.2.0/28"},
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(block, unbacked).
+		WithStatusSubresource(block, unbacked).
+		Build()
+
+	nb := &netbox.Client{URL: server.URL, Token: "secret"}
+	provider := &NetBoxProvider{Client: c, NetBox: nb}
+
+	claim := func(name string) *controlplanev1alpha1.CIDRClaim {
+		return &controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", UID: types.UID(name + "-uid")},
+		}
+	}
+
+	allocate := func(claim *controlplanev1alpha1.CIDRClaim, sizeBit int, requestedCIDR string, blocks ...*controlplanev1alpha1.CIDRBlock) (*Allocation, error) {
+		req := &AllocationRequest{
+			Claim:         claim,
+			SizeBit:       sizeBit,
+			MinSizeBit:    sizeBit,
+			RequestedCIDR: requestedCIDR,
+		}
+
+		for _, b := range blocks {
+			var latest controlplanev1alpha1.CIDRBlock
+			if err := c.Get(ctx, client.ObjectKeyFromObject(b), &latest); err != nil {
+				t.Fatal(err)
+			}
+
+			req.Blocks = append(req.Blocks, latest)
+		}
+
+		return provider.Allocate(ctx, req)
+	}
+
+	first := claim("first")
+	allocated, err := allocate(first, 2, "", unbacked, block)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if allocated.CIDR != "192.168.1.0/30" || allocated.Block.Name != "block" || allocated.ExternalID == "" {
+		t.Fatalf("Allocate() = %+v, want 192.168.1.0/30 of block with an external ID", allocated)
+	}
+
+	var latest controlplanev1alpha1.CIDRBlock
+	if err := c.Get(ctx, client.ObjectKeyFromObject(block), &latest); err != nil {
+		t.Fatal(err)
+	}
+	if len(latest.Status.Allocations) != 1 || latest.Status.Allocations[0].ExternalID != allocated.ExternalID {
+		t.Errorf("allocations = %+v, want the external ID %s", latest.Status.Allocations, allocated.ExternalID)
+	}
+
+	// The allocation recorded in the ledger is reused
+	reused, err := allocate(first, 2, "", block)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if *reused != *allocated {
+		t.Errorf("Allocate() = %+v, want %+v", reused, allocated)
+	}
+
+	// A prefix reserved before a failed update of the ledger is adopted
+	orphan, err := nb.CreatePrefix(ctx, &netbox.Prefix{
+		Prefix:      "192.168.1.4/30",
+		Description: prefixDescription(claim("second")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adopted, err := allocate(claim("second"), 2, "", block)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if adopted.CIDR != "192.168.1.4/30" || adopted.ExternalID != strconv.Itoa(orphan.ID) {
+		t.Errorf("Allocate() = %+v, want the orphan %+v", adopted, orphan)
+	}
+
+	requested, err := allocate(claim("third"), 0, "192.168.1.12/30", block)
+	if err != nil {
+		t.Fatalf("Allocate() error = %v", err)
+	}
+	if requested.CIDR != "192.168.1.12/30" {
+		t.Errorf("Allocate() = %+v, want 192.168.1.12/30", requested)
+	}
+
+	if _, err := allocate(claim("fourth"), 0, "192.168.1.0/31", block); err == nil || !strings.Contains(err.Error(), "not available") {
+		t.Errorf("Allocate() of an allocated CIDR error = %v", err)
+	}
+
+	if _, err := allocate(claim("fourth"), 3, "", block); err == nil {
+		t.Error("Allocate() in the full block succeeded")
+	}
+
+	if got := len(server.Prefixes()); got != 4 {
+		t.Errorf("len(Prefixes()) = %d, want 4", got)
+	}
+
+	// The prefix freed in NetBox is neither retained nor quarantined in the ledger
+	now := metav1.Now()
+	first.DeletionTimestamp = &now
+	first.Spec.ReclaimPolicy = controlplanev1alpha1.ReclaimPolicyRetain
+
+	if err := provider.Release(ctx, first, nil); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	for _, p := range server.Prefixes() {
+		if p.Prefix == allocated.CIDR {
+			t.Errorf("%s is not deleted from NetBox", allocated.CIDR)
+		}
+	}
+
+	if err := c.Get(ctx, client.ObjectKeyFromObject(block), &latest); err != nil {
+		t.Fatal(err)
+	}
+	if len(latest.Status.Allocations) != 2 {
+		t.Errorf("allocations = %+v, want the ones of the other claims", latest.Status.Allocations)
+	}
+	if len(latest.Status.ReleasedPrefixes) != 0 {
+		t.Errorf("released prefixes = %+v, want none", latest.Status.ReleasedPrefixes)
+	}
+}
+
+func TestNetBoxProviderReleaseOrphans(t *testing.T) {
+	ctx := context.Background()
+
+	server := netboxtest.NewServer()
+	defer server.Close()
+	server.AddPrefix("192.168.1.0/28")
+
+	scheme := runtime.NewScheme()
+	if err := controlplanev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	nb := &netbox.Client{URL: server.URL}
+
+	var ids []string
+	for _, cidr := range []string{"192.168.1.0/30", "192.168.1.4/30"} {
+		p, err := nb.CreatePrefix(ctx, &netbox.Prefix{Prefix: cidr})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, strconv.Itoa(p.ID))
+	}
+
+	block := &controlplanev1alpha1.CIDRBlock{
+		ObjectMeta: metav1.ObjectMeta{Name: "block", Namespace: "test"},
+		Spec:       controlplanev1alpha1.CIDRBlockSpec{CIDR: "192.168.1.0/28"},
+		Status: controlplanev1alpha1.CIDRBlockStatus{
+			Allocations: []controlplanev1alpha1.CIDRBlockAllocation{
+				{ClaimName: "bound", ClaimNamespace: "test", ClaimUID: "bound-uid", CIDR: "192.168.1.0/30", ExternalID: ids[0]},
+				{ClaimName: "deleted", ClaimNamespace: "test", ClaimUID: "deleted-uid", CIDR: "192.168.1.4/30", ExternalID: ids[1]},
+				{ClaimName: "legacy", ClaimNamespace: "test", ClaimUID: "legacy-uid", CIDR: "192.168.1.8/30"},
+			},
+		},
+	}
+	bound := controlplanev1alpha1.CIDRClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "bound", Namespace: "test", UID: "bound-uid"},
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(block).
+		WithStatusSubresource(block).
+		Build()
+
+	provider := &NetBoxProvider{Client: c, NetBox: nb}
+
+	orphans := orphanedClaims(block, []controlplanev1alpha1.CIDRClaim{bound})
+	if len(orphans) != 1 || orphans[0].Name != "deleted" || orphans[0].UID != "deleted-uid" {
+		t.Fatalf("orphanedClaims() = %+v, want the deleted claim", orphans)
+	}
+
+	for i := range orphans {
+		if err := provider.Release(ctx, &orphans[i], nil); err != nil {
+			t.Fatalf("Release() error = %v", err)
+		}
+	}
+
+	for _, p := range server.Prefixes() {
+		if p.Prefix == "192.168.1.4/30" {
+			t.Error("the prefix of the deleted claim is not deleted from NetBox")
+		}
+	}
+
+	var latest controlplanev1alpha1.CIDRBlock
+	if err := c.Get(ctx, client.ObjectKeyFromObject(block), &latest); err != nil {
+		t.Fatal(err)
+	}
+	if len(latest.Status.Allocations) != 2 || latest.Status.Allocations[0].ExternalID != ids[0] {
+		t.Errorf("allocations = %+v, want the ones of the bound and legacy claims", latest.Status.Allocations)
+	}
+}
+
+func TestNetBoxProviderRollbackConflict(t *testing.T) {
+	ctx := context.Background()
+
+	server := netboxtest.NewServer()
+	defer server.Close()
+	parentID := server.AddPrefix("192.168.1.0/24")
+
+	nb := &netbox.Client{URL: server.URL}
+	provider := &NetBoxProvider{NetBox: nb}
+	parent := &netbox.Prefix{ID: parentID, Prefix: "192.168.1.0/24"}
+
+	create := func(cidr string) *netbox.Prefix {
+		p, err := nb.CreatePrefix(ctx, &netbox.Prefix{Prefix: cidr})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return p
+	}
+
+	free := create("192.168.1.0/30")
+	if got, err := provider.rollbackConflict(ctx, parent, free); err != nil || got == nil || got.ID != free.ID {
+		t.Errorf("rollbackConflict() = %+v, %v, want %+v", got, err, free)
+	}
+
+	tests := []struct {
+		name     string
+		existing string
+		created  string
+	}{
+		{name: "same prefix", existing: "192.168.1.4/30", created: "192.168.1.4/30"},
+		{name: "containing prefix", existing: "192.168.1.16/28", created: "192.168.1.20/30"},
+		{name: "contained prefix", existing: "192.168.1.36/30", created: "192.168.1.32/28"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			create(tt.existing)
+			created := create(tt.created)
+
+			got, err := provider.rollbackConflict(ctx, parent, created)
+			if err != nil || got != nil {
+				t.Errorf("rollbackConflict() = %+v, %v, want nil", got, err)
+			}
+
+			for _, p := range server.Prefixes() {
+				if p.ID == created.ID {
+					t.Errorf("%s is not deleted from NetBox", tt.created)
+				}
+			}
+		})
+	}
+}
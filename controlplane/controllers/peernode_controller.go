@@ -18,10 +18,19 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
 )
@@ -30,29 +39,160 @@ import (
 type PeerNodeReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// OfflineTimeout is the duration without heartbeats after which the PeerNode is marked offline.
+	// Zero disables it.
+	OfflineTimeout time.Duration
+
+	// DeletionTimeout is the duration without heartbeats after which the PeerNode
+	// and the CIDRClaims owned by it are deleted. Zero disables it.
+	DeletionTimeout time.Duration
 }
 
 //+kubebuilder:rbac:groups=controlplane.miscord.win,resources=peernodes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=controlplane.miscord.win,resources=peernodes/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=controlplane.miscord.win,resources=peernodes/finalizers,verbs=update
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrclaims,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the PeerNode object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.0/pkg/reconcile
+// Reconcile tracks the liveness of the PeerNode with the Lease renewed by the node.
+// A PeerNode is marked offline after OfflineTimeout without renewals, and deleted
+// with the CIDRClaims owned by it after DeletionTimeout.
+// PeerNodes without a Lease controlled by them are left untouched since the node may not send heartbeats.
 func (r *PeerNodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	return ctrl.Result{}, nil
+	logger := log.FromContext(ctx)
+
+	var peerNode controlplanev1alpha1.PeerNode
+
+	err := r.Get(ctx, req.NamespacedName, &peerNode)
+
+	if errors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get PeerNode: %w", err)
+	}
+
+	if peerNode.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	var lease coordinationv1.Lease
+
+	err = r.Get(ctx, req.NamespacedName, &lease)
+
+	if errors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get Lease: %w", err)
+	}
+
+	// A Lease of the same name not controlled by the PeerNode is not its heartbeat
+	if !metav1.IsControlledBy(&lease, &peerNode) {
+		return ctrl.Result{}, nil
+	}
+
+	renewTime := lease.CreationTimestamp.Time
+	if lease.Spec.RenewTime != nil {
+		renewTime = lease.Spec.RenewTime.Time
+	}
+	elapsed := time.Since(renewTime)
+
+	if r.DeletionTimeout > 0 && elapsed >= r.DeletionTimeout {
+		logger.Info("deleting PeerNode without heartbeats", "lastRenewTime", renewTime)
+
+		if err := r.deletePeerNode(ctx, &peerNode); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	state := controlplanev1alpha1.PeerNodeStatusStateOnline
+	message := ""
+	if r.OfflineTimeout > 0 && elapsed >= r.OfflineTimeout {
+		state = controlplanev1alpha1.PeerNodeStatusStateOffline
+		message = fmt.Sprintf("no heartbeat since %s", renewTime.Format(time.RFC3339))
+	}
+
+	var requeueAfter time.Duration
+	switch {
+	case state == controlplanev1alpha1.PeerNodeStatusStateOnline && r.OfflineTimeout > 0:
+		requeueAfter = r.OfflineTimeout - elapsed
+	case r.DeletionTimeout > 0:
+		requeueAfter = r.DeletionTimeout - elapsed
+	}
+
+	if peerNode.Status.State != state || peerNode.Status.Message != message {
+		updated := peerNode.DeepCopy()
+		updated.Status.ObservedGeneration = peerNode.Generation
+		updated.Status.State = state
+		updated.Status.Message = message
+
+		if err := r.Status().Patch(ctx, updated, client.MergeFrom(&peerNode)); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+func (r *PeerNodeReconciler) deletePeerNode(ctx context.Context, peerNode *controlplanev1alpha1.PeerNode) error {
+	var cidrClaims controlplanev1alpha1.CIDRClaimList
+	if err := r.List(ctx, &cidrClaims, &client.ListOptions{
+		Namespace: peerNode.Namespace,
+	}); err != nil {
+		return fmt.Errorf("failed to list CIDRClaims: %w", err)
+	}
+
+	for i := range cidrClaims.Items {
+		if !isOwnedBy(&cidrClaims.Items[i], peerNode) {
+			continue
+		}
+
+		if err := r.Delete(ctx, &cidrClaims.Items[i]); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete CIDRClaim %s: %w", cidrClaims.Items[i].Name, err)
+		}
+	}
+
+	if err := r.Delete(ctx, peerNode, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete PeerNode: %w", err)
+	}
+
+	return nil
+}
+
+func isOwnedBy(obj client.Object, owner client.Object) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PeerNodeLeaseCache returns the cache options limiting the Leases cached by the manager to the
+// ones renewed by the nodes. Otherwise, all the Leases in the cluster are cached to watch them.
+func PeerNodeLeaseCache() map[client.Object]cache.ByObject {
+	requirement, err := labels.NewRequirement(controlplanev1alpha1.PeerNodeLeaseLabelKey, selection.Exists, nil)
+	if err != nil {
+		// The key is a valid constant
+		panic(err)
+	}
+
+	return map[client.Object]cache.ByObject{
+		&coordinationv1.Lease{}: {Label: labels.NewSelector().Add(*requirement)},
+	}
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// The manager should be created with PeerNodeLeaseCache.
 func (r *PeerNodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&controlplanev1alpha1.PeerNode{}).
 		Owns(&controlplanev1alpha1.CIDRClaim{}).
+		Owns(&coordinationv1.Lease{}).
 		Complete(r)
 }
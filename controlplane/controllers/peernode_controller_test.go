@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+var _ = Describe("PeerNode", func() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+
+		deleteAll(ctx)
+
+		scheme := scheme.Scheme
+
+		mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+			Scheme: scheme,
+			Cache:  cache.Options{ByObject: PeerNodeLeaseCache()},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = (&CIDRClaimReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   scheme,
+			Recorder: mgr.GetEventRecorder("cidrclaim-controller"),
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = (&PeerNodeReconciler{
+			Client:          mgr.GetClient(),
+			Scheme:          scheme,
+			OfflineTimeout:  time.Minute,
+			DeletionTimeout: time.Hour,
+		}).SetupWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		go func() {
+			err := mgr.Start(ctx)
+
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		cancel()
+
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	createPeerNode := func(renewTime time.Time) *controlplanev1alpha1.PeerNode {
+		peerNode := &controlplanev1alpha1.PeerNode{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "peer-node",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.PeerNodeSpec{
+				Endpoints: []string{},
+			},
+		}
+
+		err := k8sClient.Create(ctx, peerNode)
+		Expect(err).NotTo(HaveOccurred())
+
+		holder := "node"
+		durationSeconds := int32(40)
+		microTime := v1.NewMicroTime(renewTime)
+		lease := &coordinationv1.Lease{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      peerNode.Name,
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					controlplanev1alpha1.PeerNodeLeaseLabelKey: peerNode.Name,
+				},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: &durationSeconds,
+				RenewTime:            &microTime,
+			},
+		}
+		err = controllerutil.SetControllerReference(peerNode, lease, scheme.Scheme)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = k8sClient.Create(ctx, lease)
+		Expect(err).NotTo(HaveOccurred())
+
+		return peerNode
+	}
+
+	It("Mark PeerNode online and offline", func() {
+		peerNode := createPeerNode(time.Now())
+
+		peerNodeKey := client.ObjectKeyFromObject(peerNode)
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, peerNodeKey, peerNode)
+
+			if err != nil {
+				return err
+			}
+
+			if peerNode.Status.State != controlplanev1alpha1.PeerNodeStatusStateOnline {
+				return fmt.Errorf("not online: %s", peerNode.Status.State)
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		var lease coordinationv1.Lease
+		err := k8sClient.Get(ctx, peerNodeKey, &lease)
+		Expect(err).NotTo(HaveOccurred())
+
+		renewTime := v1.NewMicroTime(time.Now().Add(-2 * time.Minute))
+		lease.Spec.RenewTime = &renewTime
+		err = k8sClient.Update(ctx, &lease)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() error {
+			err := k8sClient.Get(ctx, peerNodeKey, peerNode)
+
+			if err != nil {
+				return err
+			}
+
+			if peerNode.Status.State != controlplanev1alpha1.PeerNodeStatusStateOffline {
+				return fmt.Errorf("not offline: %s", peerNode.Status.State)
+			}
+
+			return nil
+		}).Should(Succeed())
+
+		Expect(peerNode.Status.Message).To(HavePrefix("no heartbeat since"))
+	})
+
+	It("Delete dead PeerNode with its CIDRClaims", func() {
+		peerNode := createPeerNode(time.Now().Add(-2 * time.Hour))
+
+		cidrClaim := controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-claim",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+			},
+		}
+		err := controllerutil.SetOwnerReference(peerNode, &cidrClaim, scheme.Scheme)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = k8sClient.Create(ctx, &cidrClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(peerNode), peerNode)
+
+			return errors.IsNotFound(err)
+		}).Should(BeTrue())
+
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(&cidrClaim), &cidrClaim)
+
+			return errors.IsNotFound(err)
+		}).Should(BeTrue())
+	})
+})
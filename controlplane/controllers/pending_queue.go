@@ -0,0 +1,361 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+// pendingRetryInterval is how long a CIDRClaim waits for the older claims enqueued before it
+const pendingRetryInterval = time.Second
+
+// pendingClaim is a CIDRClaim waiting for capacity
+type pendingClaim struct {
+	key     types.NamespacedName
+	created time.Time
+}
+
+// pendingSelector is the FIFO queue of the claims waiting for CIDRBlocks matching the selector
+type pendingSelector struct {
+	selector labels.Selector
+	claims   []pendingClaim
+}
+
+// pendingQueue keeps the CIDRClaims which failed to be bound in FIFO queues per block selector.
+// When capacity may have been freed in a CIDRBlock, the claims waiting for the blocks matching
+// it are enqueued in the order of their creation. The claims created later are not bound until
+// the enqueued ones are reconciled so that the longest-waiting claim is bound first.
+type pendingQueue struct {
+	mu sync.Mutex
+
+	// selectors maps a namespace to the queues per the string representation of selectors
+	selectors map[string]map[string]*pendingSelector
+	// claims maps a CIDRClaim to the selectors it waits for
+	claims map[types.NamespacedName][]string
+	// woken are the waiting claims enqueued for freed capacity which have not been reconciled since
+	woken map[types.NamespacedName]bool
+}
+
+func newPendingQueue() *pendingQueue {
+	return &pendingQueue{
+		selectors: map[string]map[string]*pendingSelector{},
+		claims:    map[types.NamespacedName][]string{},
+		woken:     map[types.NamespacedName]bool{},
+	}
+}
+
+// add puts the claim into the queues of the selectors. A claim already waiting keeps its position.
+func (q *pendingQueue) add(claim *controlplanev1alpha1.CIDRClaim, selectors []labels.Selector) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name}
+
+	// The claim failed again after it was woken
+	delete(q.woken, key)
+
+	waiting := make(map[string]bool, len(q.claims[key]))
+	for _, s := range q.claims[key] {
+		waiting[s] = true
+	}
+
+	queues, ok := q.selectors[claim.Namespace]
+	if !ok {
+		queues = map[string]*pendingSelector{}
+		q.selectors[claim.Namespace] = queues
+	}
+
+	entry := pendingClaim{
+		key:     key,
+		created: claim.CreationTimestamp.Time,
+	}
+
+	for _, selector := range selectors {
+		s := selector.String()
+
+		if waiting[s] {
+			continue
+		}
+		waiting[s] = true
+
+		queue, ok := queues[s]
+		if !ok {
+			queue = &pendingSelector{selector: selector}
+			queues[s] = queue
+		}
+
+		i := sort.Search(len(queue.claims), func(i int) bool {
+			return entry.before(&queue.claims[i])
+		})
+		queue.claims = append(queue.claims, pendingClaim{})
+		copy(queue.claims[i+1:], queue.claims[i:])
+		queue.claims[i] = entry
+
+		q.claims[key] = append(q.claims[key], s)
+	}
+}
+
+// remove drops the claim from all the queues
+func (q *pendingQueue) remove(key types.NamespacedName) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queues := q.selectors[key.Namespace]
+	for _, s := range q.claims[key] {
+		queue, ok := queues[s]
+		if !ok {
+			continue
+		}
+
+		for i := range queue.claims {
+			if queue.claims[i].key == key {
+				queue.claims = append(queue.claims[:i], queue.claims[i+1:]...)
+
+				break
+			}
+		}
+
+		if len(queue.claims) == 0 {
+			delete(queues, s)
+		}
+	}
+
+	if len(queues) == 0 {
+		delete(q.selectors, key.Namespace)
+	}
+	delete(q.claims, key)
+	delete(q.woken, key)
+}
+
+// wake marks the claim as enqueued for freed capacity
+func (q *pendingQueue) wake(key types.NamespacedName) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.claims[key]; ok {
+		q.woken[key] = true
+	}
+}
+
+// ahead returns the woken claims created before the claim which wait for any of the blocks.
+// The claim should not be bound until they are reconciled.
+func (q *pendingQueue) ahead(claim *controlplanev1alpha1.CIDRClaim, blocks []controlplanev1alpha1.CIDRBlock) []types.NamespacedName {
+	entry := pendingClaim{
+		key:     types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name},
+		created: claim.CreationTimestamp.Time,
+	}
+
+	var keys []types.NamespacedName
+	for i := range blocks {
+		for _, c := range q.waitingClaims(&blocks[i]) {
+			if q.isWoken(c.key) && c.before(&entry) && !slices.Contains(keys, c.key) {
+				keys = append(keys, c.key)
+			}
+		}
+	}
+
+	return keys
+}
+
+func (q *pendingQueue) isWoken(key types.NamespacedName) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.woken[key]
+}
+
+// waiting returns the claims waiting for blocks matching the block in the order of their creation.
+// Claims in all namespaces are returned for blocks shared across namespaces.
+func (q *pendingQueue) waiting(block *controlplanev1alpha1.CIDRBlock) []types.NamespacedName {
+	return pendingKeys(q.waitingClaims(block))
+}
+
+func (q *pendingQueue) waitingClaims(block *controlplanev1alpha1.CIDRBlock) []pendingClaim {
+	namespace := block.Namespace
+	if block.Spec.NamespaceSelector != nil {
+		namespace = metav1.NamespaceAll
+	}
+
+	return q.waitingFor(namespace, func(selector labels.Selector) bool {
+		return selector.Matches(labels.Set(block.Labels))
+	})
+}
+
+// waitingFor returns the claims in the queues of the selectors accepted by match in the order of their creation.
+// The queues in all namespaces are searched if namespace is empty.
+func (q *pendingQueue) waitingFor(namespace string, match func(labels.Selector) bool) []pendingClaim {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var claims []pendingClaim
+	found := map[types.NamespacedName]bool{}
+	for ns, queues := range q.selectors {
+		if namespace != metav1.NamespaceAll && ns != namespace {
+			continue
+		}
+
+		for _, queue := range queues {
+			if !match(queue.selector) {
+				continue
+			}
+
+			for _, c := range queue.claims {
+				if found[c.key] {
+					continue
+				}
+				found[c.key] = true
+
+				claims = append(claims, c)
+			}
+		}
+	}
+
+	sort.Slice(claims, func(i, j int) bool {
+		return claims[i].before(&claims[j])
+	})
+
+	return claims
+}
+
+// waitingIn returns all the claims waiting in the namespace in the order of their creation
+func (q *pendingQueue) waitingIn(namespace string) []types.NamespacedName {
+	return pendingKeys(q.waitingFor(namespace, func(labels.Selector) bool { return true }))
+}
+
+func pendingKeys(claims []pendingClaim) []types.NamespacedName {
+	keys := make([]types.NamespacedName, 0, len(claims))
+	for _, c := range claims {
+		keys = append(keys, c.key)
+	}
+
+	return keys
+}
+
+func (c *pendingClaim) before(other *pendingClaim) bool {
+	if !c.created.Equal(other.created) {
+		return c.created.Before(other.created)
+	}
+
+	return c.key.String() < other.key.String()
+}
+
+// eventHandler returns a handler of CIDRBlock events which enqueues the waiting claims when
+// the block is created, relabelled or may have freed capacity.
+func (q *pendingQueue) eventHandler() handler.EventHandler {
+	enqueue := func(block *controlplanev1alpha1.CIDRBlock, wq workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+		for _, key := range q.waiting(block) {
+			q.wake(key)
+			wq.Add(reconcile.Request{NamespacedName: key})
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, wq workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if block, ok := e.Object.(*controlplanev1alpha1.CIDRBlock); ok {
+				enqueue(block, wq)
+			}
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, wq workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			oldBlock, ok := e.ObjectOld.(*controlplanev1alpha1.CIDRBlock)
+			if !ok {
+				return
+			}
+			block, ok := e.ObjectNew.(*controlplanev1alpha1.CIDRBlock)
+			if !ok {
+				return
+			}
+
+			if mayHaveFreed(oldBlock, block) {
+				enqueue(block, wq)
+			}
+		},
+	}
+}
+
+// quotaEventHandler returns a handler of CIDRQuota events which enqueues all the waiting
+// claims in the namespace when a quota is created, changed or deleted
+func (q *pendingQueue) quotaEventHandler() handler.EventHandler {
+	enqueue := func(namespace string, wq workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+		for _, key := range q.waitingIn(namespace) {
+			q.wake(key)
+			wq.Add(reconcile.Request{NamespacedName: key})
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, wq workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(e.Object.GetNamespace(), wq)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, wq workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() {
+				enqueue(e.ObjectNew.GetNamespace(), wq)
+			}
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, wq workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(e.Object.GetNamespace(), wq)
+		},
+	}
+}
+
+// mayHaveFreed returns true if the update of the block may let waiting claims be bound
+func mayHaveFreed(old, block *controlplanev1alpha1.CIDRBlock) bool {
+	switch {
+	case !equality.Semantic.DeepEqual(old.Labels, block.Labels):
+		return true
+	case old.Generation != block.Generation:
+		return true
+	case len(block.Status.Allocations) < len(old.Status.Allocations):
+		return true
+	case len(block.Status.ReleasedPrefixes) < len(old.Status.ReleasedPrefixes):
+		return true
+	case len(block.Status.ReservedCIDRs) < len(old.Status.ReservedCIDRs):
+		return true
+	}
+
+	return false
+}
+
+// pendingSelectors returns the selectors of the requests of the claim
+func pendingSelectors(requests []allocationRequest) []labels.Selector {
+	selectors := make([]labels.Selector, 0, len(requests))
+	for _, request := range requests {
+		selector, err := metav1.LabelSelectorAsSelector(&request.selector)
+
+		if err != nil {
+			continue
+		}
+
+		selectors = append(selectors, selector)
+	}
+
+	return selectors
+}
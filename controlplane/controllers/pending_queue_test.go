@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func TestPendingQueue(t *testing.T) {
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	claim := func(name string, age time.Duration) *controlplanev1alpha1.CIDRClaim {
+		return &controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: v1.NewTime(base.Add(-age)),
+			},
+		}
+	}
+
+	block := func(l map[string]string) *controlplanev1alpha1.CIDRBlock {
+		return &controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "block",
+				Namespace: "default",
+				Labels:    l,
+			},
+		}
+	}
+
+	names := func(keys []types.NamespacedName) []string {
+		var names []string
+		for _, k := range keys {
+			names = append(names, k.Name)
+		}
+
+		return names
+	}
+
+	v4 := labels.SelectorFromSet(labels.Set{"type": "v4"})
+	zoneA := labels.SelectorFromSet(labels.Set{"type": "v4", "zone": "a"})
+
+	q := newPendingQueue()
+	q.add(claim("newest", 1*time.Minute), []labels.Selector{v4})
+	q.add(claim("oldest", 3*time.Minute), []labels.Selector{zoneA})
+	q.add(claim("middle", 2*time.Minute), []labels.Selector{v4, zoneA})
+	q.add(claim("middle", 2*time.Minute), []labels.Selector{v4})
+
+	if got, expected := names(q.waiting(block(map[string]string{"type": "v4", "zone": "a"}))), []string{"oldest", "middle", "newest"}; !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got, expected := names(q.waiting(block(map[string]string{"type": "v4"}))), []string{"middle", "newest"}; !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := q.waiting(block(map[string]string{"type": "v6"})); len(got) != 0 {
+		t.Errorf("expected no claims, got %v", got)
+	}
+
+	q.remove(types.NamespacedName{Namespace: "default", Name: "middle"})
+	q.remove(types.NamespacedName{Namespace: "default", Name: "unknown"})
+
+	if got, expected := names(q.waiting(block(map[string]string{"type": "v4", "zone": "a"}))), []string{"oldest", "newest"}; !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// Only the woken claims created earlier go ahead of a claim
+	zoneABlocks := []controlplanev1alpha1.CIDRBlock{*block(map[string]string{"type": "v4", "zone": "a"})}
+	if got := q.ahead(claim("new", 0), zoneABlocks); len(got) != 0 {
+		t.Errorf("expected no claims ahead before waking, got %v", got)
+	}
+
+	q.wake(types.NamespacedName{Namespace: "default", Name: "oldest"})
+	q.wake(types.NamespacedName{Namespace: "default", Name: "newest"})
+	q.wake(types.NamespacedName{Namespace: "default", Name: "unknown"})
+
+	if got, expected := names(q.ahead(claim("new", 0), zoneABlocks)), []string{"oldest", "newest"}; !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got, expected := names(q.ahead(claim("newest", 1*time.Minute), zoneABlocks)), []string{"oldest"}; !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := q.ahead(claim("new", 0), []controlplanev1alpha1.CIDRBlock{*block(map[string]string{"type": "v6"})}); len(got) != 0 {
+		t.Errorf("expected no claims ahead for other blocks, got %v", got)
+	}
+
+	// A woken claim which failed again no longer goes ahead
+	q.add(claim("oldest", 3*time.Minute), []labels.Selector{zoneA})
+	if got, expected := names(q.ahead(claim("new", 0), zoneABlocks)), []string{"newest"}; !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	q.remove(types.NamespacedName{Namespace: "default", Name: "oldest"})
+	q.remove(types.NamespacedName{Namespace: "default", Name: "newest"})
+
+	if len(q.selectors) != 0 || len(q.claims) != 0 || len(q.woken) != 0 {
+		t.Errorf("expected the queue to be empty, got %v, %v, %v", q.selectors, q.claims, q.woken)
+	}
+}
+
+func TestMayHaveFreed(t *testing.T) {
+	old := &controlplanev1alpha1.CIDRBlock{
+		ObjectMeta: v1.ObjectMeta{
+			Labels:     map[string]string{"type": "v4"},
+			Generation: 1,
+		},
+		Status: controlplanev1alpha1.CIDRBlockStatus{
+			Allocations: []controlplanev1alpha1.CIDRBlockAllocation{
+				{ClaimName: "claim", CIDR: "192.168.1.0/28"},
+			},
+		},
+	}
+
+	if mayHaveFreed(old, old.DeepCopy()) {
+		t.Errorf("an unchanged block must not free capacity")
+	}
+
+	allocated := old.DeepCopy()
+	allocated.Status.Allocations = append(allocated.Status.Allocations, controlplanev1alpha1.CIDRBlockAllocation{
+		ClaimName: "other", CIDR: "192.168.1.16/28",
+	})
+	if mayHaveFreed(old, allocated) {
+		t.Errorf("an allocation must not free capacity")
+	}
+
+	released := old.DeepCopy()
+	released.Status.Allocations = nil
+	if !mayHaveFreed(old, released) {
+		t.Errorf("a release must free capacity")
+	}
+
+	relabelled := old.DeepCopy()
+	relabelled.Labels["zone"] = "a"
+	if !mayHaveFreed(old, relabelled) {
+		t.Errorf("relabelling must enqueue the waiting claims")
+	}
+}
+
+func TestQuotaEventHandler(t *testing.T) {
+	q := newPendingQueue()
+	q.add(&controlplanev1alpha1.CIDRClaim{
+		ObjectMeta: v1.ObjectMeta{Name: "waiting", Namespace: "default"},
+	}, []labels.Selector{labels.Everything()})
+
+	wq := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer wq.ShutDown()
+
+	quota := &controlplanev1alpha1.CIDRQuota{
+		ObjectMeta: v1.ObjectMeta{Name: "quota", Namespace: "default"},
+	}
+	q.quotaEventHandler().Create(context.Background(), event.CreateEvent{Object: quota}, wq)
+
+	if wq.Len() != 1 {
+		t.Fatalf("expected the waiting claim to be enqueued, got %d requests", wq.Len())
+	}
+
+	key := types.NamespacedName{Namespace: "default", Name: "waiting"}
+	if req, _ := wq.Get(); req.NamespacedName != key {
+		t.Errorf("expected %v, got %v", key, req)
+	}
+	if !q.woken[key] {
+		t.Errorf("expected the claim to be woken")
+	}
+}
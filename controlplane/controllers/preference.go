@@ -0,0 +1,121 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+// ownerPeerNodeLabels returns the labels of the PeerNode owning the claim or nil
+func ownerPeerNodeLabels(ctx context.Context, c client.Reader, claim *controlplanev1alpha1.CIDRClaim) (map[string]string, error) {
+	for _, ref := range claim.OwnerReferences {
+		if ref.Kind != "PeerNode" || ref.APIVersion != controlplanev1alpha1.GroupVersion.String() {
+			continue
+		}
+
+		var peerNode controlplanev1alpha1.PeerNode
+		err := c.Get(ctx, types.NamespacedName{
+			Namespace: claim.Namespace,
+			Name:      ref.Name,
+		}, &peerNode)
+
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get PeerNode %s: %w", ref.Name, err)
+		}
+
+		return peerNode.Labels, nil
+	}
+
+	return nil, nil
+}
+
+// preferenceSelector returns the selector of the term with the labels copied from the
+// PeerNode. It returns nil if the term matches nothing.
+func preferenceSelector(term *controlplanev1alpha1.PreferredCIDRBlockTerm, peerNodeLabels map[string]string) labels.Selector {
+	preference := term.Preference.DeepCopy()
+
+	for _, key := range term.PeerNodeLabelKeys {
+		value, ok := peerNodeLabels[key]
+
+		if !ok {
+			return nil
+		}
+
+		preference.MatchExpressions = append(preference.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      key,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   []string{value},
+		})
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(preference)
+
+	if err != nil {
+		return nil
+	}
+
+	return selector
+}
+
+// sortByPreference sorts the blocks in the descending order of the sum of the weights of
+// the matching terms. Blocks with the same score are sorted by their names and namespaces so
+// that the allocation is deterministic.
+func sortByPreference(
+	blocks []controlplanev1alpha1.CIDRBlock,
+	preferred []controlplanev1alpha1.PreferredCIDRBlockTerm,
+	peerNodeLabels map[string]string,
+) {
+	selectors := make([]labels.Selector, len(preferred))
+	for i := range preferred {
+		selectors[i] = preferenceSelector(&preferred[i], peerNodeLabels)
+	}
+
+	scores := make(map[types.NamespacedName]int64, len(blocks))
+	for _, block := range blocks {
+		for i, selector := range selectors {
+			if selector != nil && selector.Matches(labels.Set(block.Labels)) {
+				scores[client.ObjectKeyFromObject(&block)] += int64(preferred[i].Weight)
+			}
+		}
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		ki, kj := client.ObjectKeyFromObject(&blocks[i]), client.ObjectKeyFromObject(&blocks[j])
+
+		if si, sj := scores[ki], scores[kj]; si != sj {
+			return si > sj
+		}
+		if ki.Name != kj.Name {
+			return ki.Name < kj.Name
+		}
+
+		return ki.Namespace < kj.Namespace
+	})
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"slices"
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func TestSortByPreference(t *testing.T) {
+	block := func(name string, labels map[string]string) controlplanev1alpha1.CIDRBlock {
+		return controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+			},
+		}
+	}
+
+	blocks := []controlplanev1alpha1.CIDRBlock{
+		block("shared-b", map[string]string{"shared": "true"}),
+		block("zone-b", map[string]string{"topology.kubernetes.io/zone": "b"}),
+		block("shared-a", map[string]string{"shared": "true"}),
+		block("zone-a", map[string]string{"topology.kubernetes.io/zone": "a"}),
+		block("other", nil),
+	}
+
+	preferred := []controlplanev1alpha1.PreferredCIDRBlockTerm{
+		{
+			Weight:            100,
+			PeerNodeLabelKeys: []string{"topology.kubernetes.io/zone"},
+		},
+		{
+			Weight: 10,
+			Preference: v1.LabelSelector{
+				MatchLabels: map[string]string{"shared": "true"},
+			},
+		},
+	}
+
+	names := func() []string {
+		var names []string
+		for _, b := range blocks {
+			names = append(names, b.Name)
+		}
+
+		return names
+	}
+
+	sortByPreference(blocks, preferred, map[string]string{"topology.kubernetes.io/zone": "b"})
+
+	expected := []string{"zone-b", "shared-a", "shared-b", "other", "zone-a"}
+	if got := names(); !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// The term copying the labels of the PeerNode matches nothing without the PeerNode
+	sortByPreference(blocks, preferred, nil)
+
+	expected = []string{"shared-a", "shared-b", "other", "zone-a", "zone-b"}
+	if got := names(); !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+// listQuotas returns the CIDRQuotas in the namespace
+func listQuotas(ctx context.Context, c client.Client, namespace string) ([]controlplanev1alpha1.CIDRQuota, error) {
+	var quotas controlplanev1alpha1.CIDRQuotaList
+	if err := c.List(ctx, &quotas, &client.ListOptions{
+		Namespace: namespace,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list CIDRQuotas: %w", err)
+	}
+
+	return quotas.Items, nil
+}
+
+// allocationsIn returns the allocations of the claims in the namespace among the allocations of the block
+func allocationsIn(
+	block *controlplanev1alpha1.CIDRBlock,
+	allocations []controlplanev1alpha1.CIDRBlockAllocation,
+	namespace string,
+) []controlplanev1alpha1.CIDRBlockAllocation {
+	filtered := make([]controlplanev1alpha1.CIDRBlockAllocation, 0, len(allocations))
+	for _, a := range allocations {
+		if allocationClaimKey(block, &a).Namespace == namespace {
+			filtered = append(filtered, a)
+		}
+	}
+
+	return filtered
+}
+
+// quotaSelects returns true if the quota applies to the block
+func quotaSelects(quota *controlplanev1alpha1.CIDRQuota, block *controlplanev1alpha1.CIDRBlock) bool {
+	if quota.Spec.Selector == nil {
+		return true
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(quota.Spec.Selector)
+
+	if err != nil {
+		return false
+	}
+
+	return selector.Matches(labels.Set(block.Labels))
+}
+
+// quotaUsage is the number of claims and addresses counted against a CIDRQuota
+type quotaUsage struct {
+	claims    map[types.UID]bool
+	addresses *big.Int
+}
+
+func newQuotaUsage() *quotaUsage {
+	return &quotaUsage{
+		claims:    map[types.UID]bool{},
+		addresses: big.NewInt(0),
+	}
+}
+
+// add counts the allocation
+func (u *quotaUsage) add(a *controlplanev1alpha1.CIDRBlockAllocation) {
+	addr := ipaddr.NewIPAddressString(a.CIDR).GetAddress()
+
+	if addr == nil {
+		return
+	}
+
+	u.claims[a.ClaimUID] = true
+	u.addresses.Add(u.addresses, addr.GetCount())
+}
+
+// maxSizeBit returns the largest size of a new allocation for a claim within the quota.
+// It returns false if the quota allows no more allocations.
+func (u *quotaUsage) maxSizeBit(quota *controlplanev1alpha1.CIDRQuota) (int, bool) {
+	if quota.Spec.MaxClaims != nil && int64(len(u.claims))+1 > *quota.Spec.MaxClaims {
+		return 0, false
+	}
+
+	if quota.Spec.MaxAddresses == "" {
+		return ipaddr.IPv6BitCount, true
+	}
+
+	limit, ok := new(big.Int).SetString(quota.Spec.MaxAddresses, 10)
+	if !ok {
+		return ipaddr.IPv6BitCount, true
+	}
+
+	headroom := new(big.Int).Sub(limit, u.addresses)
+	if headroom.Sign() <= 0 {
+		return 0, false
+	}
+
+	return headroom.BitLen() - 1, true
+}
+
+// quotaSizeBitLimits returns the largest size of a new allocation for the claim in each block
+// limited by the quotas. Blocks which the quotas allow no more allocations in are reported
+// with negative limits. allocations returns the allocations bound to a block.
+func quotaSizeBitLimits(
+	quotas []controlplanev1alpha1.CIDRQuota,
+	blocks []controlplanev1alpha1.CIDRBlock,
+	allocations func(block *controlplanev1alpha1.CIDRBlock) []controlplanev1alpha1.CIDRBlockAllocation,
+) map[types.NamespacedName]int {
+	limits := map[types.NamespacedName]int{}
+
+	for i := range quotas {
+		quota := &quotas[i]
+
+		usage := newQuotaUsage()
+		var selected []types.NamespacedName
+		for j := range blocks {
+			block := &blocks[j]
+
+			if !quotaSelects(quota, block) {
+				continue
+			}
+			selected = append(selected, client.ObjectKeyFromObject(block))
+
+			for _, a := range allocations(block) {
+				usage.add(&a)
+			}
+		}
+
+		limit, ok := usage.maxSizeBit(quota)
+		if !ok {
+			limit = -1
+		}
+
+		for _, key := range selected {
+			if current, ok := limits[key]; !ok || limit < current {
+				limits[key] = limit
+			}
+		}
+	}
+
+	return limits
+}
+
+// withinQuota returns the blocks in which the quotas allow the smallest allocation for the request.
+// Blocks which already have an allocation for the claim are kept so that it can be reused.
+func withinQuota(
+	blocks []controlplanev1alpha1.CIDRBlock,
+	claim *controlplanev1alpha1.CIDRClaim,
+	request allocationRequest,
+	limits map[types.NamespacedName]int,
+) []controlplanev1alpha1.CIDRBlock {
+	if len(limits) == 0 {
+		return blocks
+	}
+
+	smallest := request.minSizeBit
+	if request.requestedCIDR != "" {
+		if addr := ipaddr.NewIPAddressString(request.requestedCIDR).GetAddress(); addr != nil && addr.GetPrefixLen() != nil {
+			smallest = prefixSizeBit(addr)
+		}
+	}
+
+	filtered := make([]controlplanev1alpha1.CIDRBlock, 0, len(blocks))
+	for _, block := range blocks {
+		if limit, ok := limits[client.ObjectKeyFromObject(&block)]; ok && limit < smallest && findAllocation(&block, claim) < 0 {
+			continue
+		}
+
+		filtered = append(filtered, block)
+	}
+
+	return filtered
+}
+
+// exceedsQuota returns true if an allocation of the size in the block exceeds the quotas
+func exceedsQuota(limits map[types.NamespacedName]int, block types.NamespacedName, sizeBit int) bool {
+	limit, ok := limits[block]
+
+	return ok && sizeBit > limit
+}
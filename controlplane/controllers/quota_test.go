@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+func TestQuotaSizeBitLimits(t *testing.T) {
+	block := func(name, addressType string) controlplanev1alpha1.CIDRBlock {
+		return controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name: name,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": addressType,
+				},
+			},
+		}
+	}
+
+	blocks := []controlplanev1alpha1.CIDRBlock{
+		block("v4-001", "v4"),
+		block("v4-002", "v4"),
+		block("v6-001", "v6"),
+	}
+
+	allocations := map[string][]controlplanev1alpha1.CIDRBlockAllocation{
+		"v4-001": {
+			{ClaimName: "claim-001", ClaimUID: "001", CIDR: "192.168.1.0/28"},
+		},
+		"v4-002": {
+			{ClaimName: "claim-002", ClaimUID: "002", CIDR: "192.168.2.0/30"},
+		},
+		"v6-001": {
+			{ClaimName: "claim-002", ClaimUID: "002", CIDR: "fe80::/64"},
+		},
+	}
+
+	maxClaims := int64(3)
+	quotas := []controlplanev1alpha1.CIDRQuota{
+		{
+			Spec: controlplanev1alpha1.CIDRQuotaSpec{
+				Selector: &v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+				MaxAddresses: "64",
+			},
+		},
+		{
+			Spec: controlplanev1alpha1.CIDRQuotaSpec{
+				MaxClaims: &maxClaims,
+			},
+		},
+	}
+
+	limits := quotaSizeBitLimits(quotas, blocks, func(block *controlplanev1alpha1.CIDRBlock) []controlplanev1alpha1.CIDRBlockAllocation {
+		return allocations[block.Name]
+	})
+
+	// 64 - 16 - 4 = 44 addresses are left for IPv4 blocks
+	expected := map[string]int{"v4-001": 5, "v4-002": 5, "v6-001": 128}
+	for name, limit := range expected {
+		if got := limits[types.NamespacedName{Name: name}]; got != limit {
+			t.Errorf("expected the limit of %s to be %d, got %d", name, limit, got)
+		}
+	}
+
+	maxClaims = 2
+	limits = quotaSizeBitLimits(quotas, blocks, func(block *controlplanev1alpha1.CIDRBlock) []controlplanev1alpha1.CIDRBlockAllocation {
+		return allocations[block.Name]
+	})
+
+	for _, b := range blocks {
+		if got := limits[types.NamespacedName{Name: b.Name}]; got >= 0 {
+			t.Errorf("expected %s to exceed the quota of claims, got %d", b.Name, got)
+		}
+	}
+
+	request := allocationRequest{sizeBit: 8, minSizeBit: 4}
+	claim := &controlplanev1alpha1.CIDRClaim{ObjectMeta: v1.ObjectMeta{Name: "claim-003", UID: "003"}}
+	if filtered := withinQuota(blocks, claim, request, limits); len(filtered) != 0 {
+		t.Errorf("expected no blocks within the quota, got %d", len(filtered))
+	}
+	if filtered := withinQuota(blocks, claim, request, map[types.NamespacedName]int{{Name: "v4-001"}: 3, {Name: "v4-002"}: 4}); len(filtered) != 2 {
+		t.Errorf("expected 2 blocks within the quota, got %d", len(filtered))
+	}
+}
+
+func TestAllocationsIn(t *testing.T) {
+	block := &controlplanev1alpha1.CIDRBlock{
+		ObjectMeta: v1.ObjectMeta{Name: "shared", Namespace: "pools"},
+	}
+
+	allocations := []controlplanev1alpha1.CIDRBlockAllocation{
+		{ClaimName: "legacy", CIDR: "192.168.1.0/28"},
+		{ClaimName: "tenant-a", ClaimNamespace: "tenant-a", CIDR: "192.168.1.16/28"},
+		{ClaimName: "tenant-b", ClaimNamespace: "tenant-b", CIDR: "192.168.1.32/28"},
+	}
+
+	// Allocations without the namespace belong to the namespace of the block
+	if got := allocationsIn(block, allocations, "pools"); len(got) != 1 || got[0].ClaimName != "legacy" {
+		t.Errorf("unexpected allocations in pools: %+v", got)
+	}
+	if got := allocationsIn(block, allocations, "tenant-a"); len(got) != 1 || got[0].ClaimName != "tenant-a" {
+		t.Errorf("unexpected allocations in tenant-a: %+v", got)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+// listReservations returns the CIDRReservations in the namespace, or in all namespaces if it is empty
+func listReservations(ctx context.Context, c client.Client, namespace string) ([]controlplanev1alpha1.CIDRReservation, error) {
+	var reservations controlplanev1alpha1.CIDRReservationList
+	if err := c.List(ctx, &reservations, &client.ListOptions{
+		Namespace: namespace,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to list CIDRReservations: %w", err)
+	}
+
+	return reservations.Items, nil
+}
+
+// reservedAddresses returns the ranges in the block excluded by its spec or reserved by
+// the reservations in its namespace selecting it. Ranges larger than the block are clipped to the block.
+func reservedAddresses(
+	block *controlplanev1alpha1.CIDRBlock,
+	reservations []controlplanev1alpha1.CIDRReservation,
+) []*ipaddr.IPAddress {
+	blockSubnet := ipaddr.NewIPAddressString(block.Spec.CIDR).GetAddress()
+
+	if blockSubnet == nil {
+		return nil
+	}
+	blockSubnet = blockSubnet.ToPrefixBlock()
+
+	cidrs := make([]string, 0, len(block.Spec.Excludes)+len(reservations))
+	cidrs = append(cidrs, block.Spec.Excludes...)
+
+	for _, r := range reservations {
+		if r.Namespace != block.Namespace {
+			continue
+		}
+
+		if r.Spec.Selector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(r.Spec.Selector)
+
+			if err != nil || !selector.Matches(labels.Set(block.Labels)) {
+				continue
+			}
+		}
+
+		cidrs = append(cidrs, r.Spec.CIDR)
+	}
+
+	reserved := make([]*ipaddr.IPAddress, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		addr := ipaddr.NewIPAddressString(cidr).GetAddress()
+
+		if addr == nil || addr.IsIPv4() != blockSubnet.IsIPv4() {
+			continue
+		}
+
+		if addr.GetPrefixLen() != nil {
+			addr = addr.ToPrefixBlock()
+		}
+
+		intersection := blockSubnet.Intersect(addr)
+
+		if intersection == nil {
+			continue
+		}
+
+		reserved = append(reserved, intersection)
+	}
+
+	return reserved
+}
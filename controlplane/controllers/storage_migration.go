@@ -0,0 +1,151 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// StorageVersionMigrator rewrites the objects of CustomResourceDefinitions still stored in
+// previous versions in the current storage version and removes the previous versions from
+// status.storedVersions of the CustomResourceDefinitions so that they can be unserved later.
+type StorageVersionMigrator struct {
+	client.Client
+
+	// CRDs are the names of the CustomResourceDefinitions to migrate
+	CRDs []string
+
+	// RetryInterval is the interval of retries of failed migrations
+	RetryInterval time.Duration
+}
+
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=peernodes,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrblocks,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrclaims,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups=controlplane.miscord.win,resources=cidrclaimtemplates,verbs=get;list;watch;update
+
+// Start migrates the CustomResourceDefinitions once. Failed migrations are retried
+// because the conversion webhook may not be reachable right after the Manager starts.
+func (m *StorageVersionMigrator) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("storage-version-migrator")
+
+	interval := m.RetryInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	for _, name := range m.CRDs {
+		err := wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+			if err := m.migrate(ctx, name); err != nil {
+				logger.Error(err, "failed to migrate storage version", "crd", name)
+
+				return false, nil
+			}
+
+			return true, nil
+		})
+
+		if err != nil {
+			// The Manager is stopping
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (m *StorageVersionMigrator) NeedLeaderElection() bool {
+	return true
+}
+
+// SetupWithManager adds the migrator to the Manager.
+func (m *StorageVersionMigrator) SetupWithManager(mgr ctrl.Manager) error {
+	return mgr.Add(m)
+}
+
+func (m *StorageVersionMigrator) migrate(ctx context.Context, name string) error {
+	var crd apiextensionsv1.CustomResourceDefinition
+	if err := m.Get(ctx, client.ObjectKey{Name: name}, &crd); err != nil {
+		return fmt.Errorf("failed to get CustomResourceDefinition: %w", err)
+	}
+
+	storageVersion := storageVersionOf(&crd)
+	if storageVersion == "" {
+		return fmt.Errorf("no storage version in CustomResourceDefinition %s", name)
+	}
+
+	if slices.Equal(crd.Status.StoredVersions, []string{storageVersion}) {
+		return nil
+	}
+
+	var list unstructured.UnstructuredList
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   crd.Spec.Group,
+		Version: storageVersion,
+		Kind:    crd.Spec.Names.ListKind,
+	})
+	if err := m.List(ctx, &list); err != nil {
+		return fmt.Errorf("failed to list %s: %w", crd.Spec.Names.Plural, err)
+	}
+
+	for i := range list.Items {
+		// An update without changes makes the API server write the object in the storage version.
+		// Objects updated or deleted in the meantime need no migration.
+		err := m.Update(ctx, &list.Items[i])
+
+		if err != nil && !errors.IsConflict(err) && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to migrate %s %s: %w", crd.Spec.Names.Kind, client.ObjectKeyFromObject(&list.Items[i]), err)
+		}
+	}
+
+	updated := crd.DeepCopy()
+	updated.Status.StoredVersions = []string{storageVersion}
+
+	if err := m.Status().Patch(ctx, updated, client.MergeFromWithOptions(&crd, client.MergeFromWithOptimisticLock{})); err != nil {
+		return fmt.Errorf("failed to update stored versions: %w", err)
+	}
+
+	log.FromContext(ctx).Info("migrated storage version", "crd", name, "version", storageVersion, "objects", len(list.Items))
+
+	return nil
+}
+
+// storageVersionOf returns the storage version of the CustomResourceDefinition
+func storageVersionOf(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	controlplanev1beta1 "github.com/miscord-dev/tetrapod/controlplane/api/v1beta1"
+)
+
+var _ = Describe("StorageVersionMigrator", func() {
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		deleteAll(ctx)
+	})
+
+	It("Rewrite the objects in the storage version", func() {
+		const crdName = "cidrblocks.controlplane.miscord.win"
+
+		cidrBlock := controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: "192.168.1.0/24",
+			},
+		}
+		err := k8sClient.Create(ctx, &cidrBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Pretend that objects were stored in v1alpha1 before v1beta1 was introduced
+		var crd apiextensionsv1.CustomResourceDefinition
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: crdName}, &crd)
+		Expect(err).NotTo(HaveOccurred())
+
+		updated := crd.DeepCopy()
+		updated.Status.StoredVersions = []string{"v1alpha1", "v1beta1"}
+		err = k8sClient.Status().Patch(ctx, updated, client.MergeFrom(&crd))
+		Expect(err).NotTo(HaveOccurred())
+
+		err = (&StorageVersionMigrator{
+			Client: k8sClient,
+			CRDs:   []string{crdName},
+		}).Start(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: crdName}, &crd)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(crd.Status.StoredVersions).To(Equal([]string{"v1beta1"}))
+
+		var migrated controlplanev1beta1.CIDRBlock
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(&cidrBlock), &migrated)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(migrated.Spec.CIDR).To(Equal("192.168.1.0/24"))
+		Expect(migrated.ResourceVersion).NotTo(Equal(cidrBlock.ResourceVersion))
+	})
+})
@@ -24,15 +24,21 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
+	coordinationv1 "k8s.io/api/coordination/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	controlplanev1beta1 "github.com/miscord-dev/tetrapod/controlplane/api/v1beta1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -42,6 +48,7 @@ import (
 var cfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
+var stopWebhook context.CancelFunc
 
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -51,25 +58,73 @@ func TestAPIs(t *testing.T) {
 
 const testNamespace = "test"
 
+// tenantNamespace is labeled so that CIDRBlocks in testNamespace can be shared with it
+const tenantNamespace = "test-tenant"
+
 var _ = BeforeSuite(func() {
 	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
 
+	// The versions are registered before starting the environment so that
+	// the conversion webhooks of the CRDs are enabled
+	err := controlplanev1alpha1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = controlplanev1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	err = apiextensionsv1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
+	//+kubebuilder:scaffold:scheme
+
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
 		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
 		ErrorIfCRDPathMissing: true,
 	}
 
-	var err error
 	// cfg is defined in this file globally.
 	cfg, err = testEnv.Start()
 	Expect(err).NotTo(HaveOccurred())
 	Expect(cfg).NotTo(BeNil())
 
-	err = controlplanev1alpha1.AddToScheme(scheme.Scheme)
+	By("starting the conversion webhook")
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme: scheme.Scheme,
+		// The metrics port is left to the managers of the specs
+		Metrics: metricsserver.Options{BindAddress: "0"},
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    testEnv.WebhookInstallOptions.LocalServingHost,
+			Port:    testEnv.WebhookInstallOptions.LocalServingPort,
+			CertDir: testEnv.WebhookInstallOptions.LocalServingCertDir,
+		}),
+	})
 	Expect(err).NotTo(HaveOccurred())
 
-	//+kubebuilder:scaffold:scheme
+	for _, hub := range []interface {
+		SetupWebhookWithManager(ctrl.Manager) error
+	}{
+		&controlplanev1beta1.PeerNode{},
+		&controlplanev1beta1.CIDRBlock{},
+		&controlplanev1beta1.CIDRClaim{},
+		&controlplanev1beta1.CIDRClaimTemplate{},
+	} {
+		err = hub.SetupWebhookWithManager(mgr)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	var ctx context.Context
+	ctx, stopWebhook = context.WithCancel(context.Background())
+	go func() {
+		defer GinkgoRecover()
+
+		err := mgr.Start(ctx)
+		Expect(err).NotTo(HaveOccurred())
+	}()
+
+	Eventually(func() error {
+		return mgr.GetWebhookServer().StartedChecker()(nil)
+	}).Should(Succeed())
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
@@ -79,10 +134,75 @@ var _ = BeforeSuite(func() {
 	ns.Name = testNamespace
 	err = k8sClient.Create(context.Background(), ns)
 	Expect(err).NotTo(HaveOccurred())
+
+	tenant := &corev1.Namespace{}
+	tenant.Name = tenantNamespace
+	tenant.Labels = map[string]string{
+		"controlplane.miscord.win/tenant": "true",
+	}
+	err = k8sClient.Create(context.Background(), tenant)
+	Expect(err).NotTo(HaveOccurred())
 })
 
+// deleteAll deletes all the objects in the test namespaces. Finalizers are removed
+// because no controller is running to release them between tests.
+func deleteAll(ctx context.Context) {
+	for _, namespace := range []string{testNamespace, tenantNamespace} {
+		var cidrBlocks controlplanev1alpha1.CIDRBlockList
+		err := k8sClient.List(ctx, &cidrBlocks, client.InNamespace(namespace))
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := range cidrBlocks.Items {
+			removeFinalizers(ctx, &cidrBlocks.Items[i])
+		}
+
+		var cidrClaims controlplanev1alpha1.CIDRClaimList
+		err = k8sClient.List(ctx, &cidrClaims, client.InNamespace(namespace))
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := range cidrClaims.Items {
+			removeFinalizers(ctx, &cidrClaims.Items[i])
+		}
+
+		var blockClaims controlplanev1alpha1.CIDRBlockClaimList
+		err = k8sClient.List(ctx, &blockClaims, client.InNamespace(namespace))
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := range blockClaims.Items {
+			removeFinalizers(ctx, &blockClaims.Items[i])
+		}
+
+		for _, obj := range []client.Object{
+			&controlplanev1alpha1.CIDRBlock{},
+			&controlplanev1alpha1.CIDRClaim{},
+			&controlplanev1alpha1.CIDRReservation{},
+			&controlplanev1alpha1.CIDRBlockClaim{},
+			&controlplanev1alpha1.CIDRQuota{},
+			&controlplanev1alpha1.PeerNode{},
+			&coordinationv1.Lease{},
+		} {
+			err := k8sClient.DeleteAllOf(ctx, obj, client.InNamespace(namespace))
+			Expect(err).NotTo(HaveOccurred())
+		}
+	}
+}
+
+func removeFinalizers(ctx context.Context, obj client.Object) {
+	if len(obj.GetFinalizers()) == 0 {
+		return
+	}
+
+	base := obj.DeepCopyObject().(client.Object)
+	obj.SetFinalizers(nil)
+
+	err := k8sClient.Patch(ctx, obj, client.MergeFrom(base))
+	Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+}
+
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
+	stopWebhook()
+
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
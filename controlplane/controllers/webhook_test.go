@@ -0,0 +1,343 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+var _ = Describe("Webhook", func() {
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		deleteAll(ctx)
+	})
+
+	cidrBlock := func(name, cidr string) *controlplanev1alpha1.CIDRBlock {
+		return &controlplanev1alpha1.CIDRBlock{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      name,
+				Namespace: testNamespace,
+				Labels: map[string]string{
+					"controlplane.miscord.win/address-type": "v4",
+				},
+			},
+			Spec: controlplanev1alpha1.CIDRBlockSpec{
+				CIDR: cidr,
+			},
+		}
+	}
+
+	cidrClaim := func(sizeBit int) *controlplanev1alpha1.CIDRClaim {
+		return &controlplanev1alpha1.CIDRClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-claim",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+				SizeBit: sizeBit,
+			},
+		}
+	}
+
+	It("Validate CIDRBlock", func() {
+		validator := &controlplanev1alpha1.CIDRBlockValidator{Client: k8sClient}
+
+		_, err := validator.ValidateCreate(ctx, cidrBlock("cidr-block-001", "192.168.1.0/24"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = validator.ValidateCreate(ctx, cidrBlock("cidr-block-001", "192.168.1.0/33"))
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.cidr"))
+
+		_, err = validator.ValidateCreate(ctx, cidrBlock("cidr-block-001", "192.168.1.1/24"))
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("must be aligned to its prefix length like 192.168.1.0/24"))
+
+		err = k8sClient.Create(ctx, cidrBlock("cidr-block-001", "192.168.1.0/24"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = validator.ValidateCreate(ctx, cidrBlock("cidr-block-002", "192.168.0.0/16"))
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("overlaps 192.168.1.0/24 of CIDRBlock cidr-block-001"))
+
+		_, err = validator.ValidateUpdate(ctx, cidrBlock("cidr-block-001", "192.168.1.0/24"), cidrBlock("cidr-block-001", "192.168.0.0/16"))
+		Expect(err).NotTo(HaveOccurred())
+
+		// Blocks in other namespaces may overlap unless either of them is shared
+		tenantBlock := cidrBlock("cidr-block-001", "192.168.0.0/16")
+		tenantBlock.Namespace = tenantNamespace
+		_, err = validator.ValidateCreate(ctx, tenantBlock)
+		Expect(err).NotTo(HaveOccurred())
+
+		tenantBlock.Spec.NamespaceSelector = &v1.LabelSelector{}
+		_, err = validator.ValidateCreate(ctx, tenantBlock)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("overlaps 192.168.1.0/24 of CIDRBlock test/cidr-block-001"))
+
+		isController := true
+		child := cidrBlock("cidr-block-002", "192.168.1.0/26")
+		child.OwnerReferences = []v1.OwnerReference{
+			{
+				APIVersion: controlplanev1alpha1.GroupVersion.String(),
+				Kind:       "CIDRBlockClaim",
+				Name:       "cidr-block-002",
+				UID:        "00000000-0000-0000-0000-000000000000",
+				Controller: &isController,
+			},
+		}
+		// The owner reference alone does not make a child block
+		_, err = validator.ValidateCreate(ctx, child)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+
+		blockClaim := &controlplanev1alpha1.CIDRBlockClaim{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-block-002",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRBlockClaimSpec{
+				Selector: v1.LabelSelector{
+					MatchLabels: map[string]string{
+						"controlplane.miscord.win/address-type": "v4",
+					},
+				},
+				SizeBit: 6,
+			},
+		}
+		err = k8sClient.Create(ctx, blockClaim)
+		Expect(err).NotTo(HaveOccurred())
+		child.OwnerReferences[0].UID = blockClaim.UID
+
+		_, err = validator.ValidateCreate(ctx, child)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+
+		// The prefix of the child is allocated from the parent to the CIDRClaim of the CIDRBlockClaim
+		var parent controlplanev1alpha1.CIDRBlock
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "cidr-block-001"}, &parent)
+		Expect(err).NotTo(HaveOccurred())
+
+		parent.Status.Allocations = []controlplanev1alpha1.CIDRBlockAllocation{
+			{ClaimName: controlplanev1alpha1.BlockClaimCIDRClaimName("cidr-block-002"), ClaimNamespace: testNamespace, CIDR: "192.168.1.0/26"},
+		}
+		err = k8sClient.Status().Update(ctx, &parent)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = validator.ValidateCreate(ctx, child)
+		Expect(err).NotTo(HaveOccurred())
+
+		excluded := cidrBlock("cidr-block-002", "192.168.2.0/24")
+		excluded.Spec.Excludes = []string{"192.168.3.0/24"}
+		_, err = validator.ValidateCreate(ctx, excluded)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.excludes[0]"))
+
+		// Excludes overlapping the block must be in it as well
+		excluded.Spec.Excludes = []string{"192.168.2.128/25", "192.168.0.0/16"}
+		_, err = validator.ValidateCreate(ctx, excluded)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).NotTo(ContainSubstring("spec.excludes[0]"))
+		Expect(err.Error()).To(ContainSubstring("spec.excludes[1]"))
+
+		quarantined := cidrBlock("cidr-block-002", "192.168.2.0/24")
+		quarantined.Spec.QuarantinePeriod = &v1.Duration{Duration: -time.Hour}
+		_, err = validator.ValidateCreate(ctx, quarantined)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.quarantinePeriod"))
+	})
+
+	It("Default CIDRBlock", func() {
+		block := cidrBlock("cidr-block-001", "fe80:0::/64")
+
+		err := (&controlplanev1alpha1.CIDRBlockDefaulter{}).Default(ctx, block)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(block.Spec.CIDR).To(Equal("fe80::/64"))
+		Expect(block.Spec.AllocationStrategy).To(Equal(controlplanev1alpha1.AllocationStrategyFirstFit))
+	})
+
+	It("Validate CIDRClaim", func() {
+		validator := &controlplanev1alpha1.CIDRClaimValidator{Client: k8sClient}
+
+		_, err := validator.ValidateCreate(ctx, cidrClaim(16))
+		Expect(err).NotTo(HaveOccurred())
+
+		err = k8sClient.Create(ctx, cidrBlock("cidr-block-001", "192.168.1.0/24"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = validator.ValidateCreate(ctx, cidrClaim(8))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = validator.ValidateCreate(ctx, cidrClaim(9))
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.sizeBit"))
+
+		// Claims admitted before the blocks shrank can still be updated without changing the spec and deleted
+		finalized := cidrClaim(9)
+		finalized.Finalizers = []string{"controlplane.miscord.win/finalizer"}
+		_, err = validator.ValidateUpdate(ctx, cidrClaim(9), finalized)
+		Expect(err).NotTo(HaveOccurred())
+
+		deleting := cidrClaim(10)
+		now := v1.Now()
+		deleting.DeletionTimestamp = &now
+		_, err = validator.ValidateUpdate(ctx, cidrClaim(9), deleting)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = validator.ValidateUpdate(ctx, cidrClaim(8), cidrClaim(9))
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+
+		elastic := cidrClaim(9)
+		minSizeBit := 4
+		elastic.Spec.MinSizeBit = &minSizeBit
+		_, err = validator.ValidateCreate(ctx, elastic)
+		Expect(err).NotTo(HaveOccurred())
+
+		minSizeBit = 10
+		_, err = validator.ValidateCreate(ctx, elastic)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.minSizeBit"))
+
+		_, err = validator.ValidateCreate(ctx, cidrClaim(129))
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("must be between 0 and 128"))
+
+		invalidSelector := cidrClaim(0)
+		invalidSelector.Spec.Selector.MatchExpressions = []v1.LabelSelectorRequirement{
+			{
+				Key:      "controlplane.miscord.win/address-type",
+				Operator: "Unknown",
+			},
+		}
+		_, err = validator.ValidateCreate(ctx, invalidSelector)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.selector.matchExpressions[0].operator"))
+
+		requested := cidrClaim(0)
+		requested.Spec.RequestedCIDR = "192.168.1.1/24"
+		_, err = validator.ValidateCreate(ctx, requested)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.requestedCIDR"))
+
+		dualStack := cidrClaim(0)
+		dualStack.Spec.Families = []controlplanev1alpha1.CIDRClaimFamily{
+			{
+				Family:        controlplanev1alpha1.AddressFamilyIPv4,
+				RequestedCIDR: "fe80::1",
+			},
+			{
+				Family: controlplanev1alpha1.AddressFamilyIPv4,
+			},
+		}
+		_, err = validator.ValidateCreate(ctx, dualStack)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.families[0].requestedCIDR"))
+		Expect(err.Error()).To(ContainSubstring("spec.families[1].family"))
+
+		preferred := cidrClaim(0)
+		preferred.Spec.Preferred = []controlplanev1alpha1.PreferredCIDRBlockTerm{
+			{
+				Weight:            0,
+				PeerNodeLabelKeys: []string{"topology.kubernetes.io/zone", "invalid key"},
+			},
+		}
+		_, err = validator.ValidateCreate(ctx, preferred)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.preferred[0].weight"))
+		Expect(err.Error()).To(ContainSubstring("spec.preferred[0].peerNodeLabelKeys[1]"))
+
+		drain := cidrClaim(0)
+		drain.Spec.DrainPeriod = &v1.Duration{Duration: -time.Minute}
+		_, err = validator.ValidateCreate(ctx, drain)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.drainPeriod"))
+
+		// CIDRBlocks shared with the namespace of the claim are matched as well
+		tenantClaim := cidrClaim(9)
+		tenantClaim.Namespace = tenantNamespace
+		_, err = validator.ValidateCreate(ctx, tenantClaim)
+		Expect(err).NotTo(HaveOccurred())
+
+		shared := cidrBlock("cidr-block-shared", "192.168.2.0/24")
+		shared.Spec.NamespaceSelector = &v1.LabelSelector{
+			MatchLabels: map[string]string{
+				"controlplane.miscord.win/tenant": "true",
+			},
+		}
+		err = k8sClient.Create(ctx, shared)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = validator.ValidateCreate(ctx, tenantClaim)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.sizeBit"))
+	})
+
+	It("Validate CIDRClaimTemplate", func() {
+		validator := &controlplanev1alpha1.CIDRClaimTemplateValidator{Client: k8sClient}
+
+		tmpl := &controlplanev1alpha1.CIDRClaimTemplate{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "cidr-claim-template",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.CIDRClaimTemplateSpec{
+				SizeBit: -1,
+			},
+		}
+
+		_, err := validator.ValidateCreate(ctx, tmpl)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.sizeBit"))
+	})
+
+	It("Validate PeerNode", func() {
+		validator := &controlplanev1alpha1.PeerNodeValidator{}
+
+		peerNode := &controlplanev1alpha1.PeerNode{
+			ObjectMeta: v1.ObjectMeta{
+				Name:      "peer-node",
+				Namespace: testNamespace,
+			},
+			Spec: controlplanev1alpha1.PeerNodeSpec{
+				Endpoints: []string{
+					"192.0.2.1:51820",
+					"[2001:db8::1]:51820",
+				},
+				StaticRoutes: []string{
+					"10.0.0.0/8",
+				},
+			},
+		}
+
+		_, err := validator.ValidateCreate(ctx, peerNode)
+		Expect(err).NotTo(HaveOccurred())
+
+		peerNode.Spec.Endpoints = []string{
+			"127.0.0.1:51820",
+			"[::]:51820",
+			"garbage",
+			"192.0.2.1:0",
+		}
+		peerNode.Spec.StaticRoutes = []string{
+			"10.0.0.1/8",
+		}
+
+		_, err = validator.ValidateCreate(ctx, peerNode)
+		Expect(errors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.endpoints[0]: Invalid value: \"127.0.0.1:51820\": must not be a loopback address"))
+		Expect(err.Error()).To(ContainSubstring("spec.endpoints[1]"))
+		Expect(err.Error()).To(ContainSubstring("spec.endpoints[2]"))
+		Expect(err.Error()).To(ContainSubstring("spec.endpoints[3]"))
+		Expect(err.Error()).To(ContainSubstring("spec.staticRoutes[0]"))
+	})
+})
@@ -19,20 +19,26 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+	controlplanev1beta1 "github.com/miscord-dev/tetrapod/controlplane/api/v1beta1"
 	"github.com/miscord-dev/tetrapod/controlplane/controllers"
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/netbox"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -43,8 +49,10 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 
 	utilruntime.Must(controlplanev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(controlplanev1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -52,11 +60,21 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var peerNodeOfflineTimeout time.Duration
+	var peerNodeDeletionTimeout time.Duration
+	var netboxURL string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&peerNodeOfflineTimeout, "peer-node-offline-timeout", 2*time.Minute,
+		"The duration without heartbeats after which a PeerNode is marked offline. Zero disables it.")
+	flag.DurationVar(&peerNodeDeletionTimeout, "peer-node-deletion-timeout", 24*time.Hour,
+		"The duration without heartbeats after which a PeerNode and its CIDRClaims are deleted. Zero disables it.")
+	flag.StringVar(&netboxURL, "netbox-url", "",
+		"The URL of NetBox to reserve the prefixes of CIDRClaims in. The API token is read from $NETBOX_TOKEN. "+
+			"The prefixes are allocated in the cluster if it is empty.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -72,6 +90,7 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "2de85232.miscord.win",
+		Cache:                  cache.Options{ByObject: controllers.PeerNodeLeaseCache()},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -89,20 +108,108 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = controllers.RegisterMetrics(mgr.GetClient()); err != nil {
+		setupLog.Error(err, "unable to register metrics")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.PeerNodeReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		OfflineTimeout:  peerNodeOfflineTimeout,
+		DeletionTimeout: peerNodeDeletionTimeout,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PeerNode")
 		os.Exit(1)
 	}
+	var ipam controllers.IPAMProvider
+	if netboxURL != "" {
+		ipam = &controllers.NetBoxProvider{
+			Client: mgr.GetClient(),
+			NetBox: &netbox.Client{
+				URL:   strings.TrimSuffix(netboxURL, "/"),
+				Token: os.Getenv("NETBOX_TOKEN"),
+			},
+		}
+	}
+
 	if err = (&controllers.CIDRClaimReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorder("cidrclaim-controller"),
+		IPAM:     ipam,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "CIDRClaim")
+		os.Exit(1)
+	}
+	if err = (&controllers.CIDRBlockReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
+		IPAM:   ipam,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "CIDRClaim")
+		setupLog.Error(err, "unable to create controller", "controller", "CIDRBlock")
 		os.Exit(1)
 	}
+	if err = (&controllers.CIDRBlockClaimReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "CIDRBlockClaim")
+		os.Exit(1)
+	}
+	if err = (&controllers.CIDRQuotaReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "CIDRQuota")
+		os.Exit(1)
+	}
+	if err = (&controllers.StorageVersionMigrator{
+		Client: mgr.GetClient(),
+		CRDs: []string{
+			"peernodes.controlplane.miscord.win",
+			"cidrblocks.controlplane.miscord.win",
+			"cidrclaims.controlplane.miscord.win",
+			"cidrclaimtemplates.controlplane.miscord.win",
+		},
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create storage version migrator")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&controlplanev1alpha1.CIDRBlock{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CIDRBlock")
+			os.Exit(1)
+		}
+		if err = (&controlplanev1alpha1.CIDRClaim{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CIDRClaim")
+			os.Exit(1)
+		}
+		if err = (&controlplanev1alpha1.CIDRClaimTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CIDRClaimTemplate")
+			os.Exit(1)
+		}
+		if err = (&controlplanev1alpha1.PeerNode{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PeerNode")
+			os.Exit(1)
+		}
+		if err = (&controlplanev1beta1.PeerNode{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "PeerNode")
+			os.Exit(1)
+		}
+		if err = (&controlplanev1beta1.CIDRBlock{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CIDRBlock")
+			os.Exit(1)
+		}
+		if err = (&controlplanev1beta1.CIDRClaim{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CIDRClaim")
+			os.Exit(1)
+		}
+		if err = (&controlplanev1beta1.CIDRClaimTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CIDRClaimTemplate")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
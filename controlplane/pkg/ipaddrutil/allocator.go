@@ -0,0 +1,174 @@
+package ipaddrutil
+
+import (
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+// Allocator picks a block with 2^sizeBit addresses from free blocks
+type Allocator interface {
+	Allocate(blocks []*ipaddr.IPAddress, sizeBit int) *ipaddr.IPAddress
+}
+
+// FirstFit picks the first prefix of the first block large enough
+type FirstFit struct{}
+
+var _ Allocator = FirstFit{}
+
+// Allocate implements Allocator
+func (FirstFit) Allocate(blocks []*ipaddr.IPAddress, sizeBit int) *ipaddr.IPAddress {
+	return FindSubBlock(blocks, sizeBit)
+}
+
+// BestFit picks the first prefix of the smallest block large enough
+// so that large free blocks are kept for large claims
+type BestFit struct{}
+
+var _ Allocator = BestFit{}
+
+// Allocate implements Allocator
+func (BestFit) Allocate(blocks []*ipaddr.IPAddress, sizeBit int) *ipaddr.IPAddress {
+	var best *ipaddr.IPAddress
+	for _, block := range blocks {
+		size := blockSizeBit(block)
+
+		if size < sizeBit {
+			continue
+		}
+
+		if best == nil || size < blockSizeBit(best) {
+			best = block
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	return FindSubBlock([]*ipaddr.IPAddress{best}, sizeBit)
+}
+
+// Sequential picks the lowest prefix after Last, the most recently allocated prefix.
+// It wraps around to the lowest prefix of all blocks when nothing is left after Last.
+type Sequential struct {
+	Last *ipaddr.IPAddress
+}
+
+var _ Allocator = &Sequential{}
+
+// Allocate implements Allocator
+func (s *Sequential) Allocate(blocks []*ipaddr.IPAddress, sizeBit int) *ipaddr.IPAddress {
+	var next, lowest *ipaddr.IPAddress
+	for _, block := range blocks {
+		if blockSizeBit(block) < sizeBit {
+			continue
+		}
+
+		if lowest == nil || block.GetValue().Cmp(lowest.GetValue()) < 0 {
+			lowest = block
+		}
+
+		if s.Last == nil || block.GetValue().Cmp(s.Last.GetUpperValue()) <= 0 {
+			continue
+		}
+
+		if next == nil || block.GetValue().Cmp(next.GetValue()) < 0 {
+			next = block
+		}
+	}
+
+	if next == nil {
+		next = lowest
+	}
+
+	if next == nil {
+		return nil
+	}
+
+	return FindSubBlock([]*ipaddr.IPAddress{next}, sizeBit)
+}
+
+// Random picks a prefix uniformly at random from all the prefixes
+// with 2^sizeBit addresses in blocks
+type Random struct {
+	// Rand is the source of randomness. A time-seeded source is used if it is nil.
+	Rand *rand.Rand
+}
+
+var _ Allocator = &Random{}
+
+// Allocate implements Allocator
+func (r *Random) Allocate(blocks []*ipaddr.IPAddress, sizeBit int) *ipaddr.IPAddress {
+	rnd := r.Rand
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	candidates := make([]*ipaddr.IPAddress, 0, len(blocks))
+	counts := make([]*big.Int, 0, len(blocks))
+	total := big.NewInt(0)
+	for _, block := range blocks {
+		size := blockSizeBit(block)
+
+		if size < sizeBit {
+			continue
+		}
+
+		count := new(big.Int).Lsh(big.NewInt(1), uint(size-sizeBit))
+
+		candidates = append(candidates, block)
+		counts = append(counts, count)
+		total.Add(total, count)
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	index := new(big.Int).Rand(rnd, total)
+	for i, block := range candidates {
+		if index.Cmp(counts[i]) >= 0 {
+			index.Sub(index, counts[i])
+
+			continue
+		}
+
+		offset := index.Lsh(index, uint(sizeBit))
+
+		return addressFromValue(
+			block,
+			offset.Add(offset, block.GetValue()),
+			block.GetBitCount()-sizeBit,
+		)
+	}
+
+	return nil
+}
+
+func blockSizeBit(block *ipaddr.IPAddress) int {
+	if block.GetPrefixLen() == nil {
+		return 0
+	}
+
+	return block.GetBitCount() - block.GetPrefixLen().Len()
+}
+
+func addressFromValue(base *ipaddr.IPAddress, value *big.Int, prefixLen int) *ipaddr.IPAddress {
+	if base.IsIPv4() {
+		return ipaddr.NewIPv4AddressFromPrefixedUint32(
+			uint32(value.Uint64()),
+			ipaddr.ToPrefixLen(prefixLen),
+		).ToIP()
+	}
+
+	addr, err := ipaddr.NewIPv6AddressFromPrefixedInt(value, ipaddr.ToPrefixLen(prefixLen))
+
+	if err != nil {
+		return nil
+	}
+
+	return addr.ToIP()
+}
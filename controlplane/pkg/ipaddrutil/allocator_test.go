@@ -0,0 +1,286 @@
+package ipaddrutil
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+func freeBlocksForTest() []*ipaddr.IPAddress {
+	return []*ipaddr.IPAddress{
+		ipAddress("192.168.1.6/31"),
+		ipAddress("192.168.1.8/29"),
+		ipAddress("192.168.1.16/28"),
+		ipAddress("192.168.1.32/27"),
+		ipAddress("192.168.1.64/26"),
+		ipAddress("192.168.1.128/25"),
+	}
+}
+
+func TestFirstFit(t *testing.T) {
+	type args struct {
+		blocks  []*ipaddr.IPAddress
+		sizeBit int
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "v4",
+			args: args{
+				blocks:  freeBlocksForTest(),
+				sizeBit: 2,
+			},
+			want: "192.168.1.8/30",
+		},
+		{
+			name: "no space",
+			args: args{
+				blocks:  freeBlocksForTest(),
+				sizeBit: 8,
+			},
+			want: "<nil>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FirstFit{}.Allocate(tt.args.blocks, tt.args.sizeBit)
+
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("FirstFit.Allocate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestFit(t *testing.T) {
+	type args struct {
+		blocks  []*ipaddr.IPAddress
+		sizeBit int
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "v4 exact",
+			args: args{
+				blocks:  freeBlocksForTest(),
+				sizeBit: 5,
+			},
+			want: "192.168.1.32/27",
+		},
+		{
+			name: "v4 smallest larger block",
+			args: args{
+				blocks: []*ipaddr.IPAddress{
+					ipAddress("192.168.1.128/25"),
+					ipAddress("192.168.1.64/26"),
+					ipAddress("192.168.1.8/29"),
+				},
+				sizeBit: 4,
+			},
+			want: "192.168.1.64/28",
+		},
+		{
+			name: "ties go to the first block",
+			args: args{
+				blocks: []*ipaddr.IPAddress{
+					ipAddress("192.168.2.0/28"),
+					ipAddress("192.168.1.0/28"),
+				},
+				sizeBit: 3,
+			},
+			want: "192.168.2.0/29",
+		},
+		{
+			name: "IPv6",
+			args: args{
+				blocks: []*ipaddr.IPAddress{
+					ipAddress("fe80::/16"),
+					ipAddress("fd00::/48"),
+				},
+				sizeBit: 64,
+			},
+			want: "fd00::/64",
+		},
+		{
+			name: "no space",
+			args: args{
+				blocks:  freeBlocksForTest(),
+				sizeBit: 8,
+			},
+			want: "<nil>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BestFit{}.Allocate(tt.args.blocks, tt.args.sizeBit)
+
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("BestFit.Allocate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequential(t *testing.T) {
+	type args struct {
+		last    *ipaddr.IPAddress
+		blocks  []*ipaddr.IPAddress
+		sizeBit int
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{
+			name: "no previous allocation",
+			args: args{
+				blocks:  freeBlocksForTest(),
+				sizeBit: 4,
+			},
+			want: "192.168.1.16/28",
+		},
+		{
+			name: "after last",
+			args: args{
+				last:    ipAddress("192.168.1.16/28"),
+				blocks:  freeBlocksForTest(),
+				sizeBit: 4,
+			},
+			want: "192.168.1.32/28",
+		},
+		{
+			name: "unordered blocks",
+			args: args{
+				last: ipAddress("192.168.1.0/28"),
+				blocks: []*ipaddr.IPAddress{
+					ipAddress("192.168.1.128/25"),
+					ipAddress("192.168.1.64/26"),
+				},
+				sizeBit: 4,
+			},
+			want: "192.168.1.64/28",
+		},
+		{
+			name: "wrap around",
+			args: args{
+				last:    ipAddress("192.168.1.192/26"),
+				blocks:  freeBlocksForTest(),
+				sizeBit: 4,
+			},
+			want: "192.168.1.16/28",
+		},
+		{
+			name: "no space",
+			args: args{
+				blocks:  freeBlocksForTest(),
+				sizeBit: 8,
+			},
+			want: "<nil>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&Sequential{Last: tt.args.last}).Allocate(tt.args.blocks, tt.args.sizeBit)
+
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("Sequential.Allocate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRandom(t *testing.T) {
+	type args struct {
+		blocks  []*ipaddr.IPAddress
+		sizeBit int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantNil bool
+	}{
+		{
+			name: "v4",
+			args: args{
+				blocks:  freeBlocksForTest(),
+				sizeBit: 2,
+			},
+		},
+		{
+			name: "v4 single address",
+			args: args{
+				blocks:  freeBlocksForTest(),
+				sizeBit: 0,
+			},
+		},
+		{
+			name: "IPv6",
+			args: args{
+				blocks: []*ipaddr.IPAddress{
+					ipAddress("fe80::/16"),
+					ipAddress("fd00::/48"),
+				},
+				sizeBit: 64,
+			},
+		},
+		{
+			name: "no space",
+			args: args{
+				blocks:  freeBlocksForTest(),
+				sizeBit: 8,
+			},
+			wantNil: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allocator := &Random{Rand: rand.New(rand.NewSource(1))}
+
+			for i := 0; i < 100; i++ {
+				got := allocator.Allocate(tt.args.blocks, tt.args.sizeBit)
+
+				if tt.wantNil {
+					if got != nil {
+						t.Fatalf("Random.Allocate() = %v, want <nil>", got)
+					}
+
+					return
+				}
+
+				if got == nil {
+					t.Fatalf("Random.Allocate() = <nil>")
+				}
+
+				if prefixLen := got.GetPrefixLen().Len(); prefixLen != got.GetBitCount()-tt.args.sizeBit {
+					t.Fatalf("Random.Allocate() = %v, unexpected prefix length %d", got, prefixLen)
+				}
+
+				if !got.IsPrefixBlock() {
+					t.Fatalf("Random.Allocate() = %v, not aligned", got)
+				}
+
+				contained := false
+				for _, block := range tt.args.blocks {
+					if block.Contains(got) {
+						contained = true
+
+						break
+					}
+				}
+
+				if !contained {
+					t.Fatalf("Random.Allocate() = %v, not in free blocks", got)
+				}
+			}
+		})
+	}
+}
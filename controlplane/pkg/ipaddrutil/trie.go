@@ -0,0 +1,155 @@
+package ipaddrutil
+
+import (
+	"math/big"
+
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+// PrefixTrie tracks used prefixes in a base prefix block with a binary trie.
+// Insert, Remove and FindFree take time proportional to the bit count of addresses
+// regardless of the number of used prefixes.
+// It has the same semantics as FreeBlocks and FindSubBlock.
+type PrefixTrie struct {
+	base *ipaddr.IPAddress
+	root *trieNode
+}
+
+// trieNode is a prefix block in the trie.
+// A node without children is either entirely used or entirely free.
+type trieNode struct {
+	used     bool
+	children *[2]*trieNode
+
+	// maxFree is log2(the number of addresses) of the largest free prefix block in the node.
+	// It is -1 if nothing is free.
+	maxFree int
+}
+
+// NewPrefixTrie returns a trie where all the addresses in base are free
+func NewPrefixTrie(base *ipaddr.IPAddress) *PrefixTrie {
+	base = base.ToPrefixBlock()
+
+	return &PrefixTrie{
+		base: base,
+		root: newLeaf(false, blockSizeBit(base)),
+	}
+}
+
+func newLeaf(used bool, sizeBit int) *trieNode {
+	if used {
+		return &trieNode{used: true, maxFree: -1}
+	}
+
+	return &trieNode{maxFree: sizeBit}
+}
+
+// Insert marks the addresses in prefix used
+func (t *PrefixTrie) Insert(prefix *ipaddr.IPAddress) {
+	t.update(prefix, true)
+}
+
+// Remove marks the addresses in prefix free
+func (t *PrefixTrie) Remove(prefix *ipaddr.IPAddress) {
+	t.update(prefix, false)
+}
+
+func (t *PrefixTrie) update(prefix *ipaddr.IPAddress, used bool) {
+	if prefix == nil || prefix.GetIPVersion() != t.base.GetIPVersion() || !t.base.Overlaps(prefix) {
+		return
+	}
+
+	if prefix.Contains(t.base) {
+		t.root = newLeaf(used, blockSizeBit(t.base))
+
+		return
+	}
+
+	for _, block := range prefix.SpanWithPrefixBlocks() {
+		t.set(t.root, blockSizeBit(t.base), block.GetValue(), blockSizeBit(block), used)
+	}
+}
+
+// set marks the block of 2^sizeBit addresses starting at value in the node of 2^nodeSizeBit addresses
+func (t *PrefixTrie) set(n *trieNode, nodeSizeBit int, value *big.Int, sizeBit int, used bool) {
+	if n.children == nil && n.used == used {
+		return
+	}
+
+	if nodeSizeBit == sizeBit {
+		*n = *newLeaf(used, nodeSizeBit)
+
+		return
+	}
+
+	if n.children == nil {
+		n.children = &[2]*trieNode{
+			newLeaf(n.used, nodeSizeBit-1),
+			newLeaf(n.used, nodeSizeBit-1),
+		}
+	}
+
+	t.set(n.children[value.Bit(nodeSizeBit-1)], nodeSizeBit-1, value, sizeBit, used)
+
+	left, right := n.children[0], n.children[1]
+	switch {
+	case left.children == nil && right.children == nil && left.used == right.used:
+		*n = *newLeaf(left.used, nodeSizeBit)
+	case left.maxFree > right.maxFree:
+		n.maxFree = left.maxFree
+	default:
+		n.maxFree = right.maxFree
+	}
+}
+
+// FindFree returns the lowest free prefix block with 2^sizeBit addresses in the
+// lowest free block large enough, like FindSubBlock(FreeBlocks(...), sizeBit)
+func (t *PrefixTrie) FindFree(sizeBit int) *ipaddr.IPAddress {
+	if sizeBit < 0 || t.root.maxFree < sizeBit {
+		return nil
+	}
+
+	value := new(big.Int).Set(t.base.GetValue())
+	n, nodeSizeBit := t.root, blockSizeBit(t.base)
+	for n.children != nil {
+		nodeSizeBit--
+
+		if n.children[0].maxFree >= sizeBit {
+			n = n.children[0]
+
+			continue
+		}
+
+		value.SetBit(value, nodeSizeBit, 1)
+		n = n.children[1]
+	}
+
+	return addressFromValue(t.base, value, t.base.GetBitCount()-sizeBit)
+}
+
+// FreeBlocks returns the free prefix blocks in ascending order like FreeBlocks
+func (t *PrefixTrie) FreeBlocks() []*ipaddr.IPAddress {
+	var blocks []*ipaddr.IPAddress
+
+	t.walk(t.root, blockSizeBit(t.base), new(big.Int).Set(t.base.GetValue()), func(value *big.Int, sizeBit int) {
+		blocks = append(blocks, addressFromValue(t.base, value, t.base.GetBitCount()-sizeBit))
+	})
+
+	return blocks
+}
+
+// walk calls fn for each free leaf in ascending order
+func (t *PrefixTrie) walk(n *trieNode, nodeSizeBit int, value *big.Int, fn func(value *big.Int, sizeBit int)) {
+	if n.maxFree < 0 {
+		return
+	}
+
+	if n.children == nil {
+		fn(value, nodeSizeBit)
+
+		return
+	}
+
+	t.walk(n.children[0], nodeSizeBit-1, value, fn)
+	t.walk(n.children[1], nodeSizeBit-1, new(big.Int).SetBit(value, nodeSizeBit-1, 1), fn)
+}
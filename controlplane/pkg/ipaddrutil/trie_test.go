@@ -0,0 +1,242 @@
+package ipaddrutil
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+)
+
+// randomPrefixes returns n random prefix blocks in base with up to 2^maxSizeBit addresses
+func randomPrefixes(rnd *rand.Rand, base *ipaddr.IPAddress, n, maxSizeBit int) []*ipaddr.IPAddress {
+	prefixes := make([]*ipaddr.IPAddress, 0, n)
+	for i := 0; i < n; i++ {
+		sizeBit := rnd.Intn(maxSizeBit + 1)
+
+		prefix := (&Random{Rand: rnd}).Allocate([]*ipaddr.IPAddress{base}, sizeBit)
+		prefixes = append(prefixes, prefix)
+	}
+
+	return prefixes
+}
+
+func TestPrefixTrie(t *testing.T) {
+	tests := []struct {
+		name       string
+		base       string
+		used       []string
+		want       []string
+		sizeBit    int
+		wantSubnet string
+	}{
+		{
+			name: "v4",
+			base: "192.168.1.0/24",
+			used: []string{
+				"192.168.1.0/25",
+				"192.168.1.192/26",
+			},
+			want:       []string{"192.168.1.128/26"},
+			sizeBit:    4,
+			wantSubnet: "192.168.1.128/28",
+		},
+		{
+			name: "single addresses",
+			base: "192.168.1.0/29",
+			used: []string{
+				"192.168.1.0",
+				"192.168.1.1",
+				"192.168.1.3",
+			},
+			want:       []string{"192.168.1.2/32", "192.168.1.4/30"},
+			sizeBit:    1,
+			wantSubnet: "192.168.1.4/31",
+		},
+		{
+			name: "overlapping and outside",
+			base: "192.168.1.0/24",
+			used: []string{
+				"192.168.1.0/26",
+				"192.168.1.0/25",
+				"10.0.0.0/8",
+				"fe80::/64",
+			},
+			want:       []string{"192.168.1.128/25"},
+			sizeBit:    8,
+			wantSubnet: "<nil>",
+		},
+		{
+			name: "containing base",
+			base: "192.168.1.0/24",
+			used: []string{
+				"192.168.0.0/16",
+			},
+			want:       []string{},
+			sizeBit:    0,
+			wantSubnet: "<nil>",
+		},
+		{
+			name: "IPv6",
+			base: "fd00::/32",
+			used: []string{
+				"fd00::/64",
+				"fd00:0:0:1::/64",
+			},
+			want: []string{
+				"fd00:0:0:2::/63",
+				"fd00:0:0:4::/62",
+				"fd00:0:0:8::/61",
+				"fd00:0:0:10::/60",
+				"fd00:0:0:20::/59",
+				"fd00:0:0:40::/58",
+				"fd00:0:0:80::/57",
+				"fd00:0:0:100::/56",
+				"fd00:0:0:200::/55",
+				"fd00:0:0:400::/54",
+				"fd00:0:0:800::/53",
+				"fd00:0:0:1000::/52",
+				"fd00:0:0:2000::/51",
+				"fd00:0:0:4000::/50",
+				"fd00:0:0:8000::/49",
+				"fd00:0:1::/48",
+				"fd00:0:2::/47",
+				"fd00:0:4::/46",
+				"fd00:0:8::/45",
+				"fd00:0:10::/44",
+				"fd00:0:20::/43",
+				"fd00:0:40::/42",
+				"fd00:0:80::/41",
+				"fd00:0:100::/40",
+				"fd00:0:200::/39",
+				"fd00:0:400::/38",
+				"fd00:0:800::/37",
+				"fd00:0:1000::/36",
+				"fd00:0:2000::/35",
+				"fd00:0:4000::/34",
+				"fd00:0:8000::/33",
+			},
+			sizeBit:    64,
+			wantSubnet: "fd00:0:0:2::/64",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trie := NewPrefixTrie(ipAddress(tt.base))
+			for _, u := range tt.used {
+				trie.Insert(ipAddress(u))
+			}
+
+			if diff := cmp.Diff(tt.want, addrSliceToStrSlice(trie.FreeBlocks())); diff != "" {
+				t.Errorf("PrefixTrie.FreeBlocks() mismatch (-want +got):\n%s", diff)
+			}
+
+			if got := trie.FindFree(tt.sizeBit); fmt.Sprint(got) != tt.wantSubnet {
+				t.Errorf("PrefixTrie.FindFree() = %v, want %v", got, tt.wantSubnet)
+			}
+		})
+	}
+}
+
+// TestPrefixTrieEquivalence compares PrefixTrie with FreeBlocks and FindSubBlock on random inputs
+func TestPrefixTrieEquivalence(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, base := range []string{"192.168.0.0/20", "fd00::/48"} {
+		base := ipAddress(base)
+
+		for i := 0; i < 50; i++ {
+			used := randomPrefixes(rnd, base, rnd.Intn(30), 6)
+
+			trie := NewPrefixTrie(base)
+			for _, u := range used {
+				trie.Insert(u)
+			}
+
+			want := FreeBlocks(base, used)
+			if diff := cmp.Diff(addrSliceToStrSlice(want), addrSliceToStrSlice(trie.FreeBlocks())); diff != "" {
+				t.Fatalf("FreeBlocks() of %v mismatch (-want +got):\n%s", used, diff)
+			}
+
+			for sizeBit := 0; sizeBit <= blockSizeBit(base); sizeBit++ {
+				want := FindSubBlock(want, sizeBit)
+
+				if got := trie.FindFree(sizeBit); fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Fatalf("FindFree(%d) of %v = %v, want %v", sizeBit, used, got, want)
+				}
+			}
+
+			// Remove the half of non-overlapping prefixes
+			trie = NewPrefixTrie(base)
+			var kept, removed []*ipaddr.IPAddress
+			for _, u := range used {
+				overlapped := false
+				for _, k := range append(kept, removed...) {
+					if k.Overlaps(u) {
+						overlapped = true
+					}
+				}
+
+				switch {
+				case overlapped:
+					continue
+				case rnd.Intn(2) == 0:
+					kept = append(kept, u)
+				default:
+					removed = append(removed, u)
+				}
+				trie.Insert(u)
+			}
+
+			for _, r := range removed {
+				trie.Remove(r)
+			}
+
+			want = FreeBlocks(base, kept)
+			if diff := cmp.Diff(addrSliceToStrSlice(want), addrSliceToStrSlice(trie.FreeBlocks())); diff != "" {
+				t.Fatalf("FreeBlocks() after removing %v from %v mismatch (-want +got):\n%s", removed, used, diff)
+			}
+		}
+	}
+}
+
+// BenchmarkPrefixTrie allocates /64 prefixes from a /32 IPv6 pool holding the number of claims
+func BenchmarkPrefixTrie(b *testing.B) {
+	base := ipAddress("fd00::/32")
+
+	for _, claims := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprintf("claims=%d", claims), func(b *testing.B) {
+			trie := NewPrefixTrie(base)
+			for i := 0; i < claims; i++ {
+				trie.Insert(trie.FindFree(64))
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				prefix := trie.FindFree(64)
+				trie.Insert(prefix)
+				trie.Remove(prefix)
+			}
+		})
+	}
+}
+
+// BenchmarkFreeBlocks is the baseline of BenchmarkPrefixTrie
+func BenchmarkFreeBlocks(b *testing.B) {
+	base := ipAddress("fd00::/32")
+
+	for _, claims := range []int{100, 1_000} {
+		b.Run(fmt.Sprintf("claims=%d", claims), func(b *testing.B) {
+			used := make([]*ipaddr.IPAddress, 0, claims)
+			for i := 0; i < claims; i++ {
+				used = append(used, FindSubBlock(FreeBlocks(base, used), 64))
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				FindSubBlock(FreeBlocks(base, used), 64)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package netbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrInsufficientSpace is returned if the parent prefix has no space for the requested prefix
+var ErrInsufficientSpace = errors.New("insufficient space in the parent prefix")
+
+// ErrNotFound is returned if the prefix does not exist
+var ErrNotFound = errors.New("prefix not found")
+
+// Client is a client of the IPAM API of NetBox or a compatible server
+type Client struct {
+	// URL is the base URL of the server like https://netbox.example.com
+	URL string
+
+	// Token is the API token sent in the Authorization header
+	Token string
+
+	// HTTPClient is the client used for the requests. http.DefaultClient is used if it is nil.
+	HTTPClient *http.Client
+}
+
+// Prefix is a prefix in the IPAM
+type Prefix struct {
+	ID          int    `json:"id,omitempty"`
+	Prefix      string `json:"prefix"`
+	Description string `json:"description,omitempty"`
+}
+
+// prefixList is a page of the prefixes
+type prefixList struct {
+	Next    *string  `json:"next"`
+	Results []Prefix `json:"results"`
+}
+
+// availablePrefixRequest is a request of a child prefix in the free space of a parent prefix
+type availablePrefixRequest struct {
+	PrefixLength int    `json:"prefix_length"`
+	Description  string `json:"description,omitempty"`
+}
+
+// ListPrefixes returns the prefixes matching the filters like prefix, within, contains and description
+func (c *Client) ListPrefixes(ctx context.Context, filters url.Values) ([]Prefix, error) {
+	next := c.URL + "/api/ipam/prefixes/"
+	if len(filters) != 0 {
+		next += "?" + filters.Encode()
+	}
+
+	var prefixes []Prefix
+	for next != "" {
+		var page prefixList
+		if _, err := c.do(ctx, http.MethodGet, next, nil, &page); err != nil {
+			return nil, fmt.Errorf("failed to list prefixes: %w", err)
+		}
+
+		prefixes = append(prefixes, page.Results...)
+
+		next = ""
+		if page.Next != nil {
+			next = *page.Next
+		}
+	}
+
+	return prefixes, nil
+}
+
+// GetPrefix returns the prefix whose CIDR is cidr
+func (c *Client) GetPrefix(ctx context.Context, cidr string) (*Prefix, error) {
+	prefixes, err := c.ListPrefixes(ctx, url.Values{"prefix": {cidr}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(prefixes) == 0 {
+		return nil, fmt.Errorf("%s: %w", cidr, ErrNotFound)
+	}
+
+	return &prefixes[0], nil
+}
+
+// AvailablePrefixes returns the free prefixes in the parent prefix
+func (c *Client) AvailablePrefixes(ctx context.Context, parentID int) ([]Prefix, error) {
+	var prefixes []Prefix
+	if _, err := c.do(ctx, http.MethodGet, c.availablePrefixesURL(parentID), nil, &prefixes); err != nil {
+		return nil, fmt.Errorf("failed to list available prefixes: %w", err)
+	}
+
+	return prefixes, nil
+}
+
+// AllocatePrefix creates a child prefix of the length in the free space of the parent prefix.
+// It returns ErrInsufficientSpace if the parent prefix has no space for it.
+func (c *Client) AllocatePrefix(ctx context.Context, parentID, prefixLength int, description string) (*Prefix, error) {
+	var prefix Prefix
+	status, err := c.do(ctx, http.MethodPost, c.availablePrefixesURL(parentID), &availablePrefixRequest{
+		PrefixLength: prefixLength,
+		Description:  description,
+	}, &prefix)
+
+	// NetBox responds 204 without a body in older versions and 409 in newer ones
+	if status == http.StatusNoContent || status == http.StatusConflict {
+		return nil, ErrInsufficientSpace
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate prefix: %w", err)
+	}
+
+	return &prefix, nil
+}
+
+// CreatePrefix creates the prefix. The server does not check that it is free.
+func (c *Client) CreatePrefix(ctx context.Context, prefix *Prefix) (*Prefix, error) {
+	var created Prefix
+	if _, err := c.do(ctx, http.MethodPost, c.URL+"/api/ipam/prefixes/", prefix, &created); err != nil {
+		return nil, fmt.Errorf("failed to create prefix: %w", err)
+	}
+
+	return &created, nil
+}
+
+// DeletePrefix deletes the prefix. Prefixes already deleted are ignored.
+func (c *Client) DeletePrefix(ctx context.Context, id int) error {
+	status, err := c.do(ctx, http.MethodDelete, c.URL+"/api/ipam/prefixes/"+strconv.Itoa(id)+"/", nil, nil)
+
+	if status == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete prefix: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) availablePrefixesURL(parentID int) string {
+	return c.URL + "/api/ipam/prefixes/" + strconv.Itoa(parentID) + "/available-prefixes/"
+}
+
+// do sends the request and decodes the response into out. It returns the status code
+// and an error if the server does not respond with 2xx.
+func (c *Client) do(ctx context.Context, method, u string, in, out any) (int, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %w", err)
+		}
+
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Token "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+		return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, req.URL.Path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return resp.StatusCode, nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,93 @@
+package netbox_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/netbox"
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/netbox/netboxtest"
+)
+
+func TestClient(t *testing.T) {
+	server := netboxtest.NewServer()
+	defer server.Close()
+	server.Token = "secret"
+	server.PageSize = 1
+
+	ctx := context.Background()
+	c := &netbox.Client{URL: server.URL, Token: "secret"}
+
+	parentID := server.AddPrefix("10.0.0.0/30")
+
+	parent, err := c.GetPrefix(ctx, "10.0.0.0/30")
+	if err != nil {
+		t.Fatalf("GetPrefix() error = %v", err)
+	}
+	if parent.ID != parentID {
+		t.Errorf("GetPrefix() ID = %d, want %d", parent.ID, parentID)
+	}
+
+	if _, err := c.GetPrefix(ctx, "10.1.0.0/24"); !errors.Is(err, netbox.ErrNotFound) {
+		t.Errorf("GetPrefix() error = %v, want %v", err, netbox.ErrNotFound)
+	}
+
+	first, err := c.AllocatePrefix(ctx, parentID, 31, "first")
+	if err != nil {
+		t.Fatalf("AllocatePrefix() error = %v", err)
+	}
+	if first.Prefix != "10.0.0.0/31" {
+		t.Errorf("AllocatePrefix() = %s, want 10.0.0.0/31", first.Prefix)
+	}
+
+	available, err := c.AvailablePrefixes(ctx, parentID)
+	if err != nil {
+		t.Fatalf("AvailablePrefixes() error = %v", err)
+	}
+	if len(available) != 1 || available[0].Prefix != "10.0.0.2/31" {
+		t.Errorf("AvailablePrefixes() = %v, want [10.0.0.2/31]", available)
+	}
+
+	second, err := c.CreatePrefix(ctx, &netbox.Prefix{Prefix: "10.0.0.2/31", Description: "second"})
+	if err != nil {
+		t.Fatalf("CreatePrefix() error = %v", err)
+	}
+
+	if _, err := c.AllocatePrefix(ctx, parentID, 31, "third"); !errors.Is(err, netbox.ErrInsufficientSpace) {
+		t.Errorf("AllocatePrefix() error = %v, want %v", err, netbox.ErrInsufficientSpace)
+	}
+
+	// The pages are followed
+	children, err := c.ListPrefixes(ctx, url.Values{"within": {"10.0.0.0/30"}})
+	if err != nil {
+		t.Fatalf("ListPrefixes() error = %v", err)
+	}
+	if len(children) != 2 {
+		t.Errorf("ListPrefixes() = %v, want 2 prefixes", children)
+	}
+
+	described, err := c.ListPrefixes(ctx, url.Values{"description": {"second"}})
+	if err != nil {
+		t.Fatalf("ListPrefixes() error = %v", err)
+	}
+	if len(described) != 1 || described[0].ID != second.ID {
+		t.Errorf("ListPrefixes() = %v, want [%v]", described, *second)
+	}
+
+	if err := c.DeletePrefix(ctx, first.ID); err != nil {
+		t.Fatalf("DeletePrefix() error = %v", err)
+	}
+	if err := c.DeletePrefix(ctx, first.ID); err != nil {
+		t.Errorf("DeletePrefix() of a deleted prefix error = %v", err)
+	}
+
+	if got := len(server.Prefixes()); got != 2 {
+		t.Errorf("len(Prefixes()) = %d, want 2", got)
+	}
+
+	unauthorized := &netbox.Client{URL: server.URL, Token: "wrong"}
+	if _, err := unauthorized.GetPrefix(ctx, "10.0.0.0/30"); err == nil {
+		t.Error("GetPrefix() with a wrong token succeeded")
+	}
+}
@@ -0,0 +1,275 @@
+// Package netboxtest provides an in-process stand-in of the IPAM API of NetBox for tests
+package netboxtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/seancfoley/ipaddress-go/ipaddr"
+
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/ipaddrutil"
+	"github.com/miscord-dev/tetrapod/controlplane/pkg/netbox"
+)
+
+// Server serves the prefixes and available-prefixes endpoints from memory
+type Server struct {
+	*httptest.Server
+
+	// Token is the API token required in the Authorization header if it is not empty
+	Token string
+
+	// PageSize is the number of prefixes in a page of the list
+	PageSize int
+
+	mu       sync.Mutex
+	nextID   int
+	prefixes []netbox.Prefix
+}
+
+// NewServer starts a server without prefixes. The caller should call Close when finished.
+func NewServer() *Server {
+	s := &Server{
+		PageSize: 50,
+		nextID:   1,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/ipam/prefixes/{$}", s.list)
+	mux.HandleFunc("POST /api/ipam/prefixes/{$}", s.create)
+	mux.HandleFunc("DELETE /api/ipam/prefixes/{id}/{$}", s.delete)
+	mux.HandleFunc("GET /api/ipam/prefixes/{id}/available-prefixes/{$}", s.available)
+	mux.HandleFunc("POST /api/ipam/prefixes/{id}/available-prefixes/{$}", s.allocate)
+
+	s.Server = httptest.NewServer(s.authorize(mux))
+
+	return s
+}
+
+// AddPrefix adds the prefix and returns its ID
+func (s *Server) AddPrefix(cidr string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.add(netbox.Prefix{Prefix: cidr}).ID
+}
+
+// Prefixes returns the prefixes in the order of creation
+func (s *Server) Prefixes() []netbox.Prefix {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.prefixes)
+}
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" && r.Header.Get("Authorization") != "Token "+s.Token {
+			writeJSON(w, http.StatusForbidden, map[string]string{"detail": "Invalid token"})
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) add(p netbox.Prefix) netbox.Prefix {
+	p.ID = s.nextID
+	s.nextID++
+	s.prefixes = append(s.prefixes, p)
+
+	return p
+}
+
+func (s *Server) find(r *http.Request) int {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return -1
+	}
+
+	return slices.IndexFunc(s.prefixes, func(p netbox.Prefix) bool {
+		return p.ID == id
+	})
+}
+
+// list supports the prefix, within, within_include, contains and description filters, and the offset of the page
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := r.URL.Query()
+	within := ipaddr.NewIPAddressString(query.Get("within")).GetAddress()
+	withinInclude := ipaddr.NewIPAddressString(query.Get("within_include")).GetAddress()
+	contains := ipaddr.NewIPAddressString(query.Get("contains")).GetAddress()
+
+	var matched []netbox.Prefix
+	for _, p := range s.prefixes {
+		if q := query.Get("prefix"); q != "" && !equalPrefix(p.Prefix, q) {
+			continue
+		}
+		if query.Has("description") && p.Description != query.Get("description") {
+			continue
+		}
+
+		addr := ipaddr.NewIPAddressString(p.Prefix).GetAddress()
+
+		if query.Has("within") && (within == nil || addr == nil || !within.Contains(addr) || within.Equal(addr)) {
+			continue
+		}
+		if query.Has("within_include") && (withinInclude == nil || addr == nil || !withinInclude.Contains(addr)) {
+			continue
+		}
+		if query.Has("contains") && (contains == nil || addr == nil || !addr.Contains(contains)) {
+			continue
+		}
+
+		matched = append(matched, p)
+	}
+
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	offset = min(max(offset, 0), len(matched))
+	end := min(offset+s.PageSize, len(matched))
+
+	page := map[string]any{
+		"count":   len(matched),
+		"next":    nil,
+		"results": matched[offset:end],
+	}
+
+	if end < len(matched) {
+		next := *r.URL
+		next.Scheme = "http"
+		next.Host = r.Host
+		q := next.Query()
+		q.Set("offset", strconv.Itoa(end))
+		next.RawQuery = q.Encode()
+
+		page["next"] = next.String()
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var p netbox.Prefix
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil || ipaddr.NewIPAddressString(p.Prefix).GetAddress() == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"prefix": "Enter a valid prefix"})
+
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, s.add(p))
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.find(r)
+	if i < 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+
+		return
+	}
+
+	s.prefixes = slices.Delete(s.prefixes, i, i+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) available(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.find(r)
+	if i < 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+
+		return
+	}
+
+	free := s.freeBlocks(&s.prefixes[i])
+
+	prefixes := make([]netbox.Prefix, 0, len(free))
+	for _, f := range free {
+		prefixes = append(prefixes, netbox.Prefix{Prefix: f.String()})
+	}
+
+	writeJSON(w, http.StatusOK, prefixes)
+}
+
+func (s *Server) allocate(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.find(r)
+	if i < 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"detail": "Not found."})
+
+		return
+	}
+
+	var req struct {
+		PrefixLength int    `json:"prefix_length"`
+		Description  string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"detail": err.Error()})
+
+		return
+	}
+
+	parent := ipaddr.NewIPAddressString(s.prefixes[i].Prefix).GetAddress()
+	allocated := ipaddrutil.FindSubBlock(s.freeBlocks(&s.prefixes[i]), parent.GetBitCount()-req.PrefixLength)
+
+	if allocated == nil {
+		writeJSON(w, http.StatusConflict, map[string]string{
+			"detail": "Insufficient space is available to accommodate the requested prefix size(s)",
+		})
+
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, s.add(netbox.Prefix{
+		Prefix:      allocated.String(),
+		Description: req.Description,
+	}))
+}
+
+// freeBlocks returns the space in the parent not covered by its child prefixes
+func (s *Server) freeBlocks(parent *netbox.Prefix) []*ipaddr.IPAddress {
+	base := ipaddr.NewIPAddressString(parent.Prefix).GetAddress().ToPrefixBlock()
+
+	var used []*ipaddr.IPAddress
+	for _, p := range s.prefixes {
+		addr := ipaddr.NewIPAddressString(p.Prefix).GetAddress()
+
+		if addr == nil || p.ID == parent.ID || !base.Contains(addr) {
+			continue
+		}
+
+		used = append(used, addr.ToPrefixBlock())
+	}
+
+	return ipaddrutil.FreeBlocks(base, used)
+}
+
+func equalPrefix(a, b string) bool {
+	x := ipaddr.NewIPAddressString(a).GetAddress()
+	y := ipaddr.NewIPAddressString(b).GetAddress()
+
+	return x != nil && y != nil && x.Equal(y)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -65,11 +65,13 @@ func loadExtraRoutes(config *Conf) ([]string, error) {
 
 	cidrs := make([]string, 0, len(cidrClaimList.Items))
 	for _, claim := range cidrClaimList.Items {
-		if claim.Status.CIDR == "" {
-			continue
-		}
+		for _, s := range claim.Status.FamilyStatuses() {
+			if s.CIDR == "" {
+				continue
+			}
 
-		cidrs = append(cidrs, claim.Status.CIDR)
+			cidrs = append(cidrs, s.CIDR)
+		}
 	}
 
 	return cidrs, nil
@@ -63,29 +63,32 @@ func MutateConfig(netConfig *allocator.Net, config *Config) error {
 		return fmt.Errorf("failed to get pod CIDRs: %w", err)
 	}
 
+	// Each address family of dual-stack claims is a range set so that a pod gets an address in each family
 	for _, c := range cidrClaimList.Items {
-		cidr, err := types.ParseCIDR(c.Status.CIDR)
-
-		if err != nil {
-			return fmt.Errorf("failed to parse CIDR %s: %w", c.Status.CIDR, err)
+		for _, s := range c.Status.FamilyStatuses() {
+			cidr, err := types.ParseCIDR(s.CIDR)
+
+			if err != nil {
+				return fmt.Errorf("failed to parse CIDR %s: %w", s.CIDR, err)
+			}
+
+			ipAddr, _ := ipaddr.NewIPAddressFromNetIPNet(cidr)
+			rangeStart := ipAddr.GetLower().Increment(1)
+			rangeEnd := ipAddr.GetUpper().Increment(-1)
+
+			switch config.IPAM.ReserveAddress {
+			case ReserveAddressLast:
+				rangeEnd = rangeEnd.Increment(-1)
+			}
+
+			netConfig.RuntimeConfig.IPRanges = append(netConfig.RuntimeConfig.IPRanges, []allocator.Range{
+				{
+					Subnet:     types.IPNet(*cidr),
+					RangeStart: rangeStart.GetNetIP(),
+					RangeEnd:   rangeEnd.GetNetIP(),
+				},
+			})
 		}
-
-		ipAddr, _ := ipaddr.NewIPAddressFromNetIPNet(cidr)
-		rangeStart := ipAddr.GetLower().Increment(1)
-		rangeEnd := ipAddr.GetUpper().Increment(-1)
-
-		switch config.IPAM.ReserveAddress {
-		case ReserveAddressLast:
-			rangeEnd = rangeEnd.Increment(-1)
-		}
-
-		netConfig.RuntimeConfig.IPRanges = append(netConfig.RuntimeConfig.IPRanges, []allocator.Range{
-			{
-				Subnet:     types.IPNet(*cidr),
-				RangeStart: rangeStart.GetNetIP(),
-				RangeEnd:   rangeEnd.GetNetIP(),
-			},
-		})
 	}
 
 	return nil
@@ -201,6 +201,10 @@ type CNIConfig struct {
 	Cleanup                                           bool         `json:"cleanup"`
 	StaticAdvertisedRoutes                            []string     `json:"staticAdvertisedRoutes"`
 	CNID                                              CNIDConfig   `json:"cnid"`
+
+	// PeerNodeLabels are added to the PeerNode of this node, e.g. topology.kubernetes.io/zone
+	// so that CIDRClaims can prefer CIDRBlocks in the topology of the node
+	PeerNodeLabels map[string]string `json:"peerNodeLabels,omitempty"`
 }
 
 func (cc *CNIConfig) Load(configPath string) error {
@@ -39,6 +39,13 @@ func (in *CNIConfig) DeepCopyInto(out *CNIConfig) {
 		copy(*out, *in)
 	}
 	in.CNID.DeepCopyInto(&out.CNID)
+	if in.PeerNodeLabels != nil {
+		in, out := &in.PeerNodeLabels, &out.PeerNodeLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CNIConfig.
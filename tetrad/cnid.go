@@ -28,6 +28,7 @@ func SetupCNId(ctx context.Context, mgr manager.Manager, config clientmiscordwin
 		ClusterName:           config.ClusterName,
 		NodeName:              config.NodeName,
 		TemplateNames:         config.CNID.AddressClaimTemplates,
+		Recorder:              mgr.GetEventRecorder("tetrad"),
 		ClaimNameGenerator: func(templateName string) string {
 			name := fmt.Sprintf("%s-%s-pod-%s", config.ClusterName, config.NodeName, templateName)
 
@@ -72,6 +73,7 @@ func SetupCNId(ctx context.Context, mgr manager.Manager, config clientmiscordwin
 			ControlPlaneNamespace: config.ControlPlane.Namespace,
 			ClusterName:           config.ClusterName,
 			NodeName:              config.NodeName,
+			Recorder:              mgr.GetEventRecorder("tetrad"),
 			Local:                 localCluster,
 		}).SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "ExtraPodCIDRSync")
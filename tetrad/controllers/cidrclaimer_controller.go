@@ -27,6 +27,7 @@ import (
 	k8slabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -47,7 +48,8 @@ type CIDRClaimerReconciler struct {
 	Labels                func(templateName string) map[string]string
 	AllocatedCallback     func(cidr string)
 
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder events.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=client.miscord.win,resources=cidrclaimers,verbs=get;list;watch;create;update;patch;delete
@@ -109,10 +111,16 @@ func (r *CIDRClaimerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	claim.Namespace = r.ControlPlaneNamespace
 	claim.Name = r.ClaimNameGenerator(req.Name)
 
-	_, err = ctrl.CreateOrUpdate(ctx, r.Client, &claim, func() error {
+	result, err := ctrl.CreateOrUpdate(ctx, r.Client, &claim, func() error {
 		claim.Labels = r.Labels(req.Name)
 		claim.Spec.Selector = tmpl.Spec.Selector
 		claim.Spec.SizeBit = tmpl.Spec.SizeBit
+		claim.Spec.MinSizeBit = tmpl.Spec.MinSizeBit
+		claim.Spec.RequestedCIDR = tmpl.Spec.RequestedCIDR
+		claim.Spec.Families = tmpl.Spec.Families
+		claim.Spec.Preferred = tmpl.Spec.Preferred
+		claim.Spec.ReclaimPolicy = tmpl.Spec.ReclaimPolicy
+		claim.Spec.DrainPeriod = tmpl.Spec.DrainPeriod
 
 		if selfNode != nil {
 			return controllerutil.SetOwnerReference(selfNode, &claim, r.Scheme)
@@ -122,8 +130,13 @@ func (r *CIDRClaimerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	})
 
 	if err != nil {
+		if claim.UID != "" {
+			recordUpsertFailure(r.Recorder, &claim, err, r.NodeName)
+		}
+
 		return reconcile.Result{}, fmt.Errorf("failed to upsert claim: %w", err)
 	}
+	recordUpsert(r.Recorder, &claim, result, r.NodeName)
 
 	if claim.Status.ObservedGeneration != claim.Generation {
 		return reconcile.Result{}, nil
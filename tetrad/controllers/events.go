@@ -0,0 +1,39 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// recordUpsert records an Event on the object created or updated by the node
+func recordUpsert(recorder events.EventRecorder, obj runtime.Object, result controllerutil.OperationResult, nodeName string) {
+	switch result {
+	case controllerutil.OperationResultCreated:
+		recorder.Eventf(obj, nil, corev1.EventTypeNormal, "Created", "Upsert", "Created by tetrad on node %s", nodeName)
+	case controllerutil.OperationResultUpdated:
+		recorder.Eventf(obj, nil, corev1.EventTypeNormal, "Updated", "Upsert", "Updated by tetrad on node %s", nodeName)
+	}
+}
+
+// recordUpsertFailure records an Event on the object the node failed to create or update
+func recordUpsertFailure(recorder events.EventRecorder, obj runtime.Object, err error, nodeName string) {
+	recorder.Eventf(obj, nil, corev1.EventTypeWarning, "UpsertFailed", "Upsert", "Failed to upsert by tetrad on node %s: %v", nodeName, err)
+}
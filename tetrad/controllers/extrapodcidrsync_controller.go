@@ -30,6 +30,7 @@ import (
 	k8slabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/cluster"
@@ -47,6 +48,7 @@ type ExtraPodCIDRSyncReconciler struct {
 	ClusterName           string
 	NodeName              string
 	ControlPlaneNamespace string
+	Recorder              events.EventRecorder
 
 	Local cluster.Cluster
 }
@@ -112,12 +114,18 @@ func (r *ExtraPodCIDRSyncReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		claim.Namespace = r.ControlPlaneNamespace
 		claim.Name = claimName
 
-		_, err = ctrl.CreateOrUpdate(ctx, r.Client, &claim, func() error {
+		result, err := ctrl.CreateOrUpdate(ctx, r.Client, &claim, func() error {
 			claim.Labels = labels.ExtraPodCIDRTypeForNode(r.ClusterName, r.NodeName, req.Namespace, req.Name, templateName)
 			claim.Labels[labels.TemplateNameLabelKey] = templateName
 
 			claim.Spec.Selector = tmpl.Spec.Selector
 			claim.Spec.SizeBit = tmpl.Spec.SizeBit
+			claim.Spec.MinSizeBit = tmpl.Spec.MinSizeBit
+			claim.Spec.RequestedCIDR = tmpl.Spec.RequestedCIDR
+			claim.Spec.Families = tmpl.Spec.Families
+			claim.Spec.Preferred = tmpl.Spec.Preferred
+			claim.Spec.ReclaimPolicy = tmpl.Spec.ReclaimPolicy
+			claim.Spec.DrainPeriod = tmpl.Spec.DrainPeriod
 
 			if selfNode != nil {
 				return controllerutil.SetOwnerReference(selfNode, &claim, r.Scheme)
@@ -127,8 +135,13 @@ func (r *ExtraPodCIDRSyncReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		})
 
 		if err != nil {
+			if claim.UID != "" {
+				recordUpsertFailure(r.Recorder, &claim, err, r.NodeName)
+			}
+
 			return ctrl.Result{}, fmt.Errorf("failed to upsert CIDRClaim: %w", err)
 		}
+		recordUpsert(r.Recorder, &claim, result, r.NodeName)
 	}
 
 	return ctrl.Result{}, nil
@@ -0,0 +1,119 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	controlplanev1alpha1 "github.com/miscord-dev/tetrapod/controlplane/api/v1alpha1"
+)
+
+// NodeLeaseRenewer renews the Lease of the self PeerNode as a heartbeat
+// so that the controlplane can garbage-collect dead nodes
+type NodeLeaseRenewer struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	ControlPlaneNamespace string
+	ClusterName           string
+	NodeName              string
+
+	// LeaseDuration is the duration the Lease is valid for
+	LeaseDuration time.Duration
+	// RenewInterval is the interval between renewals
+	RenewInterval time.Duration
+}
+
+var _ manager.Runnable = &NodeLeaseRenewer{}
+
+// Start implements manager.Runnable
+func (r *NodeLeaseRenewer) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("NodeLeaseRenewer")
+
+	ticker := time.NewTicker(r.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.renew(ctx); err != nil {
+			logger.Error(err, "failed to renew Lease")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (r *NodeLeaseRenewer) renew(ctx context.Context) error {
+	name := peerNodeName(r.ClusterName, r.NodeName)
+
+	var peerNode controlplanev1alpha1.PeerNode
+	err := r.Get(ctx, types.NamespacedName{
+		Namespace: r.ControlPlaneNamespace,
+		Name:      name,
+	}, &peerNode)
+
+	if errors.IsNotFound(err) {
+		// The Lease is created after PeerNodeSync creates the PeerNode
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get self PeerNode: %w", err)
+	}
+
+	var lease coordinationv1.Lease
+	lease.Namespace = r.ControlPlaneNamespace
+	lease.Name = name
+
+	_, err = ctrl.CreateOrUpdate(ctx, r.Client, &lease, func() error {
+		durationSeconds := int32(r.LeaseDuration.Seconds())
+		now := v1.NewMicroTime(time.Now())
+
+		if lease.Labels == nil {
+			lease.Labels = map[string]string{}
+		}
+		// The control plane only watches the Leases with the label
+		lease.Labels[controlplanev1alpha1.PeerNodeLeaseLabelKey] = peerNode.Name
+
+		lease.Spec.HolderIdentity = &r.NodeName
+		lease.Spec.LeaseDurationSeconds = &durationSeconds
+		lease.Spec.RenewTime = &now
+
+		return controllerutil.SetControllerReference(&peerNode, &lease, r.Scheme)
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to upsert Lease %s/%s: %w", lease.Namespace, lease.Name, err)
+	}
+
+	return nil
+}
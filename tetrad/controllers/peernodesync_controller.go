@@ -27,6 +27,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -48,6 +49,8 @@ type PeerNodeSyncReconciler struct {
 	NodeName               string
 	Engine                 tetraengine.TetraEngine
 	StaticAdvertisedRoutes []string
+	PeerNodeLabels         map[string]string
+	Recorder               events.EventRecorder
 
 	peerConfig atomic.Pointer[tetraengine.PeerConfig]
 }
@@ -78,8 +81,8 @@ func (r *PeerNodeSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	peerNode.Namespace = r.ControlPlaneNamespace
 	peerNode.Name = peerNodeName(r.ClusterName, r.NodeName)
 
-	_, err := ctrl.CreateOrUpdate(ctx, r.Client, &peerNode, func() error {
-		peerNode.Labels = r.labels()
+	result, err := ctrl.CreateOrUpdate(ctx, r.Client, &peerNode, func() error {
+		peerNode.Labels = r.peerNodeLabels()
 
 		peerNode.Spec.ClaimsSelector = v1.LabelSelector{
 			MatchLabels: r.labels(),
@@ -100,8 +103,13 @@ func (r *PeerNodeSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	})
 
 	if err != nil {
+		if peerNode.UID != "" {
+			recordUpsertFailure(r.Recorder, &peerNode, err, r.NodeName)
+		}
+
 		return ctrl.Result{}, fmt.Errorf("failed to upsert PeerNode %s/%s: %w", peerNode.Namespace, peerNode.Name, err)
 	}
+	recordUpsert(r.Recorder, &peerNode, result, r.NodeName)
 
 	return ctrl.Result{}, nil
 }
@@ -110,6 +118,26 @@ func (r *PeerNodeSyncReconciler) labels() map[string]string {
 	return labels.ForNode(r.ClusterName, r.NodeName)
 }
 
+// peerNodeLabels returns the labels of the PeerNode including its attributes and the
+// configured labels so that CIDRClaims can prefer CIDRBlocks by them
+func (r *PeerNodeSyncReconciler) peerNodeLabels() map[string]string {
+	labels := map[string]string{
+		"kubernetes.io/hostname": r.NodeName,
+		"kubernetes.io/os":       goruntime.GOOS,
+		"kubernetes.io/arch":     goruntime.GOARCH,
+	}
+
+	for k, v := range r.PeerNodeLabels {
+		labels[k] = v
+	}
+
+	for k, v := range r.labels() {
+		labels[k] = v
+	}
+
+	return labels
+}
+
 func (r *PeerNodeSyncReconciler) addrsLabels() map[string]string {
 	return labels.NodeTypeForNode(r.ClusterName, r.NodeName, "")
 }
@@ -107,6 +107,10 @@ func (r *PeersSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	for _, peer := range peers.Items {
 		logger := logger.WithValues("peer", peer.Name)
 
+		if peer.Status.State == controlplanev1alpha1.PeerNodeStatusStateOffline {
+			continue
+		}
+
 		selector, err := v1.LabelSelectorAsSelector(&peer.Spec.ClaimsSelector)
 
 		if err != nil {
@@ -149,11 +153,13 @@ func (r *PeersSyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 				continue
 			}
 
-			if addressesSelector.Matches(labels.Set(claim.Labels)) {
-				pc.Addresses = append(pc.Addresses, claim.Status.CIDR)
-			}
+			for _, s := range claim.Status.AllocatedStatuses() {
+				if addressesSelector.Matches(labels.Set(claim.Labels)) {
+					pc.Addresses = append(pc.Addresses, s.CIDR)
+				}
 
-			pc.AllowedIPs = append(pc.AllowedIPs, claim.Status.CIDR)
+				pc.AllowedIPs = append(pc.AllowedIPs, s.CIDR)
+			}
 		}
 
 		peerConfigs = append(peerConfigs, pc)
@@ -195,20 +201,22 @@ func (r *PeersSyncReconciler) getSelfAddresses(ctx context.Context) ([]netlink.A
 			continue
 		}
 
-		addr, err := netlink.ParseAddr(a.Status.CIDR)
+		for _, s := range a.Status.AllocatedStatuses() {
+			addr, err := netlink.ParseAddr(s.CIDR)
 
-		if err != nil {
-			logger.Error(
-				err,
-				"failed to parse address",
-				"cidrClaim", fmt.Sprintf("%s/%s", a.Namespace, a.Name),
-				"address", a.Status.CIDR,
-			)
+			if err != nil {
+				logger.Error(
+					err,
+					"failed to parse address",
+					"cidrClaim", fmt.Sprintf("%s/%s", a.Namespace, a.Name),
+					"address", s.CIDR,
+				)
 
-			continue
-		}
+				continue
+			}
 
-		addrs = append(addrs, *addr)
+			addrs = append(addrs, *addr)
+		}
 	}
 
 	return addrs, nil
@@ -213,6 +213,7 @@ func main() {
 		ClusterName:           config.ClusterName,
 		NodeName:              config.NodeName,
 		TemplateNames:         config.ControlPlane.AddressClaimTemplates,
+		Recorder:              mgr.GetEventRecorder("tetrad"),
 		ClaimNameGenerator: func(templateName string) string {
 			name := fmt.Sprintf("%s-%s-%s", config.ClusterName, config.NodeName, templateName)
 
@@ -239,6 +240,8 @@ func main() {
 		NodeName:               config.NodeName,
 		Engine:                 engine,
 		StaticAdvertisedRoutes: config.StaticAdvertisedRoutes,
+		PeerNodeLabels:         config.PeerNodeLabels,
+		Recorder:               mgr.GetEventRecorder("tetrad"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PeerNodeSync")
 		os.Exit(1)
@@ -258,6 +261,18 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "PeersSync")
 		os.Exit(1)
 	}
+	if err = mgr.Add(&controllers.NodeLeaseRenewer{
+		Client:                mgr.GetClient(),
+		Scheme:                mgr.GetScheme(),
+		ControlPlaneNamespace: config.ControlPlane.Namespace,
+		ClusterName:           config.ClusterName,
+		NodeName:              config.NodeName,
+		LeaseDuration:         40 * time.Second,
+		RenewInterval:         10 * time.Second,
+	}); err != nil {
+		setupLog.Error(err, "unable to add runnable", "runnable", "NodeLeaseRenewer")
+		os.Exit(1)
+	}
 
 	//+kubebuilder:scaffold:builder
 
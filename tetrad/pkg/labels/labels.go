@@ -7,17 +7,23 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 )
 
+const (
+	ClusterLabelKey = "client.miscord.win/cluster"
+	NodeLabelKey    = "client.miscord.win/node"
+	TypeLabelKey    = "client.miscord.win/type"
+)
+
 func ForNode(clusterName, nodeName string) map[string]string {
 	return map[string]string{
-		"client.miscord.win/cluster": clusterName,
-		"client.miscord.win/node":    nodeName,
+		ClusterLabelKey: clusterName,
+		NodeLabelKey:    nodeName,
 	}
 }
 
 func NodeTypeForNode(clusterName, nodeName, templateName string) map[string]string {
 	labels := ForNode(clusterName, nodeName)
 
-	labels["client.miscord.win/type"] = "node"
+	labels[TypeLabelKey] = "node"
 	if templateName != "" {
 		labels[TemplateNameLabelKey] = templateName
 	}
@@ -28,7 +34,7 @@ func NodeTypeForNode(clusterName, nodeName, templateName string) map[string]stri
 func PodCIDRTypeForNode(clusterName, nodeName, templateName string) map[string]string {
 	labels := ForNode(clusterName, nodeName)
 
-	labels["client.miscord.win/type"] = "pod-cidr"
+	labels[TypeLabelKey] = "pod-cidr"
 	if templateName != "" {
 		labels[TemplateNameLabelKey] = templateName
 	}
@@ -39,7 +45,7 @@ func PodCIDRTypeForNode(clusterName, nodeName, templateName string) map[string]s
 func ExtraPodCIDRTypeForNodeAll(clusterName, nodeName, templateName string) map[string]string {
 	labels := ForNode(clusterName, nodeName)
 
-	labels["client.miscord.win/type"] = "extra-pod-cidr"
+	labels[TypeLabelKey] = "extra-pod-cidr"
 	if templateName != "" {
 		labels[TemplateNameLabelKey] = templateName
 	}